@@ -12,38 +12,168 @@
 //	- application/json
 //
 // swagger:meta
-package main
-
-import (
-    "log"
-    "os"
-
-    _ "learn-api/docs" // Import the generated docs
-    "learn-api/internal/app"
-    "learn-api/internal/database"
-    "learn-api/internal/repository"
-    "learn-api/internal/services"
-)
-
-func main() {
-    // Connect to the database
-    if err := database.ConnectDB(); err != nil {
-        log.Fatal("Failed to connect to database:", err)
-    }
-
-    // Initialize repository and service
-    entityRepo := repository.NewEntityRepository()
-    entityService := services.NewEntityService(entityRepo)
-
-    // Build app with dependencies
-    app := app.NewFiberApp(entityService)
-
-    // Get port from environment variable or use default
-    port := os.Getenv("PORT")
-    if port == "" {
-        port = "8080"
-    }
-
-    log.Printf("Server starting on port %s", port)
-    log.Fatal(app.Listen(":" + port))
-}
+package main
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "log"
+    "log/slog"
+    "net"
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
+
+    _ "learn-api/docs" // Import the generated docs
+    "learn-api/internal/app"
+    "learn-api/internal/config"
+    "learn-api/internal/database"
+    "learn-api/internal/database/migrate"
+    "learn-api/internal/health"
+    "learn-api/internal/logging"
+    "learn-api/internal/metrics"
+    "learn-api/internal/repository"
+    "learn-api/internal/services"
+    "learn-api/internal/tracing"
+)
+
+// usage describes the commands; flags come before the command
+const usage = `usage: api [flags] [command]
+
+commands:
+  (none)    run the server
+  config    print the effective configuration, with secrets redacted
+  migrate   manage the database schema, see "api migrate"
+
+Run "api -h" to list the flags.`
+
+func main() {
+    // Load the configuration from defaults, the config file, environment and flags
+    cfg, args, err := config.Load(os.Args[1:])
+    if errors.Is(err, flag.ErrHelp) {
+        return
+    }
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    if len(args) > 0 {
+        switch args[0] {
+        case "config":
+            fmt.Print(cfg)
+        case "migrate":
+            // Run the migrate subcommand instead of the server
+            if err := runMigrate(cfg.Database.Config, args[1:]); err != nil {
+                log.Fatal(err)
+            }
+        default:
+            log.Fatal(usage)
+        }
+        return
+    }
+
+    // Log as the configuration describes; the commands above print for people
+    if err := logging.Setup(os.Stdout, cfg.Log); err != nil {
+        log.Fatal(err)
+    }
+
+    // Propagate trace context and export spans if an exporter is configured
+    shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+    if err != nil {
+        fatal("Failed to set up tracing", err)
+    }
+
+    // Initialize repository and service
+    var entityRepo repository.EntityRepository
+    var unitOfWork repository.UnitOfWork
+    var pool *database.Pool
+    var lifecycle app.Lifecycle
+    appMetrics := metrics.New()
+    appOpts := []app.Option{
+        app.WithRequestTimeout(cfg.Server.RequestTimeout),
+        app.WithLifecycle(&lifecycle),
+        app.WithMetrics(appMetrics),
+    }
+    if cfg.Storage == config.StorageMemory {
+        // Keep entities in memory for local development; they are lost on exit
+        slog.Info("Using in-memory storage")
+        entityRepo = repository.NewMemoryEntityRepository()
+        unitOfWork, err = repository.NewMemoryUnitOfWork(entityRepo)
+        if err != nil {
+            fatal("Failed to create unit of work", err)
+        }
+    } else {
+        // Connect to the database
+        pool, err = database.ConnectDB(cfg.Database.Config)
+        if err != nil {
+            fatal("Failed to connect to database", err)
+        }
+
+        queryTimeout := repository.WithQueryTimeout(cfg.Database.QueryTimeout)
+        entityRepo = repository.NewEntityRepository(pool, queryTimeout, repository.WithReplicas(pool))
+        unitOfWork = repository.NewUnitOfWork(pool, queryTimeout)
+        appOpts = append(appOpts, app.WithDatabase(pool))
+        if err := appMetrics.RegisterPool(pool); err != nil {
+            fatal("Failed to register pool metrics", err)
+        }
+
+        // Report not ready until the schema is migrated
+        migrations, err := migrate.Load(migrate.Source())
+        if err != nil {
+            fatal("Failed to load migrations", err)
+        }
+        migrationCheck := health.NewMigrationChecker(migrate.NewMigrator(pool.DB, migrations), migrations)
+        appOpts = append(appOpts, app.WithReadinessCheck("migrations", migrationCheck))
+        if pool.ReplicaCount() > 0 {
+            appOpts = append(appOpts, app.WithReadYourWrites(cfg.Server.ReadYourWritesWindow))
+        }
+    }
+    entityRepo = repository.NewInstrumentedRepository(entityRepo, appMetrics)
+    unitOfWork = repository.NewInstrumentedUnitOfWork(unitOfWork, appMetrics)
+    entityService := services.NewTracedEntityService(services.NewEntityService(entityRepo, unitOfWork))
+
+    // Build app with dependencies
+    server := app.NewFiberApp(entityService, appOpts...)
+
+    ln, err := net.Listen("tcp", ":"+cfg.Server.Port)
+    if err != nil {
+        fatal("Failed to listen", err)
+    }
+
+    // Shut down gracefully on SIGINT or SIGTERM; a second signal stops at once
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+    defer stop()
+    context.AfterFunc(ctx, stop)
+
+    slog.Info("Server starting", "port", cfg.Server.Port)
+    err = app.Serve(ctx, server, ln, &lifecycle, app.ShutdownConfig{
+        Delay:   cfg.Server.ShutdownDelay,
+        Timeout: cfg.Server.ShutdownTimeout,
+    })
+
+    // Close the database only once no request can use it any more
+    if pool != nil {
+        if closeErr := pool.Close(); closeErr != nil {
+            slog.Error("Error closing database", "error", closeErr)
+        }
+    }
+
+    // Flush the spans of the last requests
+    flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    if flushErr := shutdownTracing(flushCtx); flushErr != nil {
+        slog.Error("Error flushing traces", "error", flushErr)
+    }
+    if err != nil {
+        fatal("Server failed", err)
+    }
+}
+
+// fatal logs err as an error and exits
+func fatal(msg string, err error) {
+    slog.Error(msg, "error", err)
+    os.Exit(1)
+}
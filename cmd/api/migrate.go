@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"learn-api/internal/database"
+	"learn-api/internal/database/migrate"
+)
+
+// migrateUsage describes the migrate subcommand
+const migrateUsage = `usage: api [flags] migrate <command>
+
+commands:
+  up                      apply all pending migrations
+  down [n]                revert the last n applied migrations (default 1)
+  status                  list migrations and when they were applied
+  create [-dir d] <name>  add an empty migration to d (default ` + migrate.SourceDir + `)`
+
+// errMigrateUsage is returned when the migrate subcommand is misused
+var errMigrateUsage = errors.New(migrateUsage)
+
+// runMigrate runs the migrate subcommand with the arguments following "migrate"
+// against the database described by cfg
+func runMigrate(cfg database.Config, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	command, args := args[0], args[1:]
+	if command == "create" {
+		return createMigration(args)
+	}
+
+	steps := 1
+	switch {
+	case command == "down" && len(args) == 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return errMigrateUsage
+		}
+		steps = n
+	case command != "up" && command != "down" && command != "status", len(args) > 0:
+		return errMigrateUsage
+	}
+
+	migrations, err := migrate.Load(migrate.Source())
+	if err != nil {
+		return err
+	}
+
+	pool, err := database.ConnectDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	migrator := migrate.NewMigrator(pool.DB, migrations)
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Println("No applied migrations")
+		}
+		return err
+	default:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+	}
+}
+
+// createMigration runs "migrate create", which needs no database
+func createMigration(args []string) error {
+	flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := flags.String("dir", migrate.SourceDir, "directory holding the migration files")
+	if err := flags.Parse(args); err != nil {
+		return errMigrateUsage
+	}
+
+	if flags.NArg() != 1 {
+		return errMigrateUsage
+	}
+
+	paths, err := migrate.Create(*dir, flags.Arg(0))
+	for _, path := range paths {
+		fmt.Printf("Created %s\n", path)
+	}
+	return err
+}
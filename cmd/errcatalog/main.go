@@ -0,0 +1,25 @@
+// Command errcatalog writes the error catalog page, docs/errors.md, from the
+// catalog in pkg/errors. Run it with go generate ./pkg/errors.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"os"
+
+	"learn-api/pkg/errors"
+)
+
+func main() {
+	out := flag.String("o", "docs/errors.md", "file to write the catalog page to")
+	flag.Parse()
+
+	var page bytes.Buffer
+	if err := errors.WriteCatalog(&page); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, page.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,22 +0,0 @@
--- Create entities table
-CREATE TABLE IF NOT EXISTS entities (
-    id SERIAL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
--- Create a function to update the updated_at timestamp
-CREATE OR REPLACE FUNCTION update_updated_at_column()
-RETURNS TRIGGER AS $$
-BEGIN
-    NEW.updated_at = NOW();
-    RETURN NEW;
-END;
-$$ language 'plpgsql';
-
--- Create a trigger to automatically update the updated_at column
-CREATE TRIGGER update_entities_updated_at 
-    BEFORE UPDATE ON entities 
-    FOR EACH ROW 
-    EXECUTE FUNCTION update_updated_at_column();
\ No newline at end of file
@@ -1,46 +1,237 @@
 package app
 
 import (
+    "context"
+    "strconv"
+    "time"
+
     "github.com/gofiber/fiber/v2"
-    "github.com/gofiber/fiber/v2/middleware/logger"
+    "github.com/gofiber/fiber/v2/middleware/adaptor"
     "github.com/gofiber/swagger"
 
+    "learn-api/internal/database"
     "learn-api/internal/handlers"
+    "learn-api/internal/health"
+    "learn-api/internal/logging"
+    "learn-api/internal/metrics"
+    "learn-api/internal/repository"
     "learn-api/internal/services"
+    "learn-api/internal/tracing"
 )
 
+// healthCheckTimeout bounds the database ping made by the health check and each readiness check
+const healthCheckTimeout = 2 * time.Second
+
+// lastWriteHeader and lastWriteCookie carry the time of a client's last write,
+// in Unix milliseconds
+const (
+    lastWriteHeader = "X-Last-Write"
+    lastWriteCookie = "last_write"
+)
+
+// Option configures the Fiber application
+type Option func(*options)
+
+type options struct {
+    requestTimeout time.Duration
+    db             *database.Pool
+    readYourWrites time.Duration
+    lifecycle      *Lifecycle
+    checks         map[string]health.Checker
+    metrics        *metrics.Metrics
+}
+
+// WithMetrics counts and times every request and serves m on /metrics in the
+// Prometheus text format
+func WithMetrics(m *metrics.Metrics) Option {
+    return func(o *options) {
+        o.metrics = m
+    }
+}
+
+// WithReadinessCheck adds a dependency check to /readyz under name. The
+// database, when given with WithDatabase, is checked as "database" and a
+// draining Lifecycle as "shutdown".
+func WithReadinessCheck(name string, checker health.Checker) Option {
+    return func(o *options) {
+        if o.checks == nil {
+            o.checks = map[string]health.Checker{}
+        }
+        o.checks[name] = checker
+    }
+}
+
+// WithReadYourWrites sends a client's reads to the primary database for the given
+// window after its last write, so it sees its own changes while replicas catch up.
+// Successful writes return their time in the X-Last-Write header and a last_write
+// cookie; clients send either one back. Zero disables the window.
+func WithReadYourWrites(window time.Duration) Option {
+    return func(o *options) {
+        o.readYourWrites = window
+    }
+}
+
+// WithDatabase makes the health check report whether the database answers
+func WithDatabase(db *database.Pool) Option {
+    return func(o *options) {
+        o.db = db
+    }
+}
+
+// WithRequestTimeout bounds every API request by the given timeout. The deadline
+// is carried by the request's user context down to the database, where it
+// cancels any query still running. Zero disables the timeout.
+func WithRequestTimeout(timeout time.Duration) Option {
+    return func(o *options) {
+        o.requestTimeout = timeout
+    }
+}
+
 // NewFiberApp builds and configures the Fiber application.
 // It accepts a `services.EntityService` to allow testing with mocks.
-func NewFiberApp(entityService services.EntityService) *fiber.App {
+func NewFiberApp(entityService services.EntityService, opts ...Option) *fiber.App {
+    var o options
+    for _, opt := range opts {
+        opt(&o)
+    }
+
     // Initialize handler with provided service
     entityHandler := handlers.NewEntityHandler(entityService)
 
-    // Create Fiber app
-    app := fiber.New()
+    // Create Fiber app; the startup banner is left out of the structured logs
+    app := fiber.New(fiber.Config{DisableStartupMessage: true})
+
+    // Continue the caller's trace, or start one, for every request
+    app.Use(tracing.Middleware())
+
+    // Give every request an ID and log it as JSON
+    app.Use(logging.Middleware())
 
-    // Add logger middleware
-    app.Use(logger.New())
+    // Record request metrics and expose them for scraping
+    if o.metrics != nil {
+        app.Use(o.metrics.Middleware())
+        app.Get("/metrics", adaptor.HTTPHandler(o.metrics.Handler()))
+    }
 
-    // Health check endpoint
+    // Readiness checks, run by /readyz
+    readiness := health.NewRegistry(health.WithTimeout(healthCheckTimeout))
+    if o.db != nil {
+        readiness.Register("database", health.NewDatabaseChecker(o.db))
+    }
+    if o.lifecycle != nil {
+        readiness.Register("shutdown", o.lifecycle)
+    }
+    for name, checker := range o.checks {
+        readiness.Register(name, checker)
+    }
+
+    // Liveness: the process is up and serving; dependencies are not checked,
+    // so an unavailable database does not get the service restarted
+    app.Get("/livez", func(c *fiber.Ctx) error {
+        return c.JSON(fiber.Map{"status": health.StatusUp})
+    })
+
+    // Readiness: every dependency check passes, reported with their details
+    app.Get("/readyz", func(c *fiber.Ctx) error {
+        report := readiness.Check(c.UserContext())
+        if !report.Up() {
+            c.Status(fiber.StatusServiceUnavailable)
+        }
+        return c.JSON(report)
+    })
+
+    // Health check endpoint, kept for existing clients; prefer /livez and /readyz
     app.Get("/health", func(c *fiber.Ctx) error {
+        if o.lifecycle.Draining() {
+            return c.Status(fiber.StatusServiceUnavailable).SendString("shutting down")
+        }
+        if o.db != nil {
+            ctx, cancel := context.WithTimeout(c.UserContext(), healthCheckTimeout)
+            defer cancel()
+
+            if err := o.db.PingContext(ctx); err != nil {
+                return c.Status(fiber.StatusServiceUnavailable).SendString("database unavailable")
+            }
+        }
         return c.SendString("OK")
     })
 
     // Serve Swagger UI
     app.Get("/swagger/*", swagger.HandlerDefault)
 
-    // API routes
+    // API routes; every handler action is traced as a span named after it
     api := app.Group("/api/v1")
+    if o.requestTimeout > 0 {
+        api.Use(requestTimeout(o.requestTimeout))
+    }
+    if o.readYourWrites > 0 {
+        api.Use(readYourWrites(o.readYourWrites))
+    }
+
+    // Batch routes; the colon is escaped so Fiber does not read it as a parameter
+    api.Post("/entities\\:batch", tracing.Handler("EntityHandler.BatchCreateEntities", entityHandler.BatchCreateEntitiesFiber))
+    api.Put("/entities\\:batch", tracing.Handler("EntityHandler.BatchUpdateEntities", entityHandler.BatchUpdateEntitiesFiber))
+    api.Delete("/entities\\:batch", tracing.Handler("EntityHandler.BatchDeleteEntities", entityHandler.BatchDeleteEntitiesFiber))
 
     // Entity routes
     entities := api.Group("/entities")
 
-    entities.Get("/", entityHandler.GetAllEntitiesFiber)
-    entities.Post("/", entityHandler.CreateEntityFiber)
-    entities.Get("/:id", entityHandler.GetEntityByIDFiber)
-    entities.Put("/:id", entityHandler.UpdateEntityFiber)
-    entities.Delete("/:id", entityHandler.DeleteEntityFiber)
+    entities.Get("/", tracing.Handler("EntityHandler.GetAllEntities", entityHandler.GetAllEntitiesFiber))
+    entities.Post("/", tracing.Handler("EntityHandler.CreateEntity", entityHandler.CreateEntityFiber))
+    entities.Get("/search", tracing.Handler("EntityHandler.SearchEntities", entityHandler.SearchEntitiesFiber))
+    entities.Get("/trash", tracing.Handler("EntityHandler.GetDeletedEntities", entityHandler.GetDeletedEntitiesFiber))
+    entities.Delete("/trash/:id", tracing.Handler("EntityHandler.PurgeEntity", entityHandler.PurgeEntityFiber))
+    entities.Get("/:id", tracing.Handler("EntityHandler.GetEntityByID", entityHandler.GetEntityByIDFiber))
+    entities.Put("/:id", tracing.Handler("EntityHandler.UpdateEntity", entityHandler.UpdateEntityFiber))
+    entities.Patch("/:id", tracing.Handler("EntityHandler.PatchEntity", entityHandler.PatchEntityFiber))
+    entities.Delete("/:id", tracing.Handler("EntityHandler.DeleteEntity", entityHandler.DeleteEntityFiber))
+    entities.Post("/:id/restore", tracing.Handler("EntityHandler.RestoreEntity", entityHandler.RestoreEntityFiber))
 
     return app
 }
 
+// readYourWrites routes reads to the primary within window of the client's last
+// write and stamps the time of each successful write on the response
+func readYourWrites(window time.Duration) fiber.Handler {
+    return func(c *fiber.Ctx) error {
+        lastWrite := c.Get(lastWriteHeader)
+        if lastWrite == "" {
+            lastWrite = c.Cookies(lastWriteCookie)
+        }
+        if ms, err := strconv.ParseInt(lastWrite, 10, 64); err == nil {
+            if age := time.Since(time.UnixMilli(ms)); age >= 0 && age < window {
+                c.SetUserContext(repository.WithPrimary(c.UserContext()))
+            }
+        }
+
+        // Errors only become responses once the middleware returns, so the
+        // status is not final while err is set
+        err := c.Next()
+
+        if err == nil && c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead && c.Response().StatusCode() < fiber.StatusBadRequest {
+            now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+            c.Set(lastWriteHeader, now)
+            c.Cookie(&fiber.Cookie{
+                Name:     lastWriteCookie,
+                Value:    now,
+                Path:     "/",
+                MaxAge:   int((window + time.Second - 1) / time.Second),
+                HTTPOnly: true,
+                SameSite: fiber.CookieSameSiteLaxMode,
+            })
+        }
+        return err
+    }
+}
+
+// requestTimeout derives a user context with the given timeout for each request
+func requestTimeout(timeout time.Duration) fiber.Handler {
+    return func(c *fiber.Ctx) error {
+        ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+        defer cancel()
+
+        c.SetUserContext(ctx)
+        return c.Next()
+    }
+}
+
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Lifecycle tells the health check when the server is shutting down, so load
+// balancers stop sending it traffic while in-flight requests drain. The zero
+// value is a server that is not shutting down.
+type Lifecycle struct {
+	draining atomic.Bool
+}
+
+// Drain marks the server as shutting down; the health check answers 503 from now on
+func (l *Lifecycle) Drain() {
+	l.draining.Store(true)
+}
+
+// Draining reports whether the server is shutting down
+func (l *Lifecycle) Draining() bool {
+	return l != nil && l.draining.Load()
+}
+
+// Check implements health.Checker, failing once the server is shutting down
+func (l *Lifecycle) Check(ctx context.Context) (interface{}, error) {
+	if l.Draining() {
+		return nil, errors.New("shutting down")
+	}
+	return nil, nil
+}
+
+// WithLifecycle makes the health checks report not ready once lifecycle drains
+func WithLifecycle(lifecycle *Lifecycle) Option {
+	return func(o *options) {
+		o.lifecycle = lifecycle
+	}
+}
+
+// ShutdownConfig controls how Serve stops the server
+type ShutdownConfig struct {
+	// Delay is how long the server keeps accepting requests after reporting not
+	// ready, giving load balancers time to notice
+	Delay time.Duration
+	// Timeout bounds the wait for in-flight requests; connections still open
+	// afterwards are closed
+	Timeout time.Duration
+}
+
+// Serve runs app on ln until ctx ends, then shuts it down gracefully: the health
+// check reports not ready, new connections are refused after cfg.Delay, and
+// in-flight requests get up to cfg.Timeout to finish. It returns once the server
+// has stopped, so the caller can close what the requests were using.
+func Serve(ctx context.Context, app *fiber.App, ln net.Listener, lifecycle *Lifecycle, cfg ShutdownConfig) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- app.Listener(ln)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down: reporting not ready")
+	lifecycle.Drain()
+	if cfg.Delay > 0 {
+		select {
+		case err := <-errs:
+			return err
+		case <-time.After(cfg.Delay):
+		}
+	}
+
+	slog.Info("Draining in-flight requests", "timeout", cfg.Timeout.String())
+	if err := app.ShutdownWithTimeout(cfg.Timeout); err != nil {
+		return fmt.Errorf("shutting down: %w", err)
+	}
+	if err := <-errs; err != nil {
+		return err
+	}
+
+	slog.Info("Server stopped")
+	return nil
+}
@@ -0,0 +1,502 @@
+// Package config loads the server configuration from defaults, a YAML or TOML
+// file, environment variables and command-line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"learn-api/internal/database"
+	"learn-api/internal/logging"
+	"learn-api/internal/repository"
+	"learn-api/internal/tracing"
+)
+
+// FileEnv names the environment variable holding the path of the config file;
+// the -config flag takes precedence over it
+const FileEnv = "CONFIG_FILE"
+
+// redacted replaces secrets when the configuration is printed
+const redacted = "[redacted]"
+
+// Storage backends
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+// Config is the complete configuration of the server
+type Config struct {
+	Server Server
+	// Storage is where entities are kept: postgres, or memory for local development
+	Storage  string
+	Database Database
+	Log      logging.Config
+	Tracing  tracing.Config
+}
+
+// Server configures the HTTP server
+type Server struct {
+	Port string
+	// RequestTimeout bounds every API request; zero means no limit
+	RequestTimeout time.Duration
+	// ReadYourWritesWindow is how long a client reads from the primary after a write
+	// when replicas are configured; zero disables it
+	ReadYourWritesWindow time.Duration
+	// ShutdownDelay is how long the server keeps serving after a shutdown signal
+	// while its health check reports not ready
+	ShutdownDelay time.Duration
+	// ShutdownTimeout bounds the wait for in-flight requests when shutting down
+	ShutdownTimeout time.Duration
+}
+
+// Database configures the PostgreSQL connection and the repository using it
+type Database struct {
+	database.Config
+	// QueryTimeout bounds every repository call; zero means no limit
+	QueryTimeout time.Duration
+}
+
+// Default returns the configuration used for settings that are not provided
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:                 "8080",
+			RequestTimeout:       30 * time.Second,
+			ReadYourWritesWindow: 5 * time.Second,
+			ShutdownTimeout:      10 * time.Second,
+		},
+		Storage: StoragePostgres,
+		Database: Database{
+			Config:       database.DefaultConfig(),
+			QueryTimeout: repository.DefaultQueryTimeout,
+		},
+		Log:     logging.DefaultConfig(),
+		Tracing: tracing.DefaultConfig(),
+	}
+}
+
+// setting binds one configuration value to its config file key, environment
+// variable and command-line flag
+type setting struct {
+	key    string // dotted path in the config file
+	env    string
+	usage  string
+	secret bool
+	target interface{} // *string, *int, *float64, *time.Duration or *[]string
+}
+
+// flagName is the command-line flag of the setting, derived from its environment
+// variable: DB_MAX_OPEN_CONNS becomes -db-max-open-conns
+func (s setting) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(s.env), "_", "-")
+}
+
+// settings lists every setting of c in the order they are printed
+func (c *Config) settings() []setting {
+	db := &c.Database
+	return []setting{
+		{"server.port", "PORT", "port the HTTP server listens on", false, &c.Server.Port},
+		{"server.request_timeout", "REQUEST_TIMEOUT", "limit for every API request, 0 for none", false, &c.Server.RequestTimeout},
+		{"server.read_your_writes_window", "READ_YOUR_WRITES_WINDOW", "how long a client reads from the primary after a write", false, &c.Server.ReadYourWritesWindow},
+		{"server.shutdown_delay", "SHUTDOWN_DELAY", "how long to keep serving after a shutdown signal while reporting not ready", false, &c.Server.ShutdownDelay},
+		{"server.shutdown_timeout", "SHUTDOWN_TIMEOUT", "how long to wait for in-flight requests when shutting down", false, &c.Server.ShutdownTimeout},
+		{"storage", "STORAGE", "where entities are kept: postgres or memory", false, &c.Storage},
+		{"database.url", "DATABASE_URL", "connection URL, replacing host, port, user, password and name", true, &db.URL},
+		{"database.host", "DB_HOST", "database host", false, &db.Host},
+		{"database.port", "DB_PORT", "database port", false, &db.Port},
+		{"database.user", "DB_USER", "database user", false, &db.User},
+		{"database.password", "DB_PASSWORD", "database password", true, &db.Password},
+		{"database.name", "DB_NAME", "database name", false, &db.Name},
+		{"database.sslmode", "DB_SSLMODE", "disable, require, verify-ca or verify-full", false, &db.SSLMode},
+		{"database.sslrootcert", "DB_SSLROOTCERT", "CA certificate for verify-ca and verify-full", false, &db.SSLRootCert},
+		{"database.application_name", "DB_APPLICATION_NAME", "name shown in pg_stat_activity", false, &db.ApplicationName},
+		{"database.statement_timeout", "DB_STATEMENT_TIMEOUT", "server-side statement limit, 0 for none", false, &db.StatementTimeout},
+		{"database.query_timeout", "QUERY_TIMEOUT", "limit for every repository call, 0 for none", false, &db.QueryTimeout},
+		{"database.max_open_conns", "DB_MAX_OPEN_CONNS", "maximum open connections", false, &db.MaxOpenConns},
+		{"database.max_idle_conns", "DB_MAX_IDLE_CONNS", "maximum idle connections", false, &db.MaxIdleConns},
+		{"database.conn_max_lifetime", "DB_CONN_MAX_LIFETIME", "maximum lifetime of a connection", false, &db.ConnMaxLifetime},
+		{"database.conn_max_idle_time", "DB_CONN_MAX_IDLE_TIME", "maximum idle time of a connection", false, &db.ConnMaxIdleTime},
+		{"database.connect_attempts", "DB_CONNECT_ATTEMPTS", "attempts to reach the database at startup", false, &db.ConnectAttempts},
+		{"database.connect_backoff", "DB_CONNECT_BACKOFF", "wait after the first failed attempt, doubling after each one", false, &db.ConnectBackoff},
+		{"database.replica_urls", "DATABASE_REPLICA_URLS", "comma-separated connection URLs of read replicas", true, &db.ReplicaURLs},
+		{"database.replica_health_interval", "DB_REPLICA_HEALTH_INTERVAL", "how often replicas are health checked", false, &db.ReplicaHealthInterval},
+		{"log.level", "LOG_LEVEL", "least severe level logged: debug, info, warn or error", false, &c.Log.Level},
+		{"log.format", "LOG_FORMAT", "log line format: json or text", false, &c.Log.Format},
+		{"tracing.exporter", "TRACING_EXPORTER", "where spans go: none or otlp", false, &c.Tracing.Exporter},
+		{"tracing.endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTLP/HTTP collector URL", false, &c.Tracing.Endpoint},
+		{"tracing.service_name", "OTEL_SERVICE_NAME", "service name reported in traces", false, &c.Tracing.ServiceName},
+		{"tracing.sample_ratio", "TRACING_SAMPLE_RATIO", "fraction of new traces recorded, 0 to 1", false, &c.Tracing.SampleRatio},
+	}
+}
+
+// set parses value into the setting
+func (s setting) set(value string) error {
+	switch target := s.target.(type) {
+	case *string:
+		*target = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: expected an integer", s.key, value)
+		}
+		*target = n
+	case *float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: expected a number", s.key, value)
+		}
+		*target = f
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: expected a duration such as 5s", s.key, value)
+		}
+		*target = d
+	case *[]string:
+		*target = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*target = append(*target, item)
+			}
+		}
+	}
+	return nil
+}
+
+// values returns the setting's value for printing, with secrets redacted
+func (s setting) values() []string {
+	var values []string
+	switch target := s.target.(type) {
+	case *string:
+		values = []string{*target}
+	case *int:
+		values = []string{strconv.Itoa(*target)}
+	case *float64:
+		values = []string{strconv.FormatFloat(*target, 'g', -1, 64)}
+	case *time.Duration:
+		values = []string{target.String()}
+	case *[]string:
+		values = append(values, *target...)
+	}
+
+	if s.secret {
+		for i, value := range values {
+			values[i] = redact(value)
+		}
+	}
+	return values
+}
+
+// redact hides a secret value. Connection URLs keep everything but their password.
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return redacted
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	query := u.Query()
+	if query.Has("password") {
+		query.Set("password", redacted)
+		u.RawQuery = query.Encode()
+	}
+	// Keep the marker readable instead of percent-encoded
+	return strings.ReplaceAll(u.String(), url.QueryEscape(redacted), redacted)
+}
+
+// Load builds the configuration from, in increasing order of precedence:
+// the defaults, the YAML or TOML file named by -config or CONFIG_FILE, environment
+// variables and command-line flags. Every environment variable can instead be
+// given as a file holding the value, named by the variable with a _FILE suffix,
+// such as DB_PASSWORD_FILE for secrets mounted by Docker or Kubernetes.
+//
+// Arguments following the flags, such as a subcommand, are returned. Load fails
+// on unknown settings, values that cannot be parsed and invalid configurations;
+// it returns flag.ErrHelp when -h or -help is given.
+func Load(args []string) (Config, []string, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	// Parse the flags first to learn the config file, but apply them last
+	flags := flag.NewFlagSet("api", flag.ContinueOnError)
+	file := flags.String("config", "", "YAML or TOML config file (env "+FileEnv+")")
+	var set []setting
+	var values []string
+	for _, s := range settings {
+		s := s
+		flags.Func(s.flagName(), s.usage+" (env "+s.env+")", func(value string) error {
+			set = append(set, s)
+			values = append(values, value)
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+
+	path := *file
+	if path == "" {
+		path = os.Getenv(FileEnv)
+	}
+	if path != "" {
+		if err := loadFile(path, settings); err != nil {
+			return cfg, nil, err
+		}
+	}
+
+	if err := loadEnv(settings); err != nil {
+		return cfg, nil, err
+	}
+
+	for i, s := range set {
+		if err := s.set(values[i]); err != nil {
+			return cfg, nil, fmt.Errorf("flag -%s: %w", s.flagName(), err)
+		}
+	}
+
+	return cfg, flags.Args(), cfg.Validate()
+}
+
+// loadFile applies the settings found in a YAML or TOML config file. The
+// format is told by the extension, and files of other formats are rejected
+// rather than misread.
+func loadFile(path string, settings []setting) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".yaml", ".yml", ".toml":
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q, only YAML files ending in .yaml or .yml and TOML files ending in .toml are supported", path, ext)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	values := map[string]string{}
+	if ext == ".toml" {
+		var doc map[string]interface{}
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+		if err := flattenTOML(doc, "", values); err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	} else {
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		if err := flatten(doc.Content[0], "", values); err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := values[s.key]; ok {
+			if err := s.set(value); err != nil {
+				return fmt.Errorf("config file %s: %w", path, err)
+			}
+			delete(values, s.key)
+		}
+	}
+	for key := range values {
+		return fmt.Errorf("config file %s: unknown setting %s", path, key)
+	}
+	return nil
+}
+
+// flatten collects the values of a YAML mapping by their dotted keys.
+// Lists are joined with commas, the way they are given in the environment.
+func flatten(node *yaml.Node, prefix string, values map[string]string) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			if err := flatten(node.Content[i+1], key, values); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("%s must be a list of values", prefix)
+			}
+			items = append(items, item.Value)
+		}
+		values[prefix] = strings.Join(items, ",")
+	case yaml.ScalarNode:
+		if prefix == "" {
+			return errors.New("expected a mapping of settings")
+		}
+		values[prefix] = node.Value
+	default:
+		return fmt.Errorf("unsupported value for %s", prefix)
+	}
+	return nil
+}
+
+// flattenTOML collects the values of a TOML table by their dotted keys, like
+// flatten. Durations are written as strings, such as "30s".
+func flattenTOML(value interface{}, prefix string, values map[string]string) error {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			if err := flattenTOML(item, key, values); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				return fmt.Errorf("%s must be a list of values", prefix)
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		values[prefix] = strings.Join(items, ",")
+	case string, int64, float64, bool:
+		values[prefix] = fmt.Sprint(value)
+	default:
+		return fmt.Errorf("unsupported value for %s", prefix)
+	}
+	return nil
+}
+
+// loadEnv applies the settings found in environment variables, or in the files
+// named by their _FILE variants. Empty variables count as unset.
+func loadEnv(settings []setting) error {
+	for _, s := range settings {
+		value := os.Getenv(s.env)
+
+		if path := os.Getenv(s.env + "_FILE"); path != "" {
+			if value != "" {
+				return fmt.Errorf("both %s and %s_FILE are set", s.env, s.env)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("reading %s_FILE: %w", s.env, err)
+			}
+			value = strings.TrimRight(string(data), "\r\n")
+		}
+
+		if value == "" {
+			continue
+		}
+		if err := s.set(value); err != nil {
+			return fmt.Errorf("%s: %w", s.env, err)
+		}
+	}
+	return nil
+}
+
+// Validate reports every setting that can never work
+func (c Config) Validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid server.port %q: expected 1-65535", c.Server.Port))
+	}
+	if c.Server.RequestTimeout < 0 || c.Server.ReadYourWritesWindow < 0 || c.Server.ShutdownDelay < 0 || c.Database.QueryTimeout < 0 {
+		errs = append(errs, errors.New("server and query timeouts must not be negative"))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("server.shutdown_timeout must be positive"))
+	}
+
+	switch c.Storage {
+	case StoragePostgres:
+		if err := c.Database.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	case StorageMemory:
+	default:
+		errs = append(errs, fmt.Errorf("invalid storage %q: expected %s or %s", c.Storage, StoragePostgres, StorageMemory))
+	}
+
+	if err := c.Log.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// String returns the configuration as a YAML config file, with passwords and
+// connection URL credentials redacted
+func (c Config) String() string {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range c.settings() {
+		parent := root
+		parts := strings.Split(s.key, ".")
+		for _, part := range parts[:len(parts)-1] {
+			parent = child(parent, part)
+		}
+
+		var value *yaml.Node
+		if _, ok := s.target.(*[]string); ok {
+			value = &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			for _, item := range s.values() {
+				value.Content = append(value.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: item})
+			}
+		} else {
+			value = &yaml.Node{Kind: yaml.ScalarNode, Value: s.values()[0]}
+		}
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: parts[len(parts)-1]}, value)
+	}
+
+	var out strings.Builder
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return fmt.Sprintf("unprintable configuration: %v", err)
+	}
+	return out.String()
+}
+
+// child returns the mapping stored under key in parent, adding it when missing
+func child(parent *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			return parent.Content[i+1]
+		}
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, node)
+	return node
+}
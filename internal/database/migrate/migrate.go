@@ -0,0 +1,387 @@
+// Package migrate applies versioned schema migrations to the PostgreSQL database.
+//
+// Migrations are pairs of SQL files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql. The ones in the migrations directory are embedded
+// into the binary, and the versions applied to a database are recorded in its
+// schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceDir is the directory, relative to the repository root, that holds the embedded migrations
+const SourceDir = "internal/database/migrate/migrations"
+
+// DefaultTable is the table recording applied migrations
+const DefaultTable = "schema_migrations"
+
+// lockKey identifies the advisory lock held while migrating
+const lockKey int64 = 7_452_311_016
+
+//go:embed migrations/*.sql
+var embedded embed.FS
+
+// fileName matches migration files and captures version, name and direction
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// nonSlug matches the runs of characters replaced when naming a migration file
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Migration is a versioned schema change and its inverse
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes a migration and whether it has been applied
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies and reverts migrations
+type Migrator interface {
+	// Up applies every pending migration in version order and returns those applied
+	Up(ctx context.Context) ([]Migration, error)
+	// Down reverts the given number of most recently applied migrations, newest first
+	Down(ctx context.Context, steps int) ([]Migration, error)
+	// Status reports every known migration and when it was applied
+	Status(ctx context.Context) ([]Status, error)
+	// Pending reports the migrations not applied yet in version order. Unlike the
+	// other operations it neither takes the lock nor creates the tracking table, so
+	// it is cheap enough for health checks.
+	Pending(ctx context.Context) ([]Migration, error)
+}
+
+// queryer runs queries on a database or a single connection
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// migrator implements Migrator.
+// Every operation holds a PostgreSQL advisory lock, so concurrent runners take turns.
+type migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	table      string
+}
+
+// Option configures a migrator
+type Option func(*migrator)
+
+// WithTable records applied migrations in the given table instead of DefaultTable
+func WithTable(table string) Option {
+	return func(m *migrator) {
+		m.table = table
+	}
+}
+
+// NewMigrator creates a migrator applying migrations to db
+func NewMigrator(db *sql.DB, migrations []Migration, opts ...Option) Migrator {
+	m := &migrator{
+		db:         db,
+		migrations: migrations,
+		table:      DefaultTable,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Source returns the migrations embedded in the binary
+func Source() fs.FS {
+	source, _ := fs.Sub(embedded, "migrations")
+	return source
+}
+
+// Load reads the migrations in the root of source, ordered by version.
+// Every migration needs both an up and a down file.
+func Load(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		name, direction := match[2], match[3]
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, migration.Name, name)
+		}
+
+		content, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Create writes an empty up and down migration to dir, numbered after the
+// latest migration there, and returns their paths
+func Create(dir, name string) ([]string, error) {
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if slug == "" {
+		return nil, fmt.Errorf("invalid migration name %q", name)
+	}
+
+	migrations, err := Load(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	version := 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	var paths []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%04d_%s.%s.sql", version, slug, direction))
+		content := fmt.Sprintf("-- Migration %04d_%s (%s)\n", version, slug, direction)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return paths, err
+		}
+		_, err = file.WriteString(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// Up applies every pending migration in its own transaction
+func (m *migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+
+			record := `INSERT INTO ` + m.table + ` (version, name) VALUES ($1, $2)`
+			if err := m.run(ctx, conn, migration, migration.Up, record, migration.Version, migration.Name); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the most recently applied migrations in their own transactions
+func (m *migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int, 0, len(done))
+		for version := range done {
+			versions = append(versions, version)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+		for i := 0; i < steps && i < len(versions); i++ {
+			migration, ok := m.find(versions[i])
+			if !ok {
+				return fmt.Errorf("migration %d is applied but unknown to this build", versions[i])
+			}
+
+			record := `DELETE FROM ` + m.table + ` WHERE version = $1`
+			if err := m.run(ctx, conn, migration, migration.Down, record, migration.Version); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status reports every known migration and when it was applied
+func (m *migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if appliedAt, ok := done[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Pending reports the migrations not applied yet in version order
+func (m *migrator) Pending(ctx context.Context) ([]Migration, error) {
+	var table sql.NullString
+	if err := m.db.QueryRowContext(ctx, `SELECT to_regclass($1)::text`, m.table).Scan(&table); err != nil {
+		return nil, err
+	}
+
+	// Nothing was ever migrated while the tracking table is missing
+	done := map[int]time.Time{}
+	if table.Valid {
+		var err error
+		if done, err = m.applied(ctx, m.db); err != nil {
+			return nil, err
+		}
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := done[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// withLock runs fn on a single connection holding the migration advisory lock,
+// after making sure the tracking table exists
+func (m *migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Advisory locks belong to the session, so lock and unlock on the same connection
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+m.table+` (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", m.table, err)
+	}
+
+	return fn(conn)
+}
+
+// applied returns the applied migration versions and when they were applied
+func (m *migrator) applied(ctx context.Context, conn queryer) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM `+m.table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = appliedAt
+	}
+	return done, rows.Err()
+}
+
+// run executes a migration script and updates the tracking table in one transaction
+func (m *migrator) run(ctx context.Context, conn *sql.Conn, migration Migration, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// Without arguments the script runs as a simple query, which allows several statements
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// find returns the known migration with the given version
+func (m *migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
@@ -0,0 +1,3 @@
+-- The pg_trgm extension is left installed, as other schemas may use it
+DROP TABLE IF EXISTS entities;
+DROP FUNCTION IF EXISTS update_updated_at_column();
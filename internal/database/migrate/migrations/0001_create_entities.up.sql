@@ -0,0 +1,42 @@
+-- Baseline schema, formerly init.sql. Every statement is idempotent so that
+-- databases initialised by init.sql can adopt the migration history.
+
+-- Enable trigram matching for fuzzy name search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Create entities table
+CREATE TABLE IF NOT EXISTS entities (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP
+);
+
+-- Columns added to init.sql after its first release
+ALTER TABLE entities ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE entities ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- Index the trash so listing deleted entities stays cheap
+CREATE INDEX IF NOT EXISTS idx_entities_deleted_at ON entities (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Indexes backing full-text and trigram name search
+CREATE INDEX IF NOT EXISTS idx_entities_name_fts ON entities USING GIN (to_tsvector('simple', name));
+CREATE INDEX IF NOT EXISTS idx_entities_name_trgm ON entities USING GIN (name gin_trgm_ops);
+
+-- Create a function to update the updated_at timestamp
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- Create a trigger to automatically update the updated_at column
+DROP TRIGGER IF EXISTS update_entities_updated_at ON entities;
+CREATE TRIGGER update_entities_updated_at
+    BEFORE UPDATE ON entities
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pool is a pool of connections to the primary PostgreSQL database and its read replicas.
+// It embeds the primary *sql.DB, so it can be handed to anything that runs queries.
+type Pool struct {
+	*sql.DB
+
+	replicas []*replica
+	next     atomic.Uint64
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// replica is a read replica and the outcome of its latest health check
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// NewPool creates a pool over an open primary and replicas.
+// Replicas count as healthy until a health check finds otherwise.
+func NewPool(primary *sql.DB, replicas ...*sql.DB) *Pool {
+	p := &Pool{
+		DB:   primary,
+		stop: make(chan struct{}),
+	}
+	for _, db := range replicas {
+		r := &replica{db: db}
+		r.healthy.Store(true)
+		p.replicas = append(p.replicas, r)
+	}
+	return p
+}
+
+// Replica returns the next healthy replica in round-robin order, or the
+// primary when there are no healthy replicas
+func (p *Pool) Replica() *sql.DB {
+	n := uint64(len(p.replicas))
+	for i := uint64(0); i < n; i++ {
+		r := p.replicas[(p.next.Add(1)-1)%n]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return p.DB
+}
+
+// ReplicaCount returns the number of configured replicas, healthy or not
+func (p *Pool) ReplicaCount() int {
+	return len(p.replicas)
+}
+
+// Replicas returns the connection pools of every configured replica, healthy or not
+func (p *Pool) Replicas() []*sql.DB {
+	dbs := make([]*sql.DB, len(p.replicas))
+	for i, r := range p.replicas {
+		dbs[i] = r.db
+	}
+	return dbs
+}
+
+// HealthyReplicaCount returns the number of replicas currently in rotation
+func (p *Pool) HealthyReplicaCount() int {
+	healthy := 0
+	for _, r := range p.replicas {
+		if r.healthy.Load() {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+// CheckReplicas pings every replica and records which ones answer
+func (p *Pool) CheckReplicas(ctx context.Context) {
+	for i, r := range p.replicas {
+		healthy := r.db.PingContext(ctx) == nil
+		if r.healthy.Swap(healthy) != healthy {
+			if healthy {
+				slog.InfoContext(ctx, "Database replica is back in rotation", "replica", i+1)
+			} else {
+				slog.WarnContext(ctx, "Database replica failed its health check; reading from the others", "replica", i+1)
+			}
+		}
+	}
+}
+
+// watchReplicas runs CheckReplicas every interval until the pool is closed
+func (p *Pool) watchReplicas(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			p.CheckReplicas(ctx)
+			cancel()
+		}
+	}
+}
+
+// Close stops the replica health checks and closes every connection
+func (p *Pool) Close() error {
+	p.closeOnce.Do(func() {
+		if p.stop != nil {
+			close(p.stop)
+		}
+	})
+
+	for _, r := range p.replicas {
+		r.db.Close()
+	}
+	return p.DB.Close()
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"learn-api/internal/models"
+	"learn-api/pkg/errors"
+	"learn-api/pkg/i18n"
+	"learn-api/pkg/validation"
+)
+
+// BatchCreateEntities handles POST /api/v1/entities:batch request
+func (h *EntityHandler) BatchCreateEntities(w http.ResponseWriter, r *http.Request) {
+	var req models.BatchCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	// Validate request
+	validationErrors := validation.Struct(&req)
+	if len(validationErrors) > 0 {
+		err := validation.ToAPIError(validationErrors)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	result, err := h.service.CreateEntities(r.Context(), &req)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	h.writeBatchResponse(w, r, result, http.StatusCreated)
+}
+
+// BatchUpdateEntities handles PUT /api/v1/entities:batch request
+func (h *EntityHandler) BatchUpdateEntities(w http.ResponseWriter, r *http.Request) {
+	var req models.BatchUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	// Validate request
+	validationErrors := validation.Struct(&req)
+	if len(validationErrors) > 0 {
+		err := validation.ToAPIError(validationErrors)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	result, err := h.service.UpdateEntities(r.Context(), &req)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	h.writeBatchResponse(w, r, result, http.StatusOK)
+}
+
+// BatchDeleteEntities handles DELETE /api/v1/entities:batch request
+func (h *EntityHandler) BatchDeleteEntities(w http.ResponseWriter, r *http.Request) {
+	var req models.BatchDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	// Validate request
+	validationErrors := validation.Struct(&req)
+	if len(validationErrors) > 0 {
+		err := validation.ToAPIError(validationErrors)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	result, err := h.service.DeleteEntities(r.Context(), &req)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	h.writeBatchResponse(w, r, result, http.StatusOK)
+}
+
+// writeBatchResponse writes a batch result with the status chosen by batchStatus
+func (h *EntityHandler) writeBatchResponse(w http.ResponseWriter, r *http.Request, result *models.BatchResult, success int) {
+	status := batchStatus(result, success)
+	localizeBatch(result, i18n.Negotiate(r.Header.Get("Accept-Language")))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(batchEnvelope(result))
+}
+
+// BatchCreateEntitiesFiber handles POST /api/v1/entities:batch request for Fiber
+// @Summary Create entities in bulk
+// @Description Create up to 1000 entities in one request. Atomic batches run in a single transaction; otherwise each item is applied independently. Every item gets its own status and error.
+// @Tags entities
+// @Accept json
+// @Produce json
+// @Param batch body models.BatchCreateRequest true "Entities to create"
+// @Success 201 {object} map[string]interface{}
+// @Success 207 {object} map[string]interface{} "Some items failed"
+// @Failure 400 {object} map[string]interface{}
+// @Router /entities:batch [post]
+func (h *EntityHandler) BatchCreateEntitiesFiber(c *fiber.Ctx) error {
+	var req models.BatchCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		return h.writeErrorFiber(c, err)
+	}
+
+	// Validate request
+	validationErrors := validation.Struct(&req)
+	if len(validationErrors) > 0 {
+		err := validation.ToAPIError(validationErrors)
+		return h.writeErrorFiber(c, err)
+	}
+
+	result, err := h.service.CreateEntities(c.UserContext(), &req)
+	if err != nil {
+		return h.handleErrorFiber(c, err)
+	}
+
+	status := batchStatus(result, fiber.StatusCreated)
+	localizeBatch(result, language(c))
+	return c.Status(status).JSON(batchEnvelope(result))
+}
+
+// BatchUpdateEntitiesFiber handles PUT /api/v1/entities:batch request for Fiber
+// @Summary Update entities in bulk
+// @Description Update up to 1000 entities in one request. Items may carry a version to make their update conditional.
+// @Tags entities
+// @Accept json
+// @Produce json
+// @Param batch body models.BatchUpdateRequest true "Entity updates"
+// @Success 200 {object} map[string]interface{}
+// @Success 207 {object} map[string]interface{} "Some items failed"
+// @Failure 400 {object} map[string]interface{}
+// @Router /entities:batch [put]
+func (h *EntityHandler) BatchUpdateEntitiesFiber(c *fiber.Ctx) error {
+	var req models.BatchUpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		return h.writeErrorFiber(c, err)
+	}
+
+	// Validate request
+	validationErrors := validation.Struct(&req)
+	if len(validationErrors) > 0 {
+		err := validation.ToAPIError(validationErrors)
+		return h.writeErrorFiber(c, err)
+	}
+
+	result, err := h.service.UpdateEntities(c.UserContext(), &req)
+	if err != nil {
+		return h.handleErrorFiber(c, err)
+	}
+
+	status := batchStatus(result, fiber.StatusOK)
+	localizeBatch(result, language(c))
+	return c.Status(status).JSON(batchEnvelope(result))
+}
+
+// BatchDeleteEntitiesFiber handles DELETE /api/v1/entities:batch request for Fiber
+// @Summary Delete entities in bulk
+// @Description Move up to 1000 entities to the trash in one request
+// @Tags entities
+// @Accept json
+// @Produce json
+// @Param batch body models.BatchDeleteRequest true "Entities to delete"
+// @Success 200 {object} map[string]interface{}
+// @Success 207 {object} map[string]interface{} "Some items failed"
+// @Failure 400 {object} map[string]interface{}
+// @Router /entities:batch [delete]
+func (h *EntityHandler) BatchDeleteEntitiesFiber(c *fiber.Ctx) error {
+	var req models.BatchDeleteRequest
+	if err := c.BodyParser(&req); err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		return h.writeErrorFiber(c, err)
+	}
+
+	// Validate request
+	validationErrors := validation.Struct(&req)
+	if len(validationErrors) > 0 {
+		err := validation.ToAPIError(validationErrors)
+		return h.writeErrorFiber(c, err)
+	}
+
+	result, err := h.service.DeleteEntities(c.UserContext(), &req)
+	if err != nil {
+		return h.handleErrorFiber(c, err)
+	}
+
+	status := batchStatus(result, fiber.StatusOK)
+	localizeBatch(result, language(c))
+	return c.Status(status).JSON(batchEnvelope(result))
+}
+
+// batchStatus picks the response status of a batch: success when every item
+// was applied, the failing item's status when an atomic batch was rolled back,
+// and 207 Multi-Status when a best-effort batch partially failed
+func batchStatus(result *models.BatchResult, success int) int {
+	if result.Failed == 0 {
+		return success
+	}
+
+	if result.Atomic {
+		for _, item := range result.Results {
+			if item.Error != nil && item.Error.Code() != errors.CodeBatchAborted {
+				return item.Status
+			}
+		}
+	}
+
+	return http.StatusMultiStatus
+}
+
+// localizeBatch puts the errors of the items of a batch into lang
+func localizeBatch(result *models.BatchResult, lang i18n.Language) {
+	for i, item := range result.Results {
+		if item.Error != nil {
+			result.Results[i].Error = validation.Localize(item.Error, lang)
+		}
+	}
+}
+
+// batchEnvelope builds the response body of a batch request
+func batchEnvelope(result *models.BatchResult) map[string]interface{} {
+	return map[string]interface{}{
+		"data":      result.Results,
+		"atomic":    result.Atomic,
+		"succeeded": result.Succeeded,
+		"failed":    result.Failed,
+	}
+}
@@ -1,15 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 
+	"learn-api/internal/logging"
 	"learn-api/internal/models"
 	"learn-api/internal/services"
 	"learn-api/pkg/errors"
+	"learn-api/pkg/i18n"
 	"learn-api/pkg/validation"
 )
 
@@ -29,26 +37,26 @@ func NewEntityHandler(service services.EntityService) *EntityHandler {
 func (h *EntityHandler) CreateEntity(w http.ResponseWriter, r *http.Request) {
 	var req models.EntityRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		err := errors.ErrInvalidRequest
-		h.writeErrorResponse(w, err)
+		err := errors.New(errors.CodeInvalidRequest)
+		h.writeErrorResponse(w, r, err)
 		return
 	}
 
 	// Validate request
-	validationErrors := validation.ValidateEntityRequest(req.Name)
+	validationErrors := validation.Struct(&req)
 	if len(validationErrors) > 0 {
 		err := validation.ToAPIError(validationErrors)
-		h.writeErrorResponse(w, err)
+		h.writeErrorResponse(w, r, err)
 		return
 	}
 
-	entity, err := h.service.CreateEntity(&req)
+	entity, err := h.service.CreateEntity(r.Context(), &req)
 	if err != nil {
-		apiErr := errors.HandleError(err)
-		h.writeErrorResponse(w, apiErr)
+		h.handleError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", entityETag(entity.Version))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -60,23 +68,28 @@ func (h *EntityHandler) CreateEntity(w http.ResponseWriter, r *http.Request) {
 func (h *EntityHandler) GetEntityByID(w http.ResponseWriter, r *http.Request) {
 	// Extract ID from URL path
 	idStr := r.URL.Path[len("/api/v1/entities/"):]
-	id, err := strconv.Atoi(idStr)
+	id, err := parseID(idStr)
 	if err != nil {
-		err := errors.ErrInvalidRequest
-		h.writeErrorResponse(w, err)
+		err := errors.New(errors.CodeInvalidRequest)
+		h.writeErrorResponse(w, r, err)
 		return
 	}
 
-	entity, err := h.service.GetEntityByID(id)
+	entity, err := h.service.GetEntityByID(r.Context(), id)
 	if err != nil {
-		apiErr := errors.HandleError(err)
-		h.writeErrorResponse(w, apiErr)
+		h.handleError(w, r, err)
 		return
 	}
 
 	if entity == nil {
-		err := errors.ErrEntityNotFound
-		h.writeErrorResponse(w, err)
+		err := errors.New(errors.CodeEntityNotFound)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", entityETag(entity.Version))
+	if noneMatch(r.Header.Get("If-None-Match"), entity.Version) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
@@ -89,18 +102,61 @@ func (h *EntityHandler) GetEntityByID(w http.ResponseWriter, r *http.Request) {
 
 // GetAllEntities handles GET /api/v1/entities request
 func (h *EntityHandler) GetAllEntities(w http.ResponseWriter, r *http.Request) {
-	entities, err := h.service.GetAllEntities()
+	h.listEntities(w, r, h.service.GetAllEntities)
+}
+
+// GetDeletedEntities handles GET /api/v1/entities/trash request
+func (h *EntityHandler) GetDeletedEntities(w http.ResponseWriter, r *http.Request) {
+	h.listEntities(w, r, h.service.GetDeletedEntities)
+}
+
+// listEntities writes a paginated entity listing fetched by fetch
+func (h *EntityHandler) listEntities(w http.ResponseWriter, r *http.Request, fetch func(context.Context, models.ListParams) (*models.EntityPage, error)) {
+	query := r.URL.Query()
+	params, validationErrors := parseListParams(query)
+	if len(validationErrors) > 0 {
+		err := validation.ToAPIError(validationErrors)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	page, err := fetch(r.Context(), params)
 	if err != nil {
-		apiErr := errors.HandleError(err)
-		h.writeErrorResponse(w, apiErr)
+		h.handleError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"data":  entities,
-		"count": len(entities),
+		"data":  page.Entities,
+		"count": len(page.Entities),
+		"total": page.Total,
+		"limit": params.Limit,
+		"links": buildPageLinks(r.URL.Path, query, params, page),
+	})
+}
+
+// SearchEntities handles GET /api/v1/entities/search request
+func (h *EntityHandler) SearchEntities(w http.ResponseWriter, r *http.Request) {
+	q, limit, validationErrors := parseSearchParams(r.URL.Query())
+	if len(validationErrors) > 0 {
+		err := validation.ToAPIError(validationErrors)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	results, err := h.service.SearchEntities(r.Context(), q, limit)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":  results,
+		"count": len(results),
 	})
 }
 
@@ -108,41 +164,92 @@ func (h *EntityHandler) GetAllEntities(w http.ResponseWriter, r *http.Request) {
 func (h *EntityHandler) UpdateEntity(w http.ResponseWriter, r *http.Request) {
 	// Extract ID from URL path
 	idStr := r.URL.Path[len("/api/v1/entities/"):]
-	id, err := strconv.Atoi(idStr)
+	id, err := parseID(idStr)
 	if err != nil {
-		err := errors.ErrInvalidRequest
-		h.writeErrorResponse(w, err)
+		err := errors.New(errors.CodeInvalidRequest)
+		h.writeErrorResponse(w, r, err)
 		return
 	}
 
 	var req models.EntityRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		err := errors.ErrInvalidRequest
-		h.writeErrorResponse(w, err)
+		err := errors.New(errors.CodeInvalidRequest)
+		h.writeErrorResponse(w, r, err)
 		return
 	}
 
 	// Validate request
-	validationErrors := validation.ValidateEntityRequest(req.Name)
+	validationErrors := validation.Struct(&req)
 	if len(validationErrors) > 0 {
 		err := validation.ToAPIError(validationErrors)
-		h.writeErrorResponse(w, err)
+		h.writeErrorResponse(w, r, err)
 		return
 	}
 
-	entity, err := h.service.UpdateEntity(id, &req)
+	version, err := h.ifMatchVersion(r.Context(), r.Header.Get("If-Match"), id)
 	if err != nil {
-		apiErr := errors.HandleError(err)
-		h.writeErrorResponse(w, apiErr)
+		h.handleError(w, r, err)
+		return
+	}
+
+	entity, err := h.service.UpdateEntity(r.Context(), id, &req, version)
+	if err != nil {
+		h.handleError(w, r, err)
 		return
 	}
 
 	if entity == nil {
-		err := errors.ErrEntityNotFound
-		h.writeErrorResponse(w, err)
+		err := errors.New(errors.CodeEntityNotFound)
+		h.writeErrorResponse(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", entityETag(entity.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": entity,
+	})
+}
+
+// PatchEntity handles PATCH /api/v1/entities/{id} request
+func (h *EntityHandler) PatchEntity(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL path
+	idStr := r.URL.Path[len("/api/v1/entities/"):]
+	id, err := parseID(idStr)
+	if err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	contentType, ok := patchContentType(r.Header.Get("Content-Type"))
+	if !ok {
+		err := errors.New(errors.CodeUnsupportedMediaType)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	document, err := io.ReadAll(r.Body)
+	if err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	version, err := h.ifMatchVersion(r.Context(), r.Header.Get("If-Match"), id)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	entity, err := h.service.PatchEntity(r.Context(), id, &models.EntityPatch{ContentType: contentType, Document: document}, version)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", entityETag(entity.Version))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -154,17 +261,22 @@ func (h *EntityHandler) UpdateEntity(w http.ResponseWriter, r *http.Request) {
 func (h *EntityHandler) DeleteEntity(w http.ResponseWriter, r *http.Request) {
 	// Extract ID from URL path
 	idStr := r.URL.Path[len("/api/v1/entities/"):]
-	id, err := strconv.Atoi(idStr)
+	id, err := parseID(idStr)
+	if err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	version, err := h.ifMatchVersion(r.Context(), r.Header.Get("If-Match"), id)
 	if err != nil {
-		err := errors.ErrInvalidRequest
-		h.writeErrorResponse(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
-	err = h.service.DeleteEntity(id)
+	err = h.service.DeleteEntity(r.Context(), id, version)
 	if err != nil {
-		apiErr := errors.HandleError(err)
-		h.writeErrorResponse(w, apiErr)
+		h.handleError(w, r, err)
 		return
 	}
 
@@ -172,34 +284,210 @@ func (h *EntityHandler) DeleteEntity(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// writeErrorResponse writes a structured error response
-func (h *EntityHandler) writeErrorResponse(w http.ResponseWriter, err *errors.APIError) {
+// RestoreEntity handles POST /api/v1/entities/{id}/restore request
+func (h *EntityHandler) RestoreEntity(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL path
+	idStr := strings.TrimSuffix(r.URL.Path[len("/api/v1/entities/"):], "/restore")
+	id, err := parseID(idStr)
+	if err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	entity, err := h.service.RestoreEntity(r.Context(), id)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", entityETag(entity.Version))
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(err.Code)
+	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error": err,
+		"data": entity,
 	})
 }
 
+// PurgeEntity handles DELETE /api/v1/entities/trash/{id} request
+func (h *EntityHandler) PurgeEntity(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL path
+	idStr := r.URL.Path[len("/api/v1/entities/trash/"):]
+	id, err := parseID(idStr)
+	if err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		h.writeErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.service.PurgeEntity(r.Context(), id)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeErrorResponse writes a structured error response reporting the request
+// ID, in the format and language the request asks for, see newErrorResponse
+func (h *EntityHandler) writeErrorResponse(w http.ResponseWriter, r *http.Request, err *errors.APIError) {
+	resp := newErrorResponse(err, r.URL.Path, logging.RequestID(r.Context()), r.Header.Get("Accept"), r.Header.Get("Accept-Language"))
+	for key, value := range resp.headers {
+		w.Header().Set(key, value)
+	}
+	for _, header := range errorVary {
+		w.Header().Add("Vary", header)
+	}
+	w.Header().Set("Content-Type", resp.contentType)
+	w.WriteHeader(resp.status)
+	json.NewEncoder(w).Encode(resp.body)
+}
+
+// writeErrorFiber writes a structured error response reporting the request ID,
+// in the format and language the request asks for, see newErrorResponse
+func (h *EntityHandler) writeErrorFiber(c *fiber.Ctx, err *errors.APIError) error {
+	resp := newErrorResponse(err, c.Path(), logging.RequestID(c.UserContext()), c.Get(fiber.HeaderAccept), c.Get(fiber.HeaderAcceptLanguage))
+	for key, value := range resp.headers {
+		c.Set(key, value)
+	}
+	c.Vary(errorVary...)
+	return c.Status(resp.status).JSON(resp.body, resp.contentType)
+}
+
+// language picks the language of error messages from the Accept-Language header
+func language(c *fiber.Ctx) i18n.Language {
+	return i18n.Negotiate(c.Get(fiber.HeaderAcceptLanguage))
+}
+
+// handleError writes the error response for an error from the service, logging
+// its cause as handleErrorFiber does
+func (h *EntityHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := errors.HandleError(err)
+	logError(r.Context(), err, apiErr)
+	h.writeErrorResponse(w, r, apiErr)
+}
+
+// handleErrorFiber writes the error response for an error from the service,
+// logging its cause as logError does
+func (h *EntityHandler) handleErrorFiber(c *fiber.Ctx, err error) error {
+	apiErr := errors.HandleError(err)
+	logError(c.UserContext(), err, apiErr)
+	return h.writeErrorFiber(c, apiErr)
+}
+
+// logError logs the cause of server errors and database rejections, which the
+// response does not reveal, as well as timeouts and cancellations
+func logError(ctx context.Context, err error, apiErr *errors.APIError) {
+	state, fromDatabase := errors.SQLState(err)
+	switch {
+	case apiErr.Code() == errors.CodeRequestCanceled:
+		slog.InfoContext(ctx, "Request canceled by the client", "error", err)
+	case apiErr.Code() == errors.CodeTimeout:
+		slog.WarnContext(ctx, "Request timed out", "error", err)
+	case apiErr.Status() >= fiber.StatusInternalServerError:
+		slog.ErrorContext(ctx, "Request failed", "error", err)
+	case fromDatabase:
+		slog.WarnContext(ctx, "Request rejected by the database", "sqlstate", state, "error", err)
+	}
+}
+
 // GetAllEntitiesFiber handles GET /api/v1/entities request for Fiber
-// @Summary List all entities
-// @Description Get a list of all entities
+// @Summary List entities
+// @Description Get a page of entities using offset or keyset cursor pagination.
+// @Description Filter with filter[field][operator]=value (fields: id, name, created_at, updated_at;
+// @Description operators: eq, ne, gt, gte, lt, lte, contains, prefix) and sort with a comma separated
+// @Description field list where a leading '-' sorts descending, e.g. sort=-updated_at,name.
 // @Tags entities
 // @Produce json
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param offset query int false "Number of entities to skip"
+// @Param after query string false "Cursor of the last entity of the previous page"
+// @Param before query string false "Cursor of the first entity of the next page"
+// @Param sort query string false "Sort fields, e.g. -updated_at,name"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
 // @Router /entities [get]
 func (h *EntityHandler) GetAllEntitiesFiber(c *fiber.Ctx) error {
-	entities, err := h.service.GetAllEntities()
+	return h.listEntitiesFiber(c, h.service.GetAllEntities)
+}
+
+// GetDeletedEntitiesFiber handles GET /api/v1/entities/trash request for Fiber
+// @Summary List deleted entities
+// @Description Get a page of soft-deleted entities; accepts the same paging, filter and sort parameters as the entity listing
+// @Tags trash
+// @Produce json
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param offset query int false "Number of entities to skip"
+// @Param after query string false "Cursor of the last entity of the previous page"
+// @Param before query string false "Cursor of the first entity of the next page"
+// @Param sort query string false "Sort fields, e.g. -updated_at,name"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /entities/trash [get]
+func (h *EntityHandler) GetDeletedEntitiesFiber(c *fiber.Ctx) error {
+	return h.listEntitiesFiber(c, h.service.GetDeletedEntities)
+}
+
+// listEntitiesFiber writes a paginated entity listing fetched by fetch
+func (h *EntityHandler) listEntitiesFiber(c *fiber.Ctx, fetch func(context.Context, models.ListParams) (*models.EntityPage, error)) error {
+	query, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+	if err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		return h.writeErrorFiber(c, err)
+	}
+
+	params, validationErrors := parseListParams(query)
+	if len(validationErrors) > 0 {
+		err := validation.ToAPIError(validationErrors)
+		return h.writeErrorFiber(c, err)
+	}
+
+	page, err := fetch(c.UserContext(), params)
+	if err != nil {
+		return h.handleErrorFiber(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  page.Entities,
+		"count": len(page.Entities),
+		"total": page.Total,
+		"limit": params.Limit,
+		"links": buildPageLinks(c.Path(), query, params, page),
+	})
+}
+
+// SearchEntitiesFiber handles GET /api/v1/entities/search request for Fiber
+// @Summary Search entities
+// @Description Find entities by partial or misspelled name, ranked by relevance score
+// @Tags entities
+// @Produce json
+// @Param q query string true "Search text"
+// @Param limit query int false "Maximum number of results (1-100)" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /entities/search [get]
+func (h *EntityHandler) SearchEntitiesFiber(c *fiber.Ctx) error {
+	query, err := url.ParseQuery(string(c.Request().URI().QueryString()))
 	if err != nil {
-		apiErr := errors.HandleError(err)
-		return c.Status(apiErr.Code).JSON(fiber.Map{
-			"error": apiErr,
-		})
+		err := errors.New(errors.CodeInvalidRequest)
+		return h.writeErrorFiber(c, err)
+	}
+
+	q, limit, validationErrors := parseSearchParams(query)
+	if len(validationErrors) > 0 {
+		err := validation.ToAPIError(validationErrors)
+		return h.writeErrorFiber(c, err)
+	}
+
+	results, err := h.service.SearchEntities(c.UserContext(), q, limit)
+	if err != nil {
+		return h.handleErrorFiber(c, err)
 	}
 
 	return c.JSON(fiber.Map{
-		"data":  entities,
-		"count": len(entities),
+		"data":  results,
+		"count": len(results),
 	})
 }
 
@@ -216,29 +504,23 @@ func (h *EntityHandler) GetAllEntitiesFiber(c *fiber.Ctx) error {
 func (h *EntityHandler) CreateEntityFiber(c *fiber.Ctx) error {
 	var req models.EntityRequest
 	if err := c.BodyParser(&req); err != nil {
-		err := errors.ErrInvalidRequest
-		return c.Status(err.Code).JSON(fiber.Map{
-			"error": err,
-		})
+		err := errors.New(errors.CodeInvalidRequest)
+		return h.writeErrorFiber(c, err)
 	}
 
 	// Validate request
-	validationErrors := validation.ValidateEntityRequest(req.Name)
+	validationErrors := validation.Struct(&req)
 	if len(validationErrors) > 0 {
 		err := validation.ToAPIError(validationErrors)
-		return c.Status(err.Code).JSON(fiber.Map{
-			"error": err,
-		})
+		return h.writeErrorFiber(c, err)
 	}
 
-	entity, err := h.service.CreateEntity(&req)
+	entity, err := h.service.CreateEntity(c.UserContext(), &req)
 	if err != nil {
-		apiErr := errors.HandleError(err)
-		return c.Status(apiErr.Code).JSON(fiber.Map{
-			"error": apiErr,
-		})
+		return h.handleErrorFiber(c, err)
 	}
 
+	c.Set(fiber.HeaderETag, entityETag(entity.Version))
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"data": entity,
 	})
@@ -250,31 +532,31 @@ func (h *EntityHandler) CreateEntityFiber(c *fiber.Ctx) error {
 // @Tags entities
 // @Produce json
 // @Param id path int true "Entity ID"
+// @Param If-None-Match header string false "Entity tag of a cached copy"
 // @Success 200 {object} map[string]interface{}
+// @Success 304 "Cached copy is still current"
 // @Failure 404 {object} map[string]interface{}
 // @Router /entities/{id} [get]
 func (h *EntityHandler) GetEntityByIDFiber(c *fiber.Ctx) error {
-	id, err := strconv.Atoi(c.Params("id"))
+	id, err := parseID(c.Params("id"))
 	if err != nil {
-		err := errors.ErrInvalidRequest
-		return c.Status(err.Code).JSON(fiber.Map{
-			"error": err,
-		})
+		err := errors.New(errors.CodeInvalidRequest)
+		return h.writeErrorFiber(c, err)
 	}
 
-	entity, err := h.service.GetEntityByID(id)
+	entity, err := h.service.GetEntityByID(c.UserContext(), id)
 	if err != nil {
-		apiErr := errors.HandleError(err)
-		return c.Status(apiErr.Code).JSON(fiber.Map{
-			"error": apiErr,
-		})
+		return h.handleErrorFiber(c, err)
 	}
 
 	if entity == nil {
-		err := errors.ErrEntityNotFound
-		return c.Status(err.Code).JSON(fiber.Map{
-			"error": err,
-		})
+		err := errors.New(errors.CodeEntityNotFound)
+		return h.writeErrorFiber(c, err)
+	}
+
+	c.Set(fiber.HeaderETag, entityETag(entity.Version))
+	if noneMatch(c.Get(fiber.HeaderIfNoneMatch), entity.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
 	}
 
 	return c.JSON(fiber.Map{
@@ -290,51 +572,97 @@ func (h *EntityHandler) GetEntityByIDFiber(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path int true "Entity ID"
 // @Param entity body models.EntityRequest true "Entity data to update"
+// @Param If-Match header string false "Entity tag the update must apply to"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
 // @Router /entities/{id} [put]
 func (h *EntityHandler) UpdateEntityFiber(c *fiber.Ctx) error {
-	id, err := strconv.Atoi(c.Params("id"))
+	id, err := parseID(c.Params("id"))
 	if err != nil {
-		err := errors.ErrInvalidRequest
-		return c.Status(err.Code).JSON(fiber.Map{
-			"error": err,
-		})
+		err := errors.New(errors.CodeInvalidRequest)
+		return h.writeErrorFiber(c, err)
 	}
 
 	var req models.EntityRequest
 	if err := c.BodyParser(&req); err != nil {
-		err := errors.ErrInvalidRequest
-		return c.Status(err.Code).JSON(fiber.Map{
-			"error": err,
-		})
+		err := errors.New(errors.CodeInvalidRequest)
+		return h.writeErrorFiber(c, err)
 	}
 
 	// Validate request
-	validationErrors := validation.ValidateEntityRequest(req.Name)
+	validationErrors := validation.Struct(&req)
 	if len(validationErrors) > 0 {
 		err := validation.ToAPIError(validationErrors)
-		return c.Status(err.Code).JSON(fiber.Map{
-			"error": err,
-		})
+		return h.writeErrorFiber(c, err)
 	}
 
-	entity, err := h.service.UpdateEntity(id, &req)
+	version, err := h.ifMatchVersion(c.UserContext(), c.Get(fiber.HeaderIfMatch), id)
 	if err != nil {
-		apiErr := errors.HandleError(err)
-		return c.Status(apiErr.Code).JSON(fiber.Map{
-			"error": apiErr,
-		})
+		return h.handleErrorFiber(c, err)
+	}
+
+	entity, err := h.service.UpdateEntity(c.UserContext(), id, &req, version)
+	if err != nil {
+		return h.handleErrorFiber(c, err)
 	}
 
 	if entity == nil {
-		err := errors.ErrEntityNotFound
-		return c.Status(err.Code).JSON(fiber.Map{
-			"error": err,
-		})
+		err := errors.New(errors.CodeEntityNotFound)
+		return h.writeErrorFiber(c, err)
+	}
+
+	c.Set(fiber.HeaderETag, entityETag(entity.Version))
+	return c.JSON(fiber.Map{
+		"data": entity,
+	})
+}
+
+// PatchEntityFiber handles PATCH /api/v1/entities/:id request for Fiber
+// @Summary Patch entity by ID
+// @Description Partially update an entity with a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) document
+// @Tags entities
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path int true "Entity ID"
+// @Param patch body object true "Patch document"
+// @Param If-Match header string false "Entity tag the patch must apply to"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
+// @Router /entities/{id} [patch]
+func (h *EntityHandler) PatchEntityFiber(c *fiber.Ctx) error {
+	id, err := parseID(c.Params("id"))
+	if err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		return h.writeErrorFiber(c, err)
 	}
 
+	contentType, ok := patchContentType(c.Get(fiber.HeaderContentType))
+	if !ok {
+		err := errors.New(errors.CodeUnsupportedMediaType)
+		return h.writeErrorFiber(c, err)
+	}
+
+	version, err := h.ifMatchVersion(c.UserContext(), c.Get(fiber.HeaderIfMatch), id)
+	if err != nil {
+		return h.handleErrorFiber(c, err)
+	}
+
+	// Copy the body since Fiber reuses its buffer after the handler returns
+	document := append([]byte(nil), c.Body()...)
+
+	entity, err := h.service.PatchEntity(c.UserContext(), id, &models.EntityPatch{ContentType: contentType, Document: document}, version)
+	if err != nil {
+		return h.handleErrorFiber(c, err)
+	}
+
+	c.Set(fiber.HeaderETag, entityETag(entity.Version))
 	return c.JSON(fiber.Map{
 		"data": entity,
 	})
@@ -342,29 +670,104 @@ func (h *EntityHandler) UpdateEntityFiber(c *fiber.Ctx) error {
 
 // DeleteEntityFiber handles DELETE /api/v1/entities/:id request for Fiber
 // @Summary Delete entity by ID
-// @Description Delete an entity by its ID
+// @Description Soft-delete an entity by its ID, moving it to the trash
 // @Tags entities
 // @Produce json
 // @Param id path int true "Entity ID"
+// @Param If-Match header string false "Entity tag the delete must apply to"
 // @Success 204 "Entity deleted successfully"
 // @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
 // @Router /entities/{id} [delete]
 func (h *EntityHandler) DeleteEntityFiber(c *fiber.Ctx) error {
-	id, err := strconv.Atoi(c.Params("id"))
+	id, err := parseID(c.Params("id"))
+	if err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		return h.writeErrorFiber(c, err)
+	}
+
+	version, err := h.ifMatchVersion(c.UserContext(), c.Get(fiber.HeaderIfMatch), id)
+	if err != nil {
+		return h.handleErrorFiber(c, err)
+	}
+
+	err = h.service.DeleteEntity(c.UserContext(), id, version)
+	if err != nil {
+		return h.handleErrorFiber(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RestoreEntityFiber handles POST /api/v1/entities/:id/restore request for Fiber
+// @Summary Restore deleted entity
+// @Description Restore a soft-deleted entity from the trash
+// @Tags trash
+// @Produce json
+// @Param id path int true "Entity ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /entities/{id}/restore [post]
+func (h *EntityHandler) RestoreEntityFiber(c *fiber.Ctx) error {
+	id, err := parseID(c.Params("id"))
 	if err != nil {
-		err := errors.ErrInvalidRequest
-		return c.Status(err.Code).JSON(fiber.Map{
-			"error": err,
-		})
+		err := errors.New(errors.CodeInvalidRequest)
+		return h.writeErrorFiber(c, err)
 	}
 
-	err = h.service.DeleteEntity(id)
+	entity, err := h.service.RestoreEntity(c.UserContext(), id)
 	if err != nil {
-		apiErr := errors.HandleError(err)
-		return c.Status(apiErr.Code).JSON(fiber.Map{
-			"error": apiErr,
-		})
+		return h.handleErrorFiber(c, err)
+	}
+
+	c.Set(fiber.HeaderETag, entityETag(entity.Version))
+	return c.JSON(fiber.Map{
+		"data": entity,
+	})
+}
+
+// PurgeEntityFiber handles DELETE /api/v1/entities/trash/:id request for Fiber
+// @Summary Purge deleted entity
+// @Description Permanently remove a soft-deleted entity; live entities must be deleted first
+// @Tags trash
+// @Produce json
+// @Param id path int true "Entity ID"
+// @Success 204 "Entity purged successfully"
+// @Failure 404 {object} map[string]interface{}
+// @Router /entities/trash/{id} [delete]
+func (h *EntityHandler) PurgeEntityFiber(c *fiber.Ctx) error {
+	id, err := parseID(c.Params("id"))
+	if err != nil {
+		err := errors.New(errors.CodeInvalidRequest)
+		return h.writeErrorFiber(c, err)
+	}
+
+	err = h.service.PurgeEntity(c.UserContext(), id)
+	if err != nil {
+		return h.handleErrorFiber(c, err)
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
+
+// patchContentType returns the patch media type of a Content-Type header,
+// or false if it is not a supported patch format
+func patchContentType(header string) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", false
+	}
+
+	switch mediaType {
+	case models.MergePatchContentType, models.JSONPatchContentType:
+		return mediaType, true
+	}
+	return "", false
+}
+
+// parseID parses an entity ID from a request path. IDs outside the range of the
+// int32 id column are rejected here, before the database fails to read them.
+func parseID(s string) (int, error) {
+	id, err := strconv.ParseInt(s, 10, 32)
+	return int(id), err
+}
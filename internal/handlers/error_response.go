@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"learn-api/pkg/errors"
+	"learn-api/pkg/i18n"
+	"learn-api/pkg/validation"
+)
+
+// errorVary lists the request headers error responses depend on
+var errorVary = []string{fiber.HeaderAccept, fiber.HeaderAcceptLanguage}
+
+// errorResponse is the response reporting an API error, shared by the Fiber
+// and net/http handlers so both answer in the same format
+type errorResponse struct {
+	status      int
+	contentType string
+	// headers are the response headers other than Content-Type and Vary
+	headers map[string]string
+	body    interface{}
+}
+
+// newErrorResponse builds the response reporting err to the request for path
+// with the request ID id and the given Accept and Accept-Language headers.
+// Clients accepting application/problem+json over application/json get RFC 7807
+// problem details; the others keep the {"error": ...} envelope. Messages are in
+// the language of the Accept-Language header, English by default.
+func newErrorResponse(err *errors.APIError, path, id, accept, acceptLanguage string) errorResponse {
+	lang := i18n.Negotiate(acceptLanguage)
+	err = validation.Localize(err, lang).WithRequestID(id)
+
+	resp := errorResponse{
+		status:      err.Status(),
+		contentType: fiber.MIMEApplicationJSON,
+		headers:     map[string]string{fiber.HeaderContentLanguage: string(lang)},
+		body:        map[string]interface{}{"error": err},
+	}
+	if err.RetryAfter() > 0 {
+		resp.headers[fiber.HeaderRetryAfter] = strconv.Itoa(err.RetryAfter())
+	}
+	if prefersProblem(accept) {
+		resp.contentType = errors.ProblemContentType
+		resp.body = err.Problem(path)
+	}
+	return resp
+}
+
+// prefersProblem reports whether an Accept header ranks problem details above
+// plain JSON. Ties go to JSON, which older clients expect.
+func prefersProblem(accept string) bool {
+	return acceptQuality(accept, errors.ProblemContentType) > acceptQuality(accept, fiber.MIMEApplicationJSON)
+}
+
+// acceptQuality returns the quality an Accept header gives mediaType, taken
+// from the most specific media range matching it, or zero when none does
+func acceptQuality(accept, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	quality, specificity := 0.0, -1
+
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		var s int
+		switch strings.ToLower(strings.TrimSpace(params[0])) {
+		case mediaType:
+			s = 2
+		case mainType + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		quality, specificity = q, s
+	}
+	return quality
+}
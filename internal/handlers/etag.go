@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"learn-api/internal/repository"
+	"learn-api/pkg/errors"
+)
+
+// entityETag returns the strong entity tag for an entity version
+func entityETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseEntityTags extracts the versions listed in an If-Match or If-None-Match header.
+// Weak tags (W/"...") are only accepted when weak is set, as If-Match requires
+// strong comparison. Malformed tags are ignored, as are versions below 1: no
+// entity has one, and version 0 would make a write unconditional.
+func parseEntityTags(header string, weak bool) []int {
+	var versions []int
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		version, err := strconv.Atoi(tag[1 : len(tag)-1])
+		if err != nil || version < 1 {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+// noneMatch reports whether an If-None-Match header matches the current version,
+// meaning the client's cached copy is still fresh
+func noneMatch(header string, version int) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, v := range parseEntityTags(header, true) {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion resolves an If-Match header into the version a write must apply to.
+// It returns 0 when the header is absent or "*", leaving the write unconditional.
+func (h *EntityHandler) ifMatchVersion(ctx context.Context, header string, id int) (int, error) {
+	if header == "" || strings.TrimSpace(header) == "*" {
+		return 0, nil
+	}
+
+	versions := parseEntityTags(header, false)
+	switch len(versions) {
+	case 0:
+		return 0, errors.New(errors.CodePreconditionFailed)
+	case 1:
+		return versions[0], nil
+	}
+
+	// Several tags: the write applies to whichever listed version is current,
+	// and stays conditional on it so a concurrent change is still detected.
+	// The primary is read, as a lagging replica would fail a current tag.
+	entity, err := h.service.GetEntityByID(repository.WithPrimary(ctx), id)
+	if err != nil {
+		return 0, err
+	}
+	if entity == nil {
+		return 0, errors.New(errors.CodeEntityNotFound)
+	}
+	for _, version := range versions {
+		if version == entity.Version {
+			return version, nil
+		}
+	}
+	return 0, errors.New(errors.CodePreconditionFailed)
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"learn-api/internal/models"
+	"learn-api/pkg/validation"
+)
+
+// entityListFields whitelists the entity fields usable in filter and sort parameters
+var entityListFields = map[string]validation.FieldRule{
+	"id": {
+		Kind:      validation.KindInt,
+		Operators: []string{"eq", "ne", "gt", "gte", "lt", "lte"},
+		Sortable:  true,
+	},
+	"name": {
+		Kind:      validation.KindString,
+		Operators: []string{"eq", "ne", "contains", "prefix"},
+		Sortable:  true,
+	},
+	"created_at": {
+		Kind:      validation.KindTime,
+		Operators: []string{"eq", "gt", "gte", "lt", "lte"},
+		Sortable:  true,
+	},
+	"updated_at": {
+		Kind:      validation.KindTime,
+		Operators: []string{"eq", "gt", "gte", "lt", "lte"},
+		Sortable:  true,
+	},
+}
+
+// filterParam matches filter[field] and filter[field][operator] query keys
+var filterParam = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+// pageLinks holds the navigation links returned with a page of entities
+type pageLinks struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// parseListParams reads the paging, filter and sort parameters from the query string
+func parseListParams(query url.Values) (models.ListParams, []validation.ValidationError) {
+	var validationErrors []validation.ValidationError
+
+	params := models.ListParams{
+		Limit:  models.DefaultPageLimit,
+		After:  query.Get("after"),
+		Before: query.Get("before"),
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			validationErrors = append(validationErrors, validation.NewError("limit", validation.CodeLimitNotInteger, nil))
+		}
+		params.Limit = limit
+	}
+
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil {
+			validationErrors = append(validationErrors, validation.NewError("offset", validation.CodeOffsetNotInteger, nil))
+		}
+		params.Offset = offset
+	}
+
+	// Filters are collected in key order so the generated SQL is deterministic
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if strings.HasPrefix(key, "filter") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		match := filterParam.FindStringSubmatch(key)
+		if match == nil {
+			validationErrors = append(validationErrors, validation.NewError(key, validation.CodeFilterMalformed, nil))
+			continue
+		}
+
+		operator := match[2]
+		if operator == "" {
+			operator = "eq"
+		}
+
+		for _, value := range query[key] {
+			filter := models.Filter{Field: match[1], Operator: operator, Value: value}
+			validationErrors = append(validationErrors, validation.ValidateFilter(filter.Field, filter.Operator, filter.Value, entityListFields)...)
+			params.Filters = append(params.Filters, filter)
+		}
+	}
+
+	if v := query.Get("sort"); v != "" {
+		seen := map[string]bool{}
+		for _, field := range strings.Split(v, ",") {
+			sortField := models.SortField{Field: strings.TrimSpace(field)}
+			if strings.HasPrefix(sortField.Field, "-") {
+				sortField.Field = sortField.Field[1:]
+				sortField.Desc = true
+			}
+
+			if seen[sortField.Field] {
+				validationErrors = append(validationErrors, validation.NewError("sort", validation.CodeSortDuplicate, map[string]string{"field": sortField.Field}))
+				continue
+			}
+			seen[sortField.Field] = true
+
+			validationErrors = append(validationErrors, validation.ValidateSort(sortField.Field, entityListFields)...)
+			params.Sort = append(params.Sort, sortField)
+		}
+	}
+
+	if len(validationErrors) > 0 {
+		return params, validationErrors
+	}
+
+	return params, validation.ValidateListParams(params.Limit, params.Offset, models.MaxPageLimit, params.After, params.Before)
+}
+
+// parseSearchParams reads the search text and result limit from the query string
+func parseSearchParams(query url.Values) (string, int, []validation.ValidationError) {
+	q := query.Get("q")
+	limit := models.DefaultPageLimit
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return q, limit, []validation.ValidationError{
+				validation.NewError("limit", validation.CodeLimitNotInteger, nil),
+			}
+		}
+		limit = n
+	}
+
+	validationErrors := validation.ValidateSearchQuery(q)
+	validationErrors = append(validationErrors, validation.ValidateListParams(limit, 0, models.MaxPageLimit, "", "")...)
+	return q, limit, validationErrors
+}
+
+// buildPageLinks builds next/prev links for a page, preserving unrelated query parameters.
+// Offset requests beyond the first page get offset links; everything else
+// navigates by keyset cursor.
+func buildPageLinks(path string, query url.Values, params models.ListParams, page *models.EntityPage) pageLinks {
+	var links pageLinks
+
+	link := func(set map[string]string) string {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Del("offset")
+		q.Del("after")
+		q.Del("before")
+		q.Set("limit", strconv.Itoa(params.Limit))
+		for k, v := range set {
+			q.Set(k, v)
+		}
+		return path + "?" + q.Encode()
+	}
+
+	if !params.UsesCursor() && params.Offset > 0 {
+		if page.HasNext {
+			links.Next = link(map[string]string{"offset": strconv.Itoa(params.Offset + params.Limit)})
+		}
+		if page.HasPrev {
+			prev := params.Offset - params.Limit
+			if prev < 0 {
+				prev = 0
+			}
+			links.Prev = link(map[string]string{"offset": strconv.Itoa(prev)})
+		}
+		return links
+	}
+
+	if page.HasNext && page.NextCursor != "" {
+		links.Next = link(map[string]string{"after": page.NextCursor})
+	}
+	if page.HasPrev && page.PrevCursor != "" {
+		links.Prev = link(map[string]string{"before": page.PrevCursor})
+	}
+
+	return links
+}
@@ -0,0 +1,71 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"learn-api/internal/database"
+	"learn-api/internal/database/migrate"
+)
+
+// PoolStats describes the primary connection pool and the read replicas
+type PoolStats struct {
+	OpenConnections int    `json:"open_connections"`
+	InUse           int    `json:"in_use"`
+	Idle            int    `json:"idle"`
+	MaxOpen         int    `json:"max_open"`
+	WaitCount       int64  `json:"wait_count"`
+	WaitDuration    string `json:"wait_duration"`
+	Replicas        int    `json:"replicas"`
+	HealthyReplicas int    `json:"healthy_replicas"`
+}
+
+// MigrationStats describes how far the schema is migrated
+type MigrationStats struct {
+	Latest  int   `json:"latest"`
+	Pending []int `json:"pending"`
+}
+
+// NewDatabaseChecker checks that the primary database answers a ping and
+// reports the pool statistics
+func NewDatabaseChecker(pool *database.Pool) Checker {
+	return CheckerFunc(func(ctx context.Context) (interface{}, error) {
+		stats := pool.Stats()
+		details := PoolStats{
+			OpenConnections: stats.OpenConnections,
+			InUse:           stats.InUse,
+			Idle:            stats.Idle,
+			MaxOpen:         stats.MaxOpenConnections,
+			WaitCount:       stats.WaitCount,
+			WaitDuration:    stats.WaitDuration.String(),
+			Replicas:        pool.ReplicaCount(),
+			HealthyReplicas: pool.HealthyReplicaCount(),
+		}
+		return details, pool.PingContext(ctx)
+	})
+}
+
+// NewMigrationChecker checks that every known migration has been applied, so
+// the service does not serve requests against an outdated schema
+func NewMigrationChecker(migrator migrate.Migrator, migrations []migrate.Migration) Checker {
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+
+	return CheckerFunc(func(ctx context.Context) (interface{}, error) {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		details := MigrationStats{Latest: latest, Pending: []int{}}
+		for _, migration := range pending {
+			details.Pending = append(details.Pending, migration.Version)
+		}
+		if len(pending) > 0 {
+			return details, fmt.Errorf("%d pending migrations; run \"api migrate up\"", len(pending))
+		}
+		return details, nil
+	})
+}
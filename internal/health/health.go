@@ -0,0 +1,176 @@
+// Package health runs the dependency checks behind the readiness probe.
+//
+// Dependencies contribute a Checker to a Registry under a name. A check reports
+// failure by returning an error and may return details, such as pool statistics,
+// which are included in the report either way.
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds each check unless WithTimeout says otherwise
+const DefaultTimeout = 2 * time.Second
+
+// Check statuses
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Checker checks one dependency
+type Checker interface {
+	// Check returns details to report and an error when the dependency is not usable
+	Check(ctx context.Context) (interface{}, error)
+}
+
+// CheckerFunc adapts a function to a Checker
+type CheckerFunc func(ctx context.Context) (interface{}, error)
+
+// Check calls f
+func (f CheckerFunc) Check(ctx context.Context) (interface{}, error) {
+	return f(ctx)
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Status   string      `json:"status"`
+	Duration string      `json:"duration"`
+	Error    string      `json:"error,omitempty"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+// Report is the outcome of every registered check; it is up only when all of them are
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Up reports whether every check passed
+func (r Report) Up() bool {
+	return r.Status == StatusUp
+}
+
+// Registry holds the checks that decide whether the service is ready
+type Registry interface {
+	// Register adds a check under name, replacing any check with the same name
+	Register(name string, checker Checker)
+	// Names returns the names of the registered checks in sorted order
+	Names() []string
+	// Check runs every check concurrently, each bounded by the registry's timeout
+	Check(ctx context.Context) Report
+}
+
+// registry implements Registry
+type registry struct {
+	mu       sync.RWMutex
+	checkers map[string]Checker
+	timeout  time.Duration
+}
+
+// Option configures a registry
+type Option func(*registry)
+
+// WithTimeout bounds each check by timeout instead of DefaultTimeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *registry) {
+		r.timeout = timeout
+	}
+}
+
+// NewRegistry creates an empty registry, which reports up
+func NewRegistry(opts ...Option) Registry {
+	r := &registry{
+		checkers: map[string]Checker{},
+		timeout:  DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register adds a check under name, replacing any check with the same name
+func (r *registry) Register(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers[name] = checker
+}
+
+// Names returns the names of the registered checks in sorted order
+func (r *registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.checkers))
+	for name := range r.checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check runs every check concurrently, each bounded by the registry's timeout
+func (r *registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checkers := make(map[string]Checker, len(r.checkers))
+	for name, checker := range r.checkers {
+		checkers[name] = checker
+	}
+	r.mu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checkers))}
+	for name, checker := range checkers {
+		wg.Add(1)
+		go func(name string, checker Checker) {
+			defer wg.Done()
+			result := r.run(ctx, checker)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(name, checker)
+	}
+	wg.Wait()
+	return report
+}
+
+// run runs one check within the timeout. A check that ignores its context is
+// reported down once the timeout passes; it is left to finish in the background.
+func (r *registry) run(ctx context.Context, checker Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	type outcome struct {
+		details interface{}
+		err     error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		details, err := checker.Check(ctx)
+		done <- outcome{details, err}
+	}()
+
+	var out outcome
+	select {
+	case out = <-done:
+	case <-ctx.Done():
+		out.err = ctx.Err()
+	}
+
+	result := Result{Status: StatusUp, Duration: time.Since(start).String(), Details: out.details}
+	if out.err != nil {
+		result.Status = StatusDown
+		result.Error = out.err.Error()
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+// Package httpx holds the helpers the request middlewares share, so that logs,
+// metrics and traces agree on what a request ended as.
+package httpx
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ResponseStatus returns the status a request is answered with, given the error
+// its handlers returned. Errors are turned into responses only after the
+// middlewares return, so the response's own status is not final while err is set.
+func ResponseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// MatchedRoute returns the template of the route that handled the request, such
+// as /api/v1/entities/:id, and false when no handler matched. own is the route
+// of the calling middleware, taken before c.Next, which the request keeps when
+// no handler matched.
+func MatchedRoute(c *fiber.Ctx, own *fiber.Route) (string, bool) {
+	route := c.Route()
+	if route == own {
+		return "", false
+	}
+	return route.Path, true
+}
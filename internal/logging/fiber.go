@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"learn-api/internal/httpx"
+)
+
+// HeaderRequestID carries the request ID in requests and responses
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength bounds the request IDs accepted from clients
+const maxRequestIDLength = 128
+
+// validRequestID reports whether a client's request ID is short and printable,
+// so it cannot forge log lines or response headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestID returns the client's request ID when it is valid, or a new UUID
+func requestID(client string) string {
+	if !validRequestID(client) {
+		return uuid.NewString()
+	}
+	return client
+}
+
+// logRequest logs the line of an answered request, as an error for server errors
+func logRequest(ctx context.Context, status int, attrs []slog.Attr) {
+	level := slog.LevelInfo
+	if status >= fiber.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	slog.LogAttrs(ctx, level, "Request", attrs...)
+}
+
+// Middleware gives every request an ID and logs a line for it once it is
+// answered, as an error for server errors. The ID is the client's X-Request-ID
+// when it sends a valid one, or a new UUID; it is returned in the X-Request-ID
+// response header and carried by the request's user context to every log line.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		// Copied because Fiber reuses the memory behind header values
+		id := requestID(strings.Clone(c.Get(HeaderRequestID)))
+		c.Set(HeaderRequestID, id)
+		c.SetUserContext(WithRequestID(c.UserContext(), id))
+
+		own := c.Route()
+		err := c.Next()
+
+		status := httpx.ResponseStatus(c, err)
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start))/float64(time.Millisecond)),
+			slog.String("ip", c.IP()),
+			slog.Int("bytes", len(c.Response().Body())),
+		}
+		if route, ok := httpx.MatchedRoute(c, own); ok {
+			attrs = append(attrs, slog.String("route", route))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+
+		logRequest(c.UserContext(), status, attrs)
+		return err
+	}
+}
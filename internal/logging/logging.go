@@ -0,0 +1,120 @@
+// Package logging sets up structured logging with log/slog. Every line logged
+// with a request's context carries its request ID and trace, so the lines of one
+// request can be found together.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Formats
+const (
+	// FormatJSON writes one JSON object per line, for log pipelines
+	FormatJSON = "json"
+	// FormatText writes key=value pairs, for reading in a terminal
+	FormatText = "text"
+)
+
+// Config describes how logs are written
+type Config struct {
+	// Level is the least severe level logged: debug, info, warn or error
+	Level string
+	// Format is json or text
+	Format string
+}
+
+// DefaultConfig returns the configuration used for settings that are not provided
+func DefaultConfig() Config {
+	return Config{
+		Level:  "info",
+		Format: FormatJSON,
+	}
+}
+
+// Validate reports settings that can never work
+func (c Config) Validate() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return fmt.Errorf("invalid log level %q: expected debug, info, warn or error", c.Level)
+	}
+	if c.Format != FormatJSON && c.Format != FormatText {
+		return fmt.Errorf("invalid log format %q: expected %s or %s", c.Format, FormatJSON, FormatText)
+	}
+	return nil
+}
+
+// New returns a logger writing to w as cfg describes. Lines logged with a
+// context carry its request ID, trace ID and span ID.
+func New(w io.Writer, cfg Config) (*slog.Logger, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(cfg.Level))
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if cfg.Format == FormatText {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// Setup makes a logger writing to w the default, so that slog's functions and
+// the standard log package use it
+func Setup(w io.Writer, cfg Config) error {
+	logger, err := New(w, cfg)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "" if there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds the request ID and trace of the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", span.TraceID().String()),
+			slog.String("span_id", span.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
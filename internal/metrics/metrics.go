@@ -0,0 +1,145 @@
+// Package metrics collects Prometheus metrics about HTTP requests, repository
+// calls and database connection pools, and serves them in the text format.
+package metrics
+
+import (
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"learn-api/internal/database"
+	"learn-api/internal/httpx"
+	"learn-api/pkg/errors"
+)
+
+// Namespace prefixes the metrics defined by this package
+const Namespace = "learnapi"
+
+// unmatchedRoute labels requests that matched no route, so arbitrary paths
+// cannot blow up the number of series
+const unmatchedRoute = "unmatched"
+
+// Metrics holds the collectors served on /metrics
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	inFlight        prometheus.Gauge
+
+	queryDuration *prometheus.HistogramVec
+	queryErrors   *prometheus.CounterVec
+}
+
+// New creates the collectors on a registry of their own, together with the
+// standard Go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by method, route template and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being handled.",
+		}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "repository_query_duration_seconds",
+			Help:      "Duration of entity repository calls, by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "repository_query_errors_total",
+			Help:      "Entity repository calls that failed, by method. Not found and client errors are not counted.",
+		}, []string{"method"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests, m.requestDuration, m.inFlight,
+		m.queryDuration, m.queryErrors,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Registry returns the registry holding the collectors, for adding more
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// RegisterPool exposes the sql.DBStats of the pool's primary, labelled
+// db_name="primary", and of each replica, labelled replica_1, replica_2 and so on
+func (m *Metrics) RegisterPool(pool *database.Pool) error {
+	if err := m.registry.Register(collectors.NewDBStatsCollector(pool.DB, "primary")); err != nil {
+		return fmt.Errorf("registering pool metrics: %w", err)
+	}
+	for i, replica := range pool.Replicas() {
+		if err := m.registry.Register(collectors.NewDBStatsCollector(replica, "replica_"+strconv.Itoa(i+1))); err != nil {
+			return fmt.Errorf("registering pool metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// Middleware counts and times every request, labelled by its route template
+// such as /api/v1/entities/:id rather than the concrete path
+func (m *Metrics) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		start := time.Now()
+		own := c.Route()
+		err := c.Next()
+
+		status := httpx.ResponseStatus(c, err)
+		route, ok := httpx.MatchedRoute(c, own)
+		if !ok {
+			route = unmatchedRoute
+		}
+
+		// Fiber reuses the memory behind c.Method(), while the label is kept
+		labels := prometheus.Labels{"method": strings.Clone(c.Method()), "route": route, "status": strconv.Itoa(status)}
+		m.requests.With(labels).Inc()
+		m.requestDuration.With(labels).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// ObserveQuery implements repository.QueryObserver. Calls that fail only
+// because the entity is missing or the request is invalid are not counted as
+// errors, since they are expected outcomes.
+func (m *Metrics) ObserveQuery(method string, duration time.Duration, err error) {
+	m.queryDuration.WithLabelValues(method).Observe(duration.Seconds())
+
+	var apiErr *errors.APIError
+	if err != nil && !stderrors.Is(err, sql.ErrNoRows) && !stderrors.As(err, &apiErr) {
+		m.queryErrors.WithLabelValues(method).Inc()
+	}
+}
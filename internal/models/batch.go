@@ -0,0 +1,116 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"learn-api/pkg/errors"
+	"learn-api/pkg/validation"
+)
+
+// MaxBatchSize is the maximum number of items accepted by a single batch request.
+// The batch requests check it with validateBatchSize; the API docs repeat it,
+// and tests/validation fails when they disagree.
+const MaxBatchSize = 1000
+
+// validateBatchSize reports the items of a batch request when there are more
+// than MaxBatchSize of them
+func validateBatchSize(n int) []validation.ValidationError {
+	if n <= MaxBatchSize {
+		return nil
+	}
+	return []validation.ValidationError{
+		validation.NewError("items", validation.CodeMaxItems, map[string]string{
+			"field": "items",
+			"label": "Items",
+			"max":   strconv.Itoa(MaxBatchSize),
+		}),
+	}
+}
+
+// BatchCreateRequest represents a batch of entities to create.
+// Atomic batches are applied in one transaction and either succeed or fail as a whole;
+// otherwise each item is applied independently (best-effort).
+// Items are validated one by one, as each gets its own result.
+type BatchCreateRequest struct {
+	Atomic bool            `json:"atomic"`
+	Items  []EntityRequest `json:"items" validate:"required"`
+}
+
+// Validate implements validation.Validator, limiting the size of the batch
+func (r BatchCreateRequest) Validate() []validation.ValidationError {
+	return validateBatchSize(len(r.Items))
+}
+
+// BatchUpdateItem represents one entity update within a batch.
+// A non-zero Version makes the update conditional, like If-Match on a single update.
+type BatchUpdateItem struct {
+	ID      int    `json:"id" validate:"min=1,max=2147483647"`
+	Name    string `json:"name" validate:"trim,required,max=255"`
+	Version int    `json:"version,omitempty"`
+}
+
+// BatchUpdateRequest represents a batch of entity updates
+type BatchUpdateRequest struct {
+	Atomic bool              `json:"atomic"`
+	Items  []BatchUpdateItem `json:"items" validate:"required"`
+}
+
+// Validate implements validation.Validator, limiting the size of the batch
+func (r BatchUpdateRequest) Validate() []validation.ValidationError {
+	return validateBatchSize(len(r.Items))
+}
+
+// BatchDeleteItem represents one entity deletion within a batch
+type BatchDeleteItem struct {
+	ID      int `json:"id" validate:"min=1,max=2147483647"`
+	Version int `json:"version,omitempty"`
+}
+
+// BatchDeleteRequest represents a batch of entity deletions
+type BatchDeleteRequest struct {
+	Atomic bool              `json:"atomic"`
+	Items  []BatchDeleteItem `json:"items" validate:"required"`
+}
+
+// Validate implements validation.Validator, limiting the size of the batch
+func (r BatchDeleteRequest) Validate() []validation.ValidationError {
+	return validateBatchSize(len(r.Items))
+}
+
+// BatchItemResult represents the outcome of a single batch item.
+// Status is the HTTP status the item would have had as an individual request.
+type BatchItemResult struct {
+	Index  int              `json:"index"`
+	Status int              `json:"status"`
+	Data   *Entity          `json:"data,omitempty"`
+	Error  *errors.APIError `json:"error,omitempty"`
+}
+
+// MarshalJSON writes the item's error as problem details, so that each failed
+// item keeps its catalog code and the fields that made it invalid
+func (r BatchItemResult) MarshalJSON() ([]byte, error) {
+	var problem *errors.Problem
+	if r.Error != nil {
+		problem = r.Error.Problem("")
+	}
+	return json.Marshal(struct {
+		Index  int             `json:"index"`
+		Status int             `json:"status"`
+		Data   *Entity         `json:"data,omitempty"`
+		Error  *errors.Problem `json:"error,omitempty"`
+	}{
+		Index:  r.Index,
+		Status: r.Status,
+		Data:   r.Data,
+		Error:  problem,
+	})
+}
+
+// BatchResult represents the per-item outcome of a batch request
+type BatchResult struct {
+	Atomic    bool
+	Succeeded int
+	Failed    int
+	Results   []BatchItemResult
+}
@@ -6,13 +6,21 @@ import (
 
 // Entity represents a generic entity in the system
 type Entity struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Version   int        `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 // EntityRequest represents the request structure for creating/updating an entity
 type EntityRequest struct {
-	Name string `json:"name" binding:"required"`
-}
\ No newline at end of file
+	Name string `json:"name" validate:"trim,required,max=255"`
+}
+
+// EntitySearchResult represents an entity matched by a search along with its relevance score
+type EntitySearchResult struct {
+	Entity
+	Score float64 `json:"score"`
+}
@@ -0,0 +1,46 @@
+package models
+
+// Default and maximum page sizes for entity listings
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Filter represents a single field/operator/value condition on a listing
+type Filter struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// SortField represents one sort key of a listing
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ListParams represents the paging, filtering and sorting options for listing entities.
+// After and Before are opaque keyset cursors and cannot be combined with Offset.
+type ListParams struct {
+	Limit   int
+	Offset  int
+	After   string
+	Before  string
+	Filters []Filter
+	Sort    []SortField
+}
+
+// UsesCursor reports whether the listing is keyset (cursor) based
+func (p ListParams) UsesCursor() bool {
+	return p.After != "" || p.Before != ""
+}
+
+// EntityPage represents a single page of entities and its paging metadata
+type EntityPage struct {
+	Entities   []*Entity
+	Total      int
+	HasNext    bool
+	HasPrev    bool
+	NextCursor string
+	PrevCursor string
+}
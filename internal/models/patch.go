@@ -0,0 +1,13 @@
+package models
+
+// Media types accepted by PATCH requests
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// EntityPatch represents a patch document and the media type it is written in
+type EntityPatch struct {
+	ContentType string
+	Document    []byte
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"learn-api/internal/models"
+	"learn-api/pkg/errors"
+)
+
+// cursor is the keyset position encoded into opaque pagination cursors.
+// Sort records the sort order the values belong to, so a cursor cannot be
+// replayed against a listing with a different order.
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+// encodeCursor serializes a cursor into an opaque URL-safe string
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses an opaque cursor produced by encodeCursor, checks that
+// it was issued for the sort keys and returns its values parsed by the type of
+// their key, so an edited cursor is rejected rather than sent to the database
+func decodeCursor(s string, keys []models.SortField) ([]interface{}, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidCursor)
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sortSignature(keys) || len(c.Values) != len(keys) {
+		return nil, errors.New(errors.CodeInvalidCursor)
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		value, err := keyValue(key.Field, c.Values[i])
+		if err != nil {
+			return nil, errors.New(errors.CodeInvalidCursor)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// keyValue parses a value of a sort key in the type of its column: an int32
+// id, a timestamp in UTC or a name
+func keyValue(field, value string) (interface{}, error) {
+	switch field {
+	case "name":
+		return value, nil
+	case "created_at", "updated_at":
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, err
+		}
+		return t.UTC(), nil
+	default:
+		id, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		return int(id), nil
+	}
+}
@@ -1,86 +1,389 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
-	"learn-api/internal/database"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
 	"learn-api/internal/models"
 	"learn-api/pkg/errors"
 )
 
 // EntityRepository interface defines the methods for entity operations
 type EntityRepository interface {
-	Create(entity *models.Entity) error
-	GetByID(id int) (*models.Entity, error)
-	GetAll() ([]*models.Entity, error)
-	Update(id int, entity *models.Entity) error
-	Delete(id int) error
+	Create(ctx context.Context, entity *models.Entity) error
+	GetByID(ctx context.Context, id int) (*models.Entity, error)
+	GetByIDForUpdate(ctx context.Context, id int) (*models.Entity, error)
+	GetAll(ctx context.Context, params models.ListParams) (*models.EntityPage, error)
+	Search(ctx context.Context, query string, limit int) ([]*models.EntitySearchResult, error)
+	Update(ctx context.Context, id int, entity *models.Entity) error
+	UpdateFields(ctx context.Context, id int, fields map[string]interface{}, version int) (*models.Entity, error)
+	Delete(ctx context.Context, id int, version int) error
+	GetDeleted(ctx context.Context, params models.ListParams) (*models.EntityPage, error)
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, id int) error
+}
+
+// DefaultQueryTimeout bounds every repository call unless overridden with WithQueryTimeout
+const DefaultQueryTimeout = 5 * time.Second
+
+// DBTX is the database handle a repository runs its queries on.
+// It is satisfied by *sql.DB, *sql.Tx and *database.Pool.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
 // entityRepository implements EntityRepository interface
 type entityRepository struct {
-	db *sql.DB
+	db           DBTX
+	replicas     ReplicaSource
+	queryTimeout time.Duration
 }
 
-// NewEntityRepository creates a new entity repository
-func NewEntityRepository() EntityRepository {
-	return &entityRepository{
-		db: database.DB,
+// Option configures an entity repository
+type Option func(*entityRepository)
+
+// WithQueryTimeout sets how long a single repository call may run before it is
+// cancelled. A zero or negative timeout leaves calls bounded only by their context.
+func WithQueryTimeout(timeout time.Duration) Option {
+	return func(r *entityRepository) {
+		r.queryTimeout = timeout
 	}
 }
 
-// Create inserts a new entity into the database
-func (r *entityRepository) Create(entity *models.Entity) error {
-	query := `INSERT INTO entities (name, created_at, updated_at) VALUES ($1, NOW(), NOW()) RETURNING id`
-	err := r.db.QueryRow(query, entity.Name).Scan(&entity.ID)
-	if err != nil {
-		return err
+// NewEntityRepository creates an entity repository that runs its queries on db.
+// Passing a *sql.Tx confines the repository to that transaction.
+// It panics if db is nil.
+func NewEntityRepository(db DBTX, opts ...Option) EntityRepository {
+	mustHandle(db)
+	return newEntityRepository(db, opts...)
+}
+
+// newEntityRepository creates an entity repository that runs its queries on db,
+// tracing each statement
+func newEntityRepository(db DBTX, opts ...Option) *entityRepository {
+	r := &entityRepository{
+		db:           traceDB(db),
+		queryTimeout: DefaultQueryTimeout,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// mustHandle panics if handle is nil, including a nil pointer stored in an
+// interface, so a missing connection is noticed at startup rather than on the first query
+func mustHandle(handle interface{}) {
+	if handle == nil {
+		panic("repository: nil database handle")
+	}
+	if v := reflect.ValueOf(handle); v.Kind() == reflect.Ptr && v.IsNil() {
+		panic("repository: nil database handle")
+	}
+}
+
+// withTimeout bounds ctx by the query timeout. The returned function must be
+// deferred with the caller's error: it releases the context and, when the
+// context has ended, replaces the driver's error with the context's own
+// (lib/pq reports a cancelled query as a generic server error).
+func (r *entityRepository) withTimeout(ctx context.Context) (context.Context, func(*error)) {
+	cancel := context.CancelFunc(func() {})
+	if r.queryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.queryTimeout)
+	}
+
+	return ctx, func(err *error) {
+		if *err != nil && ctx.Err() != nil {
+			*err = ctx.Err()
+		}
+		cancel()
 	}
-	
-	// Fetch the created entity to get timestamps
-	return r.db.QueryRow(`SELECT created_at, updated_at FROM entities WHERE id = $1`, entity.ID).
-		Scan(&entity.CreatedAt, &entity.UpdatedAt)
+}
+
+// Create inserts a new entity into the database
+func (r *entityRepository) Create(ctx context.Context, entity *models.Entity) (err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer done(&err)
+
+	query := `INSERT INTO entities (name, created_at, updated_at) VALUES ($1, NOW(), NOW())
+		RETURNING id, created_at, updated_at, version`
+	return r.db.QueryRowContext(ctx, query, entity.Name).
+		Scan(&entity.ID, &entity.CreatedAt, &entity.UpdatedAt, &entity.Version)
 }
 
 // GetByID retrieves an entity by its ID
-func (r *entityRepository) GetByID(id int) (*models.Entity, error) {
+func (r *entityRepository) GetByID(ctx context.Context, id int) (*models.Entity, error) {
+	return r.getByID(ctx, id, false)
+}
+
+// GetByIDForUpdate retrieves an entity by its ID and locks its row until the
+// surrounding transaction ends, so it cannot change between the read and a
+// subsequent write. Outside a unit of work the lock is released immediately.
+func (r *entityRepository) GetByIDForUpdate(ctx context.Context, id int) (*models.Entity, error) {
+	return r.getByID(ctx, id, true)
+}
+
+// getByID retrieves a live entity, optionally locking its row
+func (r *entityRepository) getByID(ctx context.Context, id int, forUpdate bool) (_ *models.Entity, err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer done(&err)
+
+	db := r.reader(ctx)
 	entity := &models.Entity{}
-	query := `SELECT id, name, created_at, updated_at FROM entities WHERE id = $1`
-	err := r.db.QueryRow(query, id).Scan(&entity.ID, &entity.Name, &entity.CreatedAt, &entity.UpdatedAt)
+	query := `SELECT id, name, created_at, updated_at, version FROM entities WHERE id = $1 AND deleted_at IS NULL`
+	if forUpdate {
+		db = r.db
+		query += ` FOR UPDATE`
+	}
+	err = db.QueryRowContext(ctx, query, id).Scan(&entity.ID, &entity.Name, &entity.CreatedAt, &entity.UpdatedAt, &entity.Version)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
-		return nil, errors.ErrDatabase
+		return nil, err
 	}
 	return entity, nil
 }
 
-// GetAll retrieves all entities from the database
-func (r *entityRepository) GetAll() ([]*models.Entity, error) {
-	query := `SELECT id, name, created_at, updated_at FROM entities ORDER BY id`
-	rows, err := r.db.Query(query)
+// GetAll retrieves a page of filtered and sorted entities from the database.
+// Offset paging is used unless params carries an After or Before cursor,
+// in which case the page is selected by keyset on the sort keys.
+// Soft-deleted entities are excluded.
+func (r *entityRepository) GetAll(ctx context.Context, params models.ListParams) (*models.EntityPage, error) {
+	return r.list(ctx, params, false)
+}
+
+// GetDeleted retrieves a page of soft-deleted entities (the trash)
+func (r *entityRepository) GetDeleted(ctx context.Context, params models.ListParams) (*models.EntityPage, error) {
+	return r.list(ctx, params, true)
+}
+
+// list retrieves a page of either live or soft-deleted entities
+func (r *entityRepository) list(ctx context.Context, params models.ListParams, deleted bool) (_ *models.EntityPage, err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer done(&err)
+
+	page := &models.EntityPage{}
+
+	keys, err := sortKeys(params.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var args queryArgs
+	conditions, err := buildFilterConditions(params.Filters, &args)
+	if err != nil {
+		return nil, err
+	}
+
+	if deleted {
+		conditions = append([]string{"deleted_at IS NOT NULL"}, conditions...)
+	} else {
+		conditions = append([]string{"deleted_at IS NULL"}, conditions...)
+	}
+
+	// Count and page on the same replica so they agree
+	db := r.reader(ctx)
+	countQuery := `SELECT COUNT(*) FROM entities` + whereClause(conditions)
+	err = db.QueryRowContext(ctx, countQuery, args...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	reverse := params.Before != ""
+	if params.UsesCursor() {
+		token := params.After
+		if reverse {
+			token = params.Before
+		}
+		values, err := decodeCursor(token, keys)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, keysetCondition(keys, values, reverse, &args))
+	}
+
+	query := `SELECT id, name, created_at, updated_at, version, deleted_at FROM entities` +
+		whereClause(conditions) + orderByClause(keys, reverse)
+	if params.UsesCursor() {
+		// Keyset queries fetch one extra row to detect whether more rows follow
+		query += " LIMIT " + args.add(params.Limit+1)
+	} else {
+		query += " LIMIT " + args.add(params.Limit) + " OFFSET " + args.add(params.Offset)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var entities []*models.Entity
+	entities := []*models.Entity{}
 	for rows.Next() {
 		entity := &models.Entity{}
-		err := rows.Scan(&entity.ID, &entity.Name, &entity.CreatedAt, &entity.UpdatedAt)
+		err := rows.Scan(&entity.ID, &entity.Name, &entity.CreatedAt, &entity.UpdatedAt, &entity.Version, &entity.DeletedAt)
 		if err != nil {
 			return nil, err
 		}
 		entities = append(entities, entity)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hasMore := false
+	if params.UsesCursor() && len(entities) > params.Limit {
+		entities = entities[:params.Limit]
+		hasMore = true
+	}
+
+	if reverse {
+		for i, j := 0, len(entities)-1; i < j; i, j = i+1, j-1 {
+			entities[i], entities[j] = entities[j], entities[i]
+		}
+	}
+
+	switch {
+	case params.After != "":
+		page.HasNext = hasMore
+		page.HasPrev = true
+	case params.Before != "":
+		page.HasNext = true
+		page.HasPrev = hasMore
+	default:
+		page.HasNext = params.Offset+len(entities) < page.Total
+		page.HasPrev = params.Offset > 0
+	}
+
+	if len(entities) > 0 {
+		page.PrevCursor = cursorFor(entities[0], keys)
+		page.NextCursor = cursorFor(entities[len(entities)-1], keys)
+	}
 
-	return entities, nil
+	page.Entities = entities
+	return page, nil
 }
 
-// Update modifies an existing entity in the database
-func (r *entityRepository) Update(id int, entity *models.Entity) error {
-	query := `UPDATE entities SET name = $1, updated_at = NOW() WHERE id = $2`
-	result, err := r.db.Exec(query, entity.Name, id)
+// whereClause joins conditions into a WHERE clause, or returns an empty string
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// Search finds entities whose name matches query, ranked by relevance.
+// Full-text matches are combined with pg_trgm word similarity so partial
+// and misspelled names are still found.
+func (r *entityRepository) Search(ctx context.Context, query string, limit int) (_ []*models.EntitySearchResult, err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer done(&err)
+
+	sqlQuery := `
+		SELECT id, name, created_at, updated_at, version,
+			ts_rank(to_tsvector('simple', name), plainto_tsquery('simple', $1::text)) + word_similarity($1, name) AS score
+		FROM entities
+		WHERE deleted_at IS NULL
+			AND (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1::text) OR $1 <% name)
+		ORDER BY score DESC, id
+		LIMIT $2`
+
+	rows, err := r.reader(ctx).QueryContext(ctx, sqlQuery, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*models.EntitySearchResult{}
+	for rows.Next() {
+		result := &models.EntitySearchResult{}
+		err := rows.Scan(&result.ID, &result.Name, &result.CreatedAt, &result.UpdatedAt, &result.Version, &result.Score)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Update modifies an existing entity in the database and bumps its version.
+// When entity.Version is non-zero the update only applies to that version and
+// a PRECONDITION_FAILED error is returned if the stored version differs.
+func (r *entityRepository) Update(ctx context.Context, id int, entity *models.Entity) (err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer done(&err)
+
+	query := `UPDATE entities SET name = $1, updated_at = NOW(), version = version + 1
+		WHERE id = $2 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)
+		RETURNING name, created_at, updated_at, version`
+	err = r.db.QueryRowContext(ctx, query, entity.Name, id, entity.Version).
+		Scan(&entity.Name, &entity.CreatedAt, &entity.UpdatedAt, &entity.Version)
+	if err == sql.ErrNoRows {
+		return r.missingOrConflict(ctx, id, entity.Version)
+	}
+	return err
+}
+
+// UpdateFields sets only the given columns of an entity and bumps its version.
+// The version condition works as in Update.
+func (r *entityRepository) UpdateFields(ctx context.Context, id int, fields map[string]interface{}, version int) (_ *models.Entity, err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer done(&err)
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var args queryArgs
+	assignments := make([]string, 0, len(names)+2)
+	for _, name := range names {
+		column, ok := writableColumns[name]
+		if !ok {
+			return nil, errors.New(errors.CodeInvalidRequest)
+		}
+		assignments = append(assignments, column+" = "+args.add(fields[name]))
+	}
+	assignments = append(assignments, "updated_at = NOW()", "version = version + 1")
+
+	idArg := args.add(id)
+	versionArg := args.add(version)
+	query := `UPDATE entities SET ` + strings.Join(assignments, ", ") +
+		` WHERE id = ` + idArg + ` AND deleted_at IS NULL AND (` + versionArg + ` = 0 OR version = ` + versionArg + `)` +
+		` RETURNING id, name, created_at, updated_at, version`
+
+	entity := &models.Entity{}
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(&entity.ID, &entity.Name, &entity.CreatedAt, &entity.UpdatedAt, &entity.Version)
+	if err == sql.ErrNoRows {
+		return nil, r.missingOrConflict(ctx, id, version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+// Delete soft-deletes an entity by stamping its deleted_at column.
+// A non-zero version makes the delete conditional, as in Update.
+func (r *entityRepository) Delete(ctx context.Context, id int, version int) (err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer done(&err)
+
+	query := `UPDATE entities SET deleted_at = NOW(), version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)`
+	result, err := r.db.ExecContext(ctx, query, id, version)
 	if err != nil {
 		return err
 	}
@@ -91,18 +394,38 @@ func (r *entityRepository) Update(id int, entity *models.Entity) error {
 	}
 
 	if rowsAffected == 0 {
+		return r.missingOrConflict(ctx, id, version)
+	}
+
+	return nil
+}
+
+// missingOrConflict explains why a conditional write matched no rows:
+// the entity either does not exist or is at a different version
+func (r *entityRepository) missingOrConflict(ctx context.Context, id int, version int) error {
+	if version == 0 {
 		return sql.ErrNoRows
 	}
 
-	// Fetch the updated entity to get timestamps
-	return r.db.QueryRow(`SELECT name, created_at, updated_at FROM entities WHERE id = $1`, id).
-		Scan(&entity.Name, &entity.CreatedAt, &entity.UpdatedAt)
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM entities WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return errors.New(errors.CodePreconditionFailed)
+	}
+	return sql.ErrNoRows
 }
 
-// Delete removes an entity from the database
-func (r *entityRepository) Delete(id int) error {
-	query := `DELETE FROM entities WHERE id = $1`
-	result, err := r.db.Exec(query, id)
+// Restore brings a soft-deleted entity back from the trash
+func (r *entityRepository) Restore(ctx context.Context, id int) (err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer done(&err)
+
+	query := `UPDATE entities SET deleted_at = NULL, version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL`
+	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -117,4 +440,28 @@ func (r *entityRepository) Delete(id int) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// Purge permanently removes a soft-deleted entity from the database.
+// Live entities must be deleted first, so a single call can never destroy data.
+func (r *entityRepository) Purge(ctx context.Context, id int) (err error) {
+	ctx, done := r.withTimeout(ctx)
+	defer done(&err)
+
+	query := `DELETE FROM entities WHERE id = $1 AND deleted_at IS NOT NULL`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"learn-api/internal/models"
+)
+
+// QueryObserver records the outcome of repository calls, for example as metrics
+type QueryObserver interface {
+	// ObserveQuery is called after every call with the method name, how long
+	// the call took and the error it returned, if any
+	ObserveQuery(method string, duration time.Duration, err error)
+}
+
+// instrumentedRepository reports every call of an EntityRepository to an observer
+type instrumentedRepository struct {
+	repo     EntityRepository
+	observer QueryObserver
+}
+
+// NewInstrumentedRepository wraps repo so that every call is reported to observer
+func NewInstrumentedRepository(repo EntityRepository, observer QueryObserver) EntityRepository {
+	return &instrumentedRepository{repo: repo, observer: observer}
+}
+
+// observe reports a call that started at start; it is deferred with the call's named error
+func (r *instrumentedRepository) observe(method string, start time.Time, err *error) {
+	r.observer.ObserveQuery(method, time.Since(start), *err)
+}
+
+// Create inserts an entity and reports the call
+func (r *instrumentedRepository) Create(ctx context.Context, entity *models.Entity) (err error) {
+	defer r.observe("Create", time.Now(), &err)
+	return r.repo.Create(ctx, entity)
+}
+
+// GetByID retrieves an entity by its ID and reports the call
+func (r *instrumentedRepository) GetByID(ctx context.Context, id int) (_ *models.Entity, err error) {
+	defer r.observe("GetByID", time.Now(), &err)
+	return r.repo.GetByID(ctx, id)
+}
+
+// GetByIDForUpdate retrieves and locks an entity and reports the call
+func (r *instrumentedRepository) GetByIDForUpdate(ctx context.Context, id int) (_ *models.Entity, err error) {
+	defer r.observe("GetByIDForUpdate", time.Now(), &err)
+	return r.repo.GetByIDForUpdate(ctx, id)
+}
+
+// GetAll retrieves a page of live entities and reports the call
+func (r *instrumentedRepository) GetAll(ctx context.Context, params models.ListParams) (_ *models.EntityPage, err error) {
+	defer r.observe("GetAll", time.Now(), &err)
+	return r.repo.GetAll(ctx, params)
+}
+
+// Search finds entities by name and reports the call
+func (r *instrumentedRepository) Search(ctx context.Context, query string, limit int) (_ []*models.EntitySearchResult, err error) {
+	defer r.observe("Search", time.Now(), &err)
+	return r.repo.Search(ctx, query, limit)
+}
+
+// Update modifies an entity and reports the call
+func (r *instrumentedRepository) Update(ctx context.Context, id int, entity *models.Entity) (err error) {
+	defer r.observe("Update", time.Now(), &err)
+	return r.repo.Update(ctx, id, entity)
+}
+
+// UpdateFields sets the given fields of an entity and reports the call
+func (r *instrumentedRepository) UpdateFields(ctx context.Context, id int, fields map[string]interface{}, version int) (_ *models.Entity, err error) {
+	defer r.observe("UpdateFields", time.Now(), &err)
+	return r.repo.UpdateFields(ctx, id, fields, version)
+}
+
+// Delete soft-deletes an entity and reports the call
+func (r *instrumentedRepository) Delete(ctx context.Context, id int, version int) (err error) {
+	defer r.observe("Delete", time.Now(), &err)
+	return r.repo.Delete(ctx, id, version)
+}
+
+// GetDeleted retrieves a page of soft-deleted entities and reports the call
+func (r *instrumentedRepository) GetDeleted(ctx context.Context, params models.ListParams) (_ *models.EntityPage, err error) {
+	defer r.observe("GetDeleted", time.Now(), &err)
+	return r.repo.GetDeleted(ctx, params)
+}
+
+// Restore brings an entity back from the trash and reports the call
+func (r *instrumentedRepository) Restore(ctx context.Context, id int) (err error) {
+	defer r.observe("Restore", time.Now(), &err)
+	return r.repo.Restore(ctx, id)
+}
+
+// Purge permanently removes a deleted entity and reports the call
+func (r *instrumentedRepository) Purge(ctx context.Context, id int) (err error) {
+	defer r.observe("Purge", time.Now(), &err)
+	return r.repo.Purge(ctx, id)
+}
+
+// instrumentedUnitOfWork hands instrumented repositories to its units
+type instrumentedUnitOfWork struct {
+	unit     UnitOfWork
+	observer QueryObserver
+}
+
+// NewInstrumentedUnitOfWork wraps unit so that the calls made inside each unit
+// are reported to observer
+func NewInstrumentedUnitOfWork(unit UnitOfWork, observer QueryObserver) UnitOfWork {
+	return &instrumentedUnitOfWork{unit: unit, observer: observer}
+}
+
+// Do runs fn with an instrumented transaction-bound repository
+func (u *instrumentedUnitOfWork) Do(ctx context.Context, fn func(repo EntityRepository) error) error {
+	return u.unit.Do(ctx, func(repo EntityRepository) error {
+		return fn(NewInstrumentedRepository(repo, u.observer))
+	})
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"learn-api/internal/models"
+	"learn-api/pkg/errors"
+)
+
+// entityColumns maps listable entity fields to their SQL columns
+var entityColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// writableColumns maps the entity fields that partial updates may change to their SQL columns
+var writableColumns = map[string]string{
+	"name": "name",
+}
+
+// comparisonOperators maps filter operators to SQL comparison operators
+var comparisonOperators = map[string]string{
+	"eq":  "=",
+	"ne":  "<>",
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+// likeEscaper escapes LIKE wildcards so user input is matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// queryArgs accumulates the positional arguments of a parameterized query
+type queryArgs []interface{}
+
+// add appends a value and returns its placeholder
+func (a *queryArgs) add(value interface{}) string {
+	*a = append(*a, value)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// buildFilterConditions translates list filters into parameterized WHERE conditions
+func buildFilterConditions(filters []models.Filter, args *queryArgs) ([]string, error) {
+	var conditions []string
+
+	for _, filter := range filters {
+		column, ok := entityColumns[filter.Field]
+		if !ok {
+			return nil, errors.New(errors.CodeInvalidRequest)
+		}
+
+		switch filter.Operator {
+		case "contains":
+			conditions = append(conditions, column+` ILIKE '%' || `+args.add(likeEscaper.Replace(filter.Value))+`::text || '%'`)
+		case "prefix":
+			conditions = append(conditions, column+` ILIKE `+args.add(likeEscaper.Replace(filter.Value))+`::text || '%'`)
+		default:
+			op, ok := comparisonOperators[filter.Operator]
+			if !ok {
+				return nil, errors.New(errors.CodeInvalidRequest)
+			}
+			value, err := filterArg(filter.Field, filter.Value)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, column+" "+op+" "+args.add(value))
+		}
+	}
+
+	return conditions, nil
+}
+
+// filterArg converts a filter value to its query argument. Timestamps are bound
+// in UTC: the columns have no time zone, so PostgreSQL would drop the offset.
+func filterArg(field, value string) (interface{}, error) {
+	if field != "created_at" && field != "updated_at" {
+		return value, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidRequest)
+	}
+	return t.UTC(), nil
+}
+
+// sortKeys returns the effective sort keys of a listing.
+// The id column is always appended as a tiebreaker so the order is total,
+// which keyset pagination relies on.
+func sortKeys(sort []models.SortField) ([]models.SortField, error) {
+	keys := make([]models.SortField, 0, len(sort)+1)
+	hasID := false
+
+	for _, key := range sort {
+		if _, ok := entityColumns[key.Field]; !ok {
+			return nil, errors.New(errors.CodeInvalidRequest)
+		}
+		if key.Field == "id" {
+			hasID = true
+		}
+		keys = append(keys, key)
+	}
+
+	if !hasID {
+		keys = append(keys, models.SortField{Field: "id"})
+	}
+
+	return keys, nil
+}
+
+// sortSignature renders sort keys in the sort query parameter format
+func sortSignature(keys []models.SortField) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key.Field
+		if key.Desc {
+			parts[i] = "-" + key.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// orderByClause renders the ORDER BY clause for sort keys, optionally reversed
+func orderByClause(keys []models.SortField, reverse bool) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		direction := "ASC"
+		if key.Desc != reverse {
+			direction = "DESC"
+		}
+		parts[i] = entityColumns[key.Field] + " " + direction
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// keysetCondition renders the condition selecting the rows that follow
+// (or, with before set, precede) the cursor position in sort order:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+func keysetCondition(keys []models.SortField, values []interface{}, before bool, args *queryArgs) string {
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		placeholders[i] = args.add(value)
+	}
+
+	var alternatives []string
+	for i, key := range keys {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, entityColumns[keys[j].Field]+" = "+placeholders[j])
+		}
+
+		op := ">"
+		if key.Desc != before {
+			op = "<"
+		}
+		terms = append(terms, entityColumns[key.Field]+" "+op+" "+placeholders[i])
+
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+// cursorFor builds the cursor pointing at entity's position in sort order
+func cursorFor(entity *models.Entity, keys []models.SortField) string {
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = sortValue(entity, key.Field)
+	}
+	return encodeCursor(cursor{Sort: sortSignature(keys), Values: values})
+}
+
+// sortValue returns the value of a sortable field as a query argument
+func sortValue(entity *models.Entity, field string) string {
+	switch field {
+	case "name":
+		return entity.Name
+	case "created_at":
+		return entity.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		return entity.UpdatedAt.Format(time.RFC3339Nano)
+	default:
+		return strconv.Itoa(entity.ID)
+	}
+}
@@ -0,0 +1,561 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"learn-api/internal/models"
+	"learn-api/pkg/errors"
+)
+
+// memoryStore holds the entities of an in-memory repository
+type memoryStore struct {
+	mu       sync.Mutex
+	entities map[int]models.Entity
+	nextID   int
+}
+
+// memoryEntityRepository implements EntityRepository in memory.
+// Repositories handed to a unit of work set inUnit, as the unit already holds the store's lock.
+type memoryEntityRepository struct {
+	store  *memoryStore
+	inUnit bool
+}
+
+// NewMemoryEntityRepository creates an entity repository that keeps entities in memory.
+// It is safe for concurrent use and behaves like the PostgreSQL repository, except
+// that names are compared byte-wise rather than by collation and search only finds
+// names containing one of the query's words.
+func NewMemoryEntityRepository() EntityRepository {
+	return &memoryEntityRepository{
+		store: &memoryStore{
+			entities: make(map[int]models.Entity),
+			nextID:   1,
+		},
+	}
+}
+
+// lock locks the store unless the repository runs inside a unit of work
+func (r *memoryEntityRepository) lock() func() {
+	if r.inUnit {
+		return func() {}
+	}
+	r.store.mu.Lock()
+	return r.store.mu.Unlock
+}
+
+// now returns the current time at the precision PostgreSQL stores
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// Create stores a new entity, assigning its ID, timestamps and version
+func (r *memoryEntityRepository) Create(ctx context.Context, entity *models.Entity) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer r.lock()()
+
+	entity.ID = r.store.nextID
+	entity.CreatedAt = now()
+	entity.UpdatedAt = entity.CreatedAt
+	entity.Version = 1
+	entity.DeletedAt = nil
+
+	r.store.nextID++
+	r.store.entities[entity.ID] = *entity
+	return nil
+}
+
+// GetByID retrieves an entity by its ID
+func (r *memoryEntityRepository) GetByID(ctx context.Context, id int) (*models.Entity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer r.lock()()
+
+	entity, ok := r.store.entities[id]
+	if !ok || entity.DeletedAt != nil {
+		return nil, nil
+	}
+	return &entity, nil
+}
+
+// GetByIDForUpdate retrieves an entity by its ID. Units of work hold the whole
+// store, so the entity is already protected from concurrent writes.
+func (r *memoryEntityRepository) GetByIDForUpdate(ctx context.Context, id int) (*models.Entity, error) {
+	return r.GetByID(ctx, id)
+}
+
+// GetAll retrieves a page of filtered and sorted live entities
+func (r *memoryEntityRepository) GetAll(ctx context.Context, params models.ListParams) (*models.EntityPage, error) {
+	return r.list(ctx, params, false)
+}
+
+// GetDeleted retrieves a page of soft-deleted entities (the trash)
+func (r *memoryEntityRepository) GetDeleted(ctx context.Context, params models.ListParams) (*models.EntityPage, error) {
+	return r.list(ctx, params, true)
+}
+
+// list retrieves a page of either live or soft-deleted entities
+func (r *memoryEntityRepository) list(ctx context.Context, params models.ListParams, deleted bool) (*models.EntityPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	keys, err := sortKeys(params.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := r.filter(params.Filters, deleted)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entities, func(i, j int) bool {
+		return compareKeys(entities[i], keys, cursorValues(entities[j], keys)) < 0
+	})
+
+	page := &models.EntityPage{Total: len(entities)}
+
+	hasMore := false
+	switch {
+	case params.UsesCursor():
+		reverse := params.Before != ""
+		token := params.After
+		if reverse {
+			token = params.Before
+		}
+		values, err := decodeCursor(token, keys)
+		if err != nil {
+			return nil, err
+		}
+
+		var selected []*models.Entity
+		for _, entity := range entities {
+			cmp := compareKeys(entity, keys, values)
+			if (!reverse && cmp > 0) || (reverse && cmp < 0) {
+				selected = append(selected, entity)
+			}
+		}
+
+		if len(selected) > params.Limit {
+			hasMore = true
+			if reverse {
+				selected = selected[len(selected)-params.Limit:]
+			} else {
+				selected = selected[:params.Limit]
+			}
+		}
+		entities = selected
+	case params.Offset >= len(entities):
+		entities = nil
+	default:
+		entities = entities[params.Offset:]
+		if len(entities) > params.Limit {
+			entities = entities[:params.Limit]
+		}
+	}
+
+	switch {
+	case params.After != "":
+		page.HasNext = hasMore
+		page.HasPrev = true
+	case params.Before != "":
+		page.HasNext = true
+		page.HasPrev = hasMore
+	default:
+		page.HasNext = params.Offset+len(entities) < page.Total
+		page.HasPrev = params.Offset > 0
+	}
+
+	if len(entities) > 0 {
+		page.PrevCursor = cursorFor(entities[0], keys)
+		page.NextCursor = cursorFor(entities[len(entities)-1], keys)
+	}
+
+	page.Entities = append([]*models.Entity{}, entities...)
+	return page, nil
+}
+
+// filter returns copies of the live or deleted entities matching every filter
+func (r *memoryEntityRepository) filter(filters []models.Filter, deleted bool) ([]*models.Entity, error) {
+	defer r.lock()()
+
+	entities := []*models.Entity{}
+	for _, stored := range r.store.entities {
+		if (stored.DeletedAt != nil) != deleted {
+			continue
+		}
+
+		entity := stored
+		matches := true
+		for _, filter := range filters {
+			ok, err := matchFilter(&entity, filter)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			entities = append(entities, &entity)
+		}
+	}
+
+	return entities, nil
+}
+
+// matchFilter reports whether entity satisfies a list filter
+func matchFilter(entity *models.Entity, filter models.Filter) (bool, error) {
+	if _, ok := entityColumns[filter.Field]; !ok {
+		return false, errors.New(errors.CodeInvalidRequest)
+	}
+
+	switch filter.Operator {
+	case "contains":
+		return strings.Contains(strings.ToLower(sortValue(entity, filter.Field)), strings.ToLower(filter.Value)), nil
+	case "prefix":
+		return strings.HasPrefix(strings.ToLower(sortValue(entity, filter.Field)), strings.ToLower(filter.Value)), nil
+	}
+
+	cmp, err := compareField(entity, filter.Field, filter.Value)
+	if err != nil {
+		return false, err
+	}
+
+	switch filter.Operator {
+	case "eq":
+		return cmp == 0, nil
+	case "ne":
+		return cmp != 0, nil
+	case "gt":
+		return cmp > 0, nil
+	case "gte":
+		return cmp >= 0, nil
+	case "lt":
+		return cmp < 0, nil
+	case "lte":
+		return cmp <= 0, nil
+	}
+	return false, errors.New(errors.CodeInvalidRequest)
+}
+
+// compareField compares a field of entity with a value in query argument form
+func compareField(entity *models.Entity, field string, value string) (int, error) {
+	switch field {
+	case "name":
+		return strings.Compare(entity.Name, value), nil
+	case "created_at", "updated_at":
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return 0, errors.New(errors.CodeInvalidRequest)
+		}
+		current := entity.CreatedAt
+		if field == "updated_at" {
+			current = entity.UpdatedAt
+		}
+		return current.Compare(t), nil
+	default:
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, errors.New(errors.CodeInvalidRequest)
+		}
+		return compareInts(entity.ID, id), nil
+	}
+}
+
+// compareInts returns -1, 0 or +1 as a is less than, equal to or greater than b
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareKeys compares entity with a keyset position in sort order
+func compareKeys(entity *models.Entity, keys []models.SortField, values []interface{}) int {
+	for i, key := range keys {
+		cmp := compareKey(entity, key.Field, values[i])
+		if key.Desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// compareKey compares a field of entity with a sort key value parsed by keyValue
+func compareKey(entity *models.Entity, field string, value interface{}) int {
+	switch field {
+	case "name":
+		return strings.Compare(entity.Name, value.(string))
+	case "created_at":
+		return entity.CreatedAt.Compare(value.(time.Time))
+	case "updated_at":
+		return entity.UpdatedAt.Compare(value.(time.Time))
+	default:
+		return compareInts(entity.ID, value.(int))
+	}
+}
+
+// cursorValues returns the keyset position of entity
+func cursorValues(entity *models.Entity, keys []models.SortField) []interface{} {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		switch key.Field {
+		case "name":
+			values[i] = entity.Name
+		case "created_at":
+			values[i] = entity.CreatedAt
+		case "updated_at":
+			values[i] = entity.UpdatedAt
+		default:
+			values[i] = entity.ID
+		}
+	}
+	return values
+}
+
+// Search finds live entities whose name contains words of query, scored by
+// the fraction of words found
+func (r *memoryEntityRepository) Search(ctx context.Context, query string, limit int) ([]*models.EntitySearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+
+	results := []*models.EntitySearchResult{}
+	unlock := r.lock()
+	for _, entity := range r.store.entities {
+		if entity.DeletedAt != nil || len(words) == 0 {
+			continue
+		}
+
+		name := strings.ToLower(entity.Name)
+		found := 0
+		for _, word := range words {
+			if strings.Contains(name, word) {
+				found++
+			}
+		}
+
+		if found > 0 {
+			results = append(results, &models.EntitySearchResult{
+				Entity: entity,
+				Score:  float64(found) / float64(len(words)),
+			})
+		}
+	}
+	unlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// Update modifies a live entity and bumps its version.
+// When entity.Version is non-zero the update only applies to that version.
+func (r *memoryEntityRepository) Update(ctx context.Context, id int, entity *models.Entity) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer r.lock()()
+
+	stored, err := r.writable(id, entity.Version)
+	if err != nil {
+		return err
+	}
+
+	stored.Name = entity.Name
+	r.touch(&stored)
+
+	entity.CreatedAt = stored.CreatedAt
+	entity.UpdatedAt = stored.UpdatedAt
+	entity.Version = stored.Version
+	return nil
+}
+
+// UpdateFields sets only the given fields of an entity and bumps its version
+func (r *memoryEntityRepository) UpdateFields(ctx context.Context, id int, fields map[string]interface{}, version int) (*models.Entity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for name := range fields {
+		if _, ok := writableColumns[name]; !ok {
+			return nil, errors.New(errors.CodeInvalidRequest)
+		}
+	}
+
+	defer r.lock()()
+
+	stored, err := r.writable(id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := fields["name"]; ok {
+		name, ok := value.(string)
+		if !ok {
+			return nil, errors.New(errors.CodeInvalidRequest)
+		}
+		stored.Name = name
+	}
+
+	r.touch(&stored)
+	return &stored, nil
+}
+
+// Delete soft-deletes an entity and bumps its version
+func (r *memoryEntityRepository) Delete(ctx context.Context, id int, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer r.lock()()
+
+	stored, err := r.writable(id, version)
+	if err != nil {
+		return err
+	}
+
+	deletedAt := now()
+	stored.DeletedAt = &deletedAt
+	stored.UpdatedAt = deletedAt
+	stored.Version++
+	r.store.entities[id] = stored
+	return nil
+}
+
+// Restore brings a soft-deleted entity back from the trash
+func (r *memoryEntityRepository) Restore(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer r.lock()()
+
+	stored, ok := r.store.entities[id]
+	if !ok || stored.DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+
+	stored.DeletedAt = nil
+	r.touch(&stored)
+	return nil
+}
+
+// Purge permanently removes a soft-deleted entity
+func (r *memoryEntityRepository) Purge(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer r.lock()()
+
+	stored, ok := r.store.entities[id]
+	if !ok || stored.DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+
+	delete(r.store.entities, id)
+	return nil
+}
+
+// writable returns a live entity that a write at version may change.
+// The errors match missingOrConflict in the PostgreSQL repository.
+func (r *memoryEntityRepository) writable(id int, version int) (models.Entity, error) {
+	stored, ok := r.store.entities[id]
+	if !ok || stored.DeletedAt != nil {
+		return stored, sql.ErrNoRows
+	}
+
+	if version != 0 && stored.Version != version {
+		return stored, errors.New(errors.CodePreconditionFailed)
+	}
+
+	return stored, nil
+}
+
+// touch stamps a modified entity and stores it
+func (r *memoryEntityRepository) touch(entity *models.Entity) {
+	entity.UpdatedAt = now()
+	entity.Version++
+	r.store.entities[entity.ID] = *entity
+}
+
+// memoryUnitOfWork implements UnitOfWork for an in-memory repository
+type memoryUnitOfWork struct {
+	store *memoryStore
+}
+
+// NewMemoryUnitOfWork creates a unit of work over a repository created by
+// NewMemoryEntityRepository. Units run one at a time and roll back by restoring
+// the entities they started with; like a database sequence, IDs handed out
+// inside a rolled back unit are not reused. It returns an error for any other
+// repository, including wrapped ones.
+func NewMemoryUnitOfWork(repo EntityRepository) (UnitOfWork, error) {
+	memory, ok := repo.(*memoryEntityRepository)
+	if !ok {
+		return nil, fmt.Errorf("repository: %T is not an in-memory repository", repo)
+	}
+
+	return &memoryUnitOfWork{store: memory.store}, nil
+}
+
+// Do runs fn with exclusive access to the store
+func (u *memoryUnitOfWork) Do(ctx context.Context, fn func(repo EntityRepository) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+
+	snapshot := make(map[int]models.Entity, len(u.store.entities))
+	for id, entity := range u.store.entities {
+		snapshot[id] = entity
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			u.store.entities = snapshot
+			panic(p)
+		}
+	}()
+
+	if err := fn(&memoryEntityRepository{store: u.store, inUnit: true}); err != nil {
+		u.store.entities = snapshot
+		return err
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"context"
+
 	"learn-api/internal/models"
 
 	"github.com/stretchr/testify/mock"
@@ -12,14 +14,24 @@ type EntityRepositoryMock struct {
 }
 
 // Create mocks the Create method
-func (m *EntityRepositoryMock) Create(entity *models.Entity) error {
-	args := m.Called(entity)
+func (m *EntityRepositoryMock) Create(ctx context.Context, entity *models.Entity) error {
+	args := m.Called(ctx, entity)
 	return args.Error(0)
 }
 
 // GetByID mocks the GetByID method
-func (m *EntityRepositoryMock) GetByID(id int) (*models.Entity, error) {
-	args := m.Called(id)
+func (m *EntityRepositoryMock) GetByID(ctx context.Context, id int) (*models.Entity, error) {
+	args := m.Called(ctx, id)
+	entity, ok := args.Get(0).(*models.Entity)
+	if ok {
+		return entity, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// GetByIDForUpdate mocks the GetByIDForUpdate method
+func (m *EntityRepositoryMock) GetByIDForUpdate(ctx context.Context, id int) (*models.Entity, error) {
+	args := m.Called(ctx, id)
 	entity, ok := args.Get(0).(*models.Entity)
 	if ok {
 		return entity, args.Error(1)
@@ -28,24 +40,66 @@ func (m *EntityRepositoryMock) GetByID(id int) (*models.Entity, error) {
 }
 
 // GetAll mocks the GetAll method
-func (m *EntityRepositoryMock) GetAll() ([]*models.Entity, error) {
-	args := m.Called()
-	entities, ok := args.Get(0).([]*models.Entity)
+func (m *EntityRepositoryMock) GetAll(ctx context.Context, params models.ListParams) (*models.EntityPage, error) {
+	args := m.Called(ctx, params)
+	page, ok := args.Get(0).(*models.EntityPage)
 	if ok {
-		return entities, args.Error(1)
+		return page, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// Search mocks the Search method
+func (m *EntityRepositoryMock) Search(ctx context.Context, query string, limit int) ([]*models.EntitySearchResult, error) {
+	args := m.Called(ctx, query, limit)
+	results, ok := args.Get(0).([]*models.EntitySearchResult)
+	if ok {
+		return results, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
 // Update mocks the Update method
-func (m *EntityRepositoryMock) Update(id int, entity *models.Entity) error {
-	args := m.Called(id, entity)
+func (m *EntityRepositoryMock) Update(ctx context.Context, id int, entity *models.Entity) error {
+	args := m.Called(ctx, id, entity)
 	return args.Error(0)
 }
 
+// UpdateFields mocks the UpdateFields method
+func (m *EntityRepositoryMock) UpdateFields(ctx context.Context, id int, fields map[string]interface{}, version int) (*models.Entity, error) {
+	args := m.Called(ctx, id, fields, version)
+	entity, ok := args.Get(0).(*models.Entity)
+	if ok {
+		return entity, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 // Delete mocks the Delete method
-func (m *EntityRepositoryMock) Delete(id int) error {
-	args := m.Called(id)
+func (m *EntityRepositoryMock) Delete(ctx context.Context, id int, version int) error {
+	args := m.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+// GetDeleted mocks the GetDeleted method
+func (m *EntityRepositoryMock) GetDeleted(ctx context.Context, params models.ListParams) (*models.EntityPage, error) {
+	args := m.Called(ctx, params)
+	page, ok := args.Get(0).(*models.EntityPage)
+	if ok {
+		return page, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// Restore mocks the Restore method
+func (m *EntityRepositoryMock) Restore(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// Purge mocks the Purge method
+func (m *EntityRepositoryMock) Purge(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
@@ -57,4 +111,4 @@ func (m *EntityRepositoryMock) AssertExpectations(t mock.TestingT) bool {
 // On sets up a mock expectation
 func (m *EntityRepositoryMock) On(methodName string, arguments ...interface{}) *mock.Call {
 	return m.Mock.On(methodName, arguments...)
-}
\ No newline at end of file
+}
@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+
+	"learn-api/internal/repository"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// UnitOfWorkMock is a mock implementation of the UnitOfWork interface.
+// Unless Do is set up to return an error, the unit of work is run against Repo.
+type UnitOfWorkMock struct {
+	mock.Mock
+	Repo repository.EntityRepository
+}
+
+// Do mocks the Do method
+func (m *UnitOfWorkMock) Do(ctx context.Context, fn func(repo repository.EntityRepository) error) error {
+	args := m.Called(ctx)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(m.Repo)
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ReplicaSource hands out read replicas; *database.Pool implements it
+type ReplicaSource interface {
+	// Replica returns a replica to read from, or the primary when none is available
+	Replica() *sql.DB
+}
+
+// primaryKey marks contexts whose reads must go to the primary
+type primaryKey struct{}
+
+// WithReplicas sends GetByID, GetAll, GetDeleted and Search to replicas from source.
+// Writes, locked reads and everything inside a unit of work stay on the primary.
+func WithReplicas(source ReplicaSource) Option {
+	return func(r *entityRepository) {
+		r.replicas = source
+	}
+}
+
+// WithPrimary returns a context whose reads go to the primary, for callers that
+// must see their own recent writes before the replicas catch up
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadsFromPrimary reports whether ctx was marked by WithPrimary
+func ReadsFromPrimary(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryKey{}).(bool)
+	return forced
+}
+
+// reader returns the handle for a read-only query
+func (r *entityRepository) reader(ctx context.Context) DBTX {
+	if r.replicas == nil || ReadsFromPrimary(ctx) {
+		return r.db
+	}
+	return traceDB(r.replicas.Replica())
+}
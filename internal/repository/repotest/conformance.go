@@ -0,0 +1,550 @@
+// Package repotest provides the conformance suite every EntityRepository
+// implementation must pass.
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"learn-api/internal/models"
+	"learn-api/internal/repository"
+	"learn-api/pkg/errors"
+)
+
+// Factory returns an empty repository and a unit of work over the same storage
+type Factory func(t *testing.T) (repository.EntityRepository, repository.UnitOfWork)
+
+// RunConformance runs the conformance suite against the repositories created by newRepo.
+// Each subtest starts from a fresh, empty repository.
+func RunConformance(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.EntityRepository, uow repository.UnitOfWork)
+	}{
+		{"Create", testCreate},
+		{"GetByIDNotFound", testGetByIDNotFound},
+		{"Update", testUpdate},
+		{"UpdateFields", testUpdateFields},
+		{"DeleteRestorePurge", testDeleteRestorePurge},
+		{"OffsetPagination", testOffsetPagination},
+		{"CursorPagination", testCursorPagination},
+		{"FilterAndSort", testFilterAndSort},
+		{"InvalidCursor", testInvalidCursor},
+		{"Search", testSearch},
+		{"UnitOfWork", testUnitOfWork},
+		{"CanceledContext", testCanceledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, uow := newRepo(t)
+			tt.run(t, repo, uow)
+		})
+	}
+}
+
+// create stores entities with the given names and returns them
+func create(t *testing.T, repo repository.EntityRepository, names ...string) []*models.Entity {
+	t.Helper()
+
+	entities := make([]*models.Entity, len(names))
+	for i, name := range names {
+		entities[i] = &models.Entity{Name: name}
+		if err := repo.Create(context.Background(), entities[i]); err != nil {
+			t.Fatalf("Error creating entity: %v", err)
+		}
+	}
+	return entities
+}
+
+// names returns the names of entities in order
+func names(entities []*models.Entity) []string {
+	result := make([]string, len(entities))
+	for i, entity := range entities {
+		result[i] = entity.Name
+	}
+	return result
+}
+
+func testCreate(t *testing.T, repo repository.EntityRepository, _ repository.UnitOfWork) {
+	entities := create(t, repo, "First Entity", "Second Entity")
+
+	first, second := entities[0], entities[1]
+	if first.ID != 1 || second.ID != 2 {
+		t.Errorf("Expected auto-increment IDs 1 and 2, got %d and %d", first.ID, second.ID)
+	}
+
+	if first.CreatedAt.IsZero() || !first.UpdatedAt.Equal(first.CreatedAt) {
+		t.Errorf("Expected matching timestamps, got %v and %v", first.CreatedAt, first.UpdatedAt)
+	}
+
+	if first.Version != 1 {
+		t.Errorf("Expected version 1, got %d", first.Version)
+	}
+
+	found, err := repo.GetByID(context.Background(), second.ID)
+	if err != nil || found == nil {
+		t.Fatalf("Expected entity to be found, got %v, %v", found, err)
+	}
+
+	if found.Name != second.Name || !found.CreatedAt.Equal(second.CreatedAt) || found.Version != second.Version {
+		t.Errorf("Expected %+v, got %+v", second, found)
+	}
+}
+
+func testGetByIDNotFound(t *testing.T, repo repository.EntityRepository, _ repository.UnitOfWork) {
+	entity, err := repo.GetByID(context.Background(), 999)
+	if err != nil || entity != nil {
+		t.Errorf("Expected nil, nil for a missing entity, got %v, %v", entity, err)
+	}
+}
+
+func testUpdate(t *testing.T, repo repository.EntityRepository, _ repository.UnitOfWork) {
+	entity := create(t, repo, "Original Name")[0]
+
+	// An unconditional update bumps the version
+	update := &models.Entity{Name: "Updated Name"}
+	if err := repo.Update(context.Background(), entity.ID, update); err != nil {
+		t.Fatalf("Error updating entity: %v", err)
+	}
+
+	if update.Version != 2 || !update.CreatedAt.Equal(entity.CreatedAt) {
+		t.Errorf("Expected the updated entity to be returned, got %+v", update)
+	}
+
+	if update.UpdatedAt.Before(entity.UpdatedAt) {
+		t.Errorf("Expected UpdatedAt to move forward, got %v", update.UpdatedAt)
+	}
+
+	// A stale version is rejected
+	stale := &models.Entity{Name: "Stale Name", Version: 1}
+	if err := repo.Update(context.Background(), entity.ID, stale); !errors.Is(err, errors.CodePreconditionFailed) {
+		t.Errorf("Expected CodePreconditionFailed, got %v", err)
+	}
+
+	// The current version is accepted
+	current := &models.Entity{Name: "Current Name", Version: 2}
+	if err := repo.Update(context.Background(), entity.ID, current); err != nil || current.Version != 3 {
+		t.Errorf("Expected version 3, got %d, %v", current.Version, err)
+	}
+
+	// A missing entity is reported as no rows, whatever the version
+	if err := repo.Update(context.Background(), 999, &models.Entity{Name: "Missing"}); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+
+	if err := repo.Update(context.Background(), 999, &models.Entity{Name: "Missing", Version: 1}); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func testUpdateFields(t *testing.T, repo repository.EntityRepository, _ repository.UnitOfWork) {
+	entity := create(t, repo, "Before Patch")[0]
+
+	updated, err := repo.UpdateFields(context.Background(), entity.ID, map[string]interface{}{"name": "After Patch"}, 1)
+	if err != nil {
+		t.Fatalf("Error updating fields: %v", err)
+	}
+
+	if updated.Name != "After Patch" || updated.Version != 2 || updated.ID != entity.ID {
+		t.Errorf("Unexpected entity after update: %+v", updated)
+	}
+
+	if _, err := repo.UpdateFields(context.Background(), entity.ID, map[string]interface{}{"name": "Stale"}, 1); !errors.Is(err, errors.CodePreconditionFailed) {
+		t.Errorf("Expected CodePreconditionFailed, got %v", err)
+	}
+
+	if _, err := repo.UpdateFields(context.Background(), entity.ID, map[string]interface{}{"id": 5}, 0); !errors.Is(err, errors.CodeInvalidRequest) {
+		t.Errorf("Expected CodeInvalidRequest for a read-only field, got %v", err)
+	}
+
+	if _, err := repo.UpdateFields(context.Background(), 999, map[string]interface{}{"name": "Missing"}, 0); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func testDeleteRestorePurge(t *testing.T, repo repository.EntityRepository, _ repository.UnitOfWork) {
+	entities := create(t, repo, "Trashed Entity", "Kept Entity")
+	trashed := entities[0]
+
+	if err := repo.Delete(context.Background(), trashed.ID, 2); !errors.Is(err, errors.CodePreconditionFailed) {
+		t.Errorf("Expected CodePreconditionFailed, got %v", err)
+	}
+
+	// Leave time for updated_at to move
+	time.Sleep(time.Millisecond)
+	if err := repo.Delete(context.Background(), trashed.ID, 1); err != nil {
+		t.Fatalf("Error deleting entity: %v", err)
+	}
+
+	// Deleted entities are hidden from reads and writes
+	if entity, err := repo.GetByID(context.Background(), trashed.ID); err != nil || entity != nil {
+		t.Errorf("Expected deleted entity to be hidden, got %v, %v", entity, err)
+	}
+
+	if err := repo.Delete(context.Background(), trashed.ID, 0); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows deleting twice, got %v", err)
+	}
+
+	live, err := repo.GetAll(context.Background(), models.ListParams{Limit: 10})
+	if err != nil || live.Total != 1 {
+		t.Fatalf("Expected 1 live entity, got %v, %v", live, err)
+	}
+
+	trash, err := repo.GetDeleted(context.Background(), models.ListParams{Limit: 10})
+	if err != nil || trash.Total != 1 || trash.Entities[0].ID != trashed.ID {
+		t.Fatalf("Expected the deleted entity in the trash, got %v, %v", trash, err)
+	}
+
+	if trash.Entities[0].DeletedAt == nil || trash.Entities[0].Version != 2 {
+		t.Errorf("Expected deleted_at and version 2, got %+v", trash.Entities[0])
+	}
+
+	// Deleting and restoring are changes like any other
+	deleted := trash.Entities[0]
+	if !deleted.UpdatedAt.After(trashed.UpdatedAt) {
+		t.Errorf("Expected updated_at to advance past %v on delete, got %v", trashed.UpdatedAt, deleted.UpdatedAt)
+	}
+
+	// Only deleted entities can be restored or purged
+	if err := repo.Restore(context.Background(), entities[1].ID); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows restoring a live entity, got %v", err)
+	}
+
+	if err := repo.Purge(context.Background(), entities[1].ID); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows purging a live entity, got %v", err)
+	}
+
+	time.Sleep(time.Millisecond)
+	if err := repo.Restore(context.Background(), trashed.ID); err != nil {
+		t.Fatalf("Error restoring entity: %v", err)
+	}
+
+	restored, err := repo.GetByID(context.Background(), trashed.ID)
+	if err != nil || restored == nil || restored.DeletedAt != nil || restored.Version != 3 {
+		t.Fatalf("Expected restored entity at version 3, got %v, %v", restored, err)
+	}
+
+	if !restored.UpdatedAt.After(deleted.UpdatedAt) {
+		t.Errorf("Expected updated_at to advance past %v on restore, got %v", deleted.UpdatedAt, restored.UpdatedAt)
+	}
+
+	if err := repo.Delete(context.Background(), trashed.ID, 0); err != nil {
+		t.Fatalf("Error deleting entity: %v", err)
+	}
+
+	if err := repo.Purge(context.Background(), trashed.ID); err != nil {
+		t.Fatalf("Error purging entity: %v", err)
+	}
+
+	if err := repo.Restore(context.Background(), trashed.ID); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows restoring a purged entity, got %v", err)
+	}
+}
+
+func testOffsetPagination(t *testing.T, repo repository.EntityRepository, _ repository.UnitOfWork) {
+	create(t, repo, "One", "Two", "Three", "Four", "Five")
+
+	page, err := repo.GetAll(context.Background(), models.ListParams{Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("Error retrieving page: %v", err)
+	}
+
+	if page.Total != 5 || len(page.Entities) != 2 || page.Entities[0].ID != 3 || page.Entities[1].ID != 4 {
+		t.Fatalf("Unexpected page: total=%d entities=%v", page.Total, names(page.Entities))
+	}
+
+	if !page.HasNext || !page.HasPrev {
+		t.Errorf("Expected next and prev, got next=%v prev=%v", page.HasNext, page.HasPrev)
+	}
+
+	last, err := repo.GetAll(context.Background(), models.ListParams{Limit: 2, Offset: 4})
+	if err != nil || len(last.Entities) != 1 || last.HasNext {
+		t.Errorf("Expected a last page with one entity, got %v, %v", last, err)
+	}
+
+	past, err := repo.GetAll(context.Background(), models.ListParams{Limit: 2, Offset: 10})
+	if err != nil || len(past.Entities) != 0 || past.Total != 5 {
+		t.Errorf("Expected an empty page past the end, got %v, %v", past, err)
+	}
+}
+
+func testCursorPagination(t *testing.T, repo repository.EntityRepository, _ repository.UnitOfWork) {
+	create(t, repo, "One", "Two", "Three", "Four", "Five")
+
+	first, err := repo.GetAll(context.Background(), models.ListParams{Limit: 2})
+	if err != nil {
+		t.Fatalf("Error retrieving first page: %v", err)
+	}
+
+	second, err := repo.GetAll(context.Background(), models.ListParams{Limit: 2, After: first.NextCursor})
+	if err != nil {
+		t.Fatalf("Error retrieving second page: %v", err)
+	}
+
+	if len(second.Entities) != 2 || second.Entities[0].ID != 3 || !second.HasNext || !second.HasPrev {
+		t.Fatalf("Unexpected second page: %v next=%v prev=%v", names(second.Entities), second.HasNext, second.HasPrev)
+	}
+
+	third, err := repo.GetAll(context.Background(), models.ListParams{Limit: 2, After: second.NextCursor})
+	if err != nil || len(third.Entities) != 1 || third.HasNext {
+		t.Fatalf("Expected a last page with one entity, got %v, %v", third, err)
+	}
+
+	back, err := repo.GetAll(context.Background(), models.ListParams{Limit: 2, Before: second.PrevCursor})
+	if err != nil {
+		t.Fatalf("Error retrieving previous page: %v", err)
+	}
+
+	if len(back.Entities) != 2 || back.Entities[0].ID != 1 || back.Entities[1].ID != 2 || back.HasPrev || !back.HasNext {
+		t.Errorf("Expected previous page to match the first page, got %v prev=%v", names(back.Entities), back.HasPrev)
+	}
+}
+
+func testFilterAndSort(t *testing.T, repo repository.EntityRepository, _ repository.UnitOfWork) {
+	create(t, repo, "Filter Beta", "Filter Alpha", "Filter Gamma", "Unrelated 100%")
+
+	params := models.ListParams{
+		Limit:   2,
+		Filters: []models.Filter{{Field: "name", Operator: "prefix", Value: "filter "}},
+		Sort:    []models.SortField{{Field: "name", Desc: true}},
+	}
+
+	first, err := repo.GetAll(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Error retrieving entities: %v", err)
+	}
+
+	if first.Total != 3 || len(first.Entities) != 2 || first.Entities[0].Name != "Filter Gamma" || first.Entities[1].Name != "Filter Beta" {
+		t.Fatalf("Unexpected first page: total=%d entities=%v", first.Total, names(first.Entities))
+	}
+
+	params.After = first.NextCursor
+	second, err := repo.GetAll(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Error retrieving second page: %v", err)
+	}
+
+	if len(second.Entities) != 1 || second.Entities[0].Name != "Filter Alpha" || second.HasNext {
+		t.Errorf("Unexpected second page: %v", names(second.Entities))
+	}
+
+	// Comparison operators on the ID
+	ranged, err := repo.GetAll(context.Background(), models.ListParams{
+		Limit: 10,
+		Filters: []models.Filter{
+			{Field: "id", Operator: "gt", Value: "1"},
+			{Field: "id", Operator: "lte", Value: "3"},
+		},
+	})
+	if err != nil || ranged.Total != 2 || ranged.Entities[0].ID != 2 || ranged.Entities[1].ID != 3 {
+		t.Errorf("Expected IDs 2 and 3, got %v, %v", ranged, err)
+	}
+
+	// Timestamps are compared as instants, whatever their offset
+	bangkok := time.FixedZone("ICT", 7*60*60)
+	created := first.Entities[0].CreatedAt
+	for _, tt := range []struct {
+		value string
+		total int
+	}{
+		{value: created.Add(-time.Second).In(bangkok).Format(time.RFC3339Nano), total: 1},
+		{value: created.Add(time.Second).In(bangkok).Format(time.RFC3339Nano), total: 0},
+	} {
+		since, err := repo.GetAll(context.Background(), models.ListParams{
+			Limit: 10,
+			Filters: []models.Filter{
+				{Field: "name", Operator: "eq", Value: "Filter Gamma"},
+				{Field: "created_at", Operator: "gte", Value: tt.value},
+			},
+		})
+		if err != nil || since.Total != tt.total {
+			t.Errorf("Expected %d entity created since %s, got %v, %v", tt.total, tt.value, since, err)
+		}
+	}
+
+	// LIKE wildcards in filter values are matched literally
+	literal, err := repo.GetAll(context.Background(), models.ListParams{
+		Limit:   10,
+		Filters: []models.Filter{{Field: "name", Operator: "contains", Value: "100%"}},
+	})
+	if err != nil || literal.Total != 1 {
+		t.Errorf("Expected 1 entity matching '100%%', got %v, %v", literal, err)
+	}
+
+	if _, err := repo.GetAll(context.Background(), models.ListParams{Limit: 10, Sort: []models.SortField{{Field: "secret"}}}); !errors.Is(err, errors.CodeInvalidRequest) {
+		t.Errorf("Expected CodeInvalidRequest for an unknown sort field, got %v", err)
+	}
+}
+
+func testInvalidCursor(t *testing.T, repo repository.EntityRepository, _ repository.UnitOfWork) {
+	create(t, repo, "One", "Two", "Three")
+
+	if _, err := repo.GetAll(context.Background(), models.ListParams{Limit: 2, After: "not-a-cursor"}); !errors.Is(err, errors.CodeInvalidCursor) {
+		t.Errorf("Expected CodeInvalidCursor, got %v", err)
+	}
+
+	// A cursor issued for one order is rejected for another
+	page, err := repo.GetAll(context.Background(), models.ListParams{Limit: 2})
+	if err != nil {
+		t.Fatalf("Error retrieving page: %v", err)
+	}
+
+	params := models.ListParams{Limit: 2, After: page.NextCursor, Sort: []models.SortField{{Field: "name"}}}
+	if _, err := repo.GetAll(context.Background(), params); !errors.Is(err, errors.CodeInvalidCursor) {
+		t.Errorf("Expected CodeInvalidCursor, got %v", err)
+	}
+
+	// A well-formed cursor whose values were edited to be out of their key's type is rejected too
+	tampered := []struct {
+		cursor string
+		sort   []models.SortField
+	}{
+		{`{"s":"id","v":["abc"]}`, nil},
+		{`{"s":"id","v":["3000000000"]}`, nil},
+		{`{"s":"created_at,id","v":["x","1"]}`, []models.SortField{{Field: "created_at"}}},
+	}
+	for _, tc := range tampered {
+		params := models.ListParams{Limit: 2, After: base64.RawURLEncoding.EncodeToString([]byte(tc.cursor)), Sort: tc.sort}
+		if _, err := repo.GetAll(context.Background(), params); !errors.Is(err, errors.CodeInvalidCursor) {
+			t.Errorf("Cursor %s: expected CodeInvalidCursor, got %v", tc.cursor, err)
+		}
+	}
+}
+
+func testSearch(t *testing.T, repo repository.EntityRepository, _ repository.UnitOfWork) {
+	entities := create(t, repo, "Searchable Gizmo", "Searchable Gadget", "Something Else")
+
+	results, err := repo.Search(context.Background(), "gizmo", 10)
+	if err != nil {
+		t.Fatalf("Error searching entities: %v", err)
+	}
+
+	if len(results) == 0 || results[0].Name != "Searchable Gizmo" {
+		t.Fatalf("Expected 'Searchable Gizmo' first, got %v", results)
+	}
+
+	for i := 1; i < len(results); i++ {
+		if results[i].Score > results[i-1].Score {
+			t.Errorf("Expected results ordered by score, got %v", results)
+		}
+	}
+
+	limited, err := repo.Search(context.Background(), "searchable", 1)
+	if err != nil || len(limited) != 1 {
+		t.Errorf("Expected the limit to apply, got %v, %v", limited, err)
+	}
+
+	// Deleted entities are not found
+	if err := repo.Delete(context.Background(), entities[0].ID, 0); err != nil {
+		t.Fatalf("Error deleting entity: %v", err)
+	}
+
+	results, err = repo.Search(context.Background(), "gizmo", 10)
+	if err != nil {
+		t.Fatalf("Error searching entities: %v", err)
+	}
+
+	for _, result := range results {
+		if result.ID == entities[0].ID {
+			t.Errorf("Expected deleted entity to be excluded, got %v", results)
+		}
+	}
+}
+
+func testUnitOfWork(t *testing.T, repo repository.EntityRepository, uow repository.UnitOfWork) {
+	existing := create(t, repo, "Existing Entity")[0]
+
+	// A failing unit of work leaves no trace
+	var rolledBack models.Entity
+	err := uow.Do(context.Background(), func(tx repository.EntityRepository) error {
+		rolledBack.Name = "Rolled Back Entity"
+		if err := tx.Create(context.Background(), &rolledBack); err != nil {
+			return err
+		}
+		if err := tx.Update(context.Background(), existing.ID, &models.Entity{Name: "Rolled Back Name"}); err != nil {
+			return err
+		}
+		return errors.New(errors.CodeInvalidRequest)
+	})
+	if !errors.Is(err, errors.CodeInvalidRequest) {
+		t.Fatalf("Expected the callback error, got %v", err)
+	}
+
+	if entity, err := repo.GetByID(context.Background(), rolledBack.ID); err != nil || entity != nil {
+		t.Errorf("Expected rolled back entity to be absent, got %v, %v", entity, err)
+	}
+
+	if entity, err := repo.GetByID(context.Background(), existing.ID); err != nil || entity == nil || entity.Name != existing.Name || entity.Version != 1 {
+		t.Errorf("Expected rolled back update to be undone, got %v, %v", entity, err)
+	}
+
+	// Neither does one that panics
+	var panicked models.Entity
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the panic to be re-raised")
+			}
+		}()
+
+		uow.Do(context.Background(), func(tx repository.EntityRepository) error {
+			panicked.Name = "Panicked Entity"
+			if err := tx.Create(context.Background(), &panicked); err != nil {
+				return err
+			}
+			panic("unit of work failed")
+		})
+	}()
+
+	if entity, err := repo.GetByID(context.Background(), panicked.ID); err != nil || entity != nil {
+		t.Errorf("Expected panicked entity to be absent, got %v, %v", entity, err)
+	}
+
+	// A successful unit of work is committed, and its locked reads see its own writes
+	var committed models.Entity
+	err = uow.Do(context.Background(), func(tx repository.EntityRepository) error {
+		committed.Name = "Committed Entity"
+		if err := tx.Create(context.Background(), &committed); err != nil {
+			return err
+		}
+
+		locked, err := tx.GetByIDForUpdate(context.Background(), committed.ID)
+		if err != nil || locked == nil || locked.Name != committed.Name {
+			t.Errorf("Expected locked read of the new entity, got %v, %v", locked, err)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Error committing unit of work: %v", err)
+	}
+
+	if entity, err := repo.GetByID(context.Background(), committed.ID); err != nil || entity == nil {
+		t.Errorf("Expected committed entity to be found, got %v, %v", entity, err)
+	}
+}
+
+func testCanceledContext(t *testing.T, repo repository.EntityRepository, uow repository.UnitOfWork) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := repo.Create(ctx, &models.Entity{Name: "Canceled"}); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Create, got %v", err)
+	}
+
+	if _, err := repo.GetAll(ctx, models.ListParams{Limit: 10}); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from GetAll, got %v", err)
+	}
+
+	called := false
+	err := uow.Do(ctx, func(repository.EntityRepository) error {
+		called = true
+		return nil
+	})
+	if err == nil || called {
+		t.Errorf("Expected a canceled unit of work not to run, got %v", err)
+	}
+}
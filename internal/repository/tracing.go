@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"learn-api/internal/tracing"
+)
+
+// tracer records a span for every SQL statement
+var tracer = tracing.Tracer("learn-api/internal/repository")
+
+var (
+	// stringLiteral matches SQL string literals, including escaped quotes
+	stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	// numberLiteral matches numeric literals that are not part of a name or a $n placeholder
+	numberLiteral = regexp.MustCompile(`([^\w$.]|^)-?\d+(?:\.\d+)?`)
+	// whitespace matches runs of whitespace
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// SanitizeStatement prepares a SQL statement for a span attribute: literals are
+// replaced by ? so values never leave the process, and whitespace is collapsed.
+// Bound $n placeholders are kept.
+func SanitizeStatement(query string) string {
+	query = stringLiteral.ReplaceAllString(query, "?")
+	query = numberLiteral.ReplaceAllString(query, "${1}?")
+	return strings.TrimSpace(whitespace.ReplaceAllString(query, " "))
+}
+
+// tracedDB records a span and a debug log line for every statement run on a DBTX
+type tracedDB struct {
+	db DBTX
+}
+
+// traceDB wraps db so that its statements are traced
+func traceDB(db DBTX) DBTX {
+	if _, ok := db.(tracedDB); ok {
+		return db
+	}
+	return tracedDB{db: db}
+}
+
+// start begins the span of a statement, named after its operation such as SELECT,
+// and returns the function that ends it. A query that finds no row is not a failure.
+func (t tracedDB) start(ctx context.Context, query string) (context.Context, func(error)) {
+	statement := SanitizeStatement(query)
+	operation, _, _ := strings.Cut(statement, " ")
+	operation = strings.ToUpper(operation)
+
+	ctx, span := tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(statement),
+		),
+	)
+	began := time.Now()
+
+	return ctx, func(err error) {
+		attrs := []slog.Attr{
+			slog.String("statement", statement),
+			slog.Float64("duration_ms", float64(time.Since(began))/float64(time.Millisecond)),
+		}
+		if err != nil && err != sql.ErrNoRows {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		slog.LogAttrs(ctx, slog.LevelDebug, "SQL statement", attrs...)
+		span.End()
+	}
+}
+
+func (t tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, end := t.start(ctx, query)
+	result, err := t.db.ExecContext(ctx, query, args...)
+	end(err)
+	return result, err
+}
+
+// QueryContext traces a query until its first rows arrive; reading them is not included
+func (t tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, end := t.start(ctx, query)
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	end(err)
+	return rows, err
+}
+
+func (t tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, end := t.start(ctx, query)
+	row := t.db.QueryRowContext(ctx, query, args...)
+	end(row.Err())
+	return row
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// UnitOfWork runs several repository calls atomically
+type UnitOfWork interface {
+	// Do runs fn with a repository bound to a single database transaction.
+	// The transaction is committed if fn returns nil and rolled back if it
+	// returns an error or panics; the panic is then re-raised.
+	Do(ctx context.Context, fn func(repo EntityRepository) error) error
+}
+
+// TxBeginner starts database transactions.
+// It is satisfied by *sql.DB and *database.Pool.
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// unitOfWork implements UnitOfWork on top of a database connection pool
+type unitOfWork struct {
+	conn TxBeginner
+	opts []Option
+}
+
+// NewUnitOfWork creates a unit of work running its transactions on db. The options
+// configure the transaction-bound repositories handed to each unit, as in
+// NewEntityRepository. It panics if db is nil.
+func NewUnitOfWork(db TxBeginner, opts ...Option) UnitOfWork {
+	mustHandle(db)
+	return &unitOfWork{
+		conn: db,
+		opts: opts,
+	}
+}
+
+// Do runs fn inside a transaction
+func (u *unitOfWork) Do(ctx context.Context, fn func(repo EntityRepository) error) (err error) {
+	tx, err := u.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	// Everything in a unit of work runs on its transaction, reads included
+	repo := newEntityRepository(tx, u.opts...)
+	repo.replicas = nil
+
+	if err := fn(repo); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"learn-api/internal/models"
+	"learn-api/internal/repository"
+	"learn-api/pkg/errors"
+	"learn-api/pkg/validation"
+)
+
+// batchOp applies the batch item at index i using a repository bound to a unit of work
+type batchOp func(ctx context.Context, repo repository.EntityRepository, i int) (*models.Entity, error)
+
+// CreateEntities creates a batch of entities and reports the outcome of each item
+func (s *entityService) CreateEntities(ctx context.Context, req *models.BatchCreateRequest) (*models.BatchResult, error) {
+	invalid := make([]*errors.APIError, len(req.Items))
+	for i := range req.Items {
+		invalid[i] = validation.ToAPIError(validation.Struct(&req.Items[i]))
+	}
+
+	return s.runBatch(ctx, req.Atomic, invalid, http.StatusCreated, func(ctx context.Context, repo repository.EntityRepository, i int) (*models.Entity, error) {
+		return createEntity(ctx, repo, &req.Items[i])
+	})
+}
+
+// UpdateEntities updates a batch of entities and reports the outcome of each item
+func (s *entityService) UpdateEntities(ctx context.Context, req *models.BatchUpdateRequest) (*models.BatchResult, error) {
+	invalid := make([]*errors.APIError, len(req.Items))
+	for i := range req.Items {
+		invalid[i] = validation.ToAPIError(validation.Struct(&req.Items[i]))
+	}
+
+	return s.runBatch(ctx, req.Atomic, invalid, http.StatusOK, func(ctx context.Context, repo repository.EntityRepository, i int) (*models.Entity, error) {
+		item := req.Items[i]
+		return updateEntity(ctx, repo, item.ID, &models.EntityRequest{Name: item.Name}, item.Version)
+	})
+}
+
+// DeleteEntities soft-deletes a batch of entities and reports the outcome of each item
+func (s *entityService) DeleteEntities(ctx context.Context, req *models.BatchDeleteRequest) (*models.BatchResult, error) {
+	invalid := make([]*errors.APIError, len(req.Items))
+	for i := range req.Items {
+		invalid[i] = validation.ToAPIError(validation.Struct(&req.Items[i]))
+	}
+
+	return s.runBatch(ctx, req.Atomic, invalid, http.StatusNoContent, func(ctx context.Context, repo repository.EntityRepository, i int) (*models.Entity, error) {
+		item := req.Items[i]
+		return nil, deleteEntity(ctx, repo, item.ID, item.Version)
+	})
+}
+
+// runBatch applies op to every item that passed validation. Atomic batches run
+// in a single unit of work and stop at the first failure, marking every other item
+// as aborted. Best-effort batches apply each item in a unit of work of its own.
+func (s *entityService) runBatch(ctx context.Context, atomic bool, invalid []*errors.APIError, status int, op batchOp) (*models.BatchResult, error) {
+	result := &models.BatchResult{
+		Atomic:  atomic,
+		Results: make([]models.BatchItemResult, len(invalid)),
+	}
+
+	failed := false
+	fail := func(i int, apiErr *errors.APIError) {
+		result.Results[i].Status = apiErr.Status()
+		result.Results[i].Error = apiErr
+		failed = true
+	}
+
+	for i, apiErr := range invalid {
+		result.Results[i].Index = i
+		if apiErr != nil {
+			fail(i, apiErr)
+		}
+	}
+
+	apply := func(repo repository.EntityRepository, i int) error {
+		entity, err := op(ctx, repo, i)
+		if err != nil {
+			apiErr := errors.HandleError(err)
+			if apiErr.Status() >= http.StatusInternalServerError {
+				slog.ErrorContext(ctx, "Batch item failed", "index", i, "error", err)
+			}
+			fail(i, apiErr)
+			return apiErr
+		}
+		result.Results[i].Status = status
+		result.Results[i].Data = entity
+		return nil
+	}
+
+	switch {
+	case !atomic:
+		for i := range result.Results {
+			if invalid[i] != nil {
+				continue
+			}
+
+			err := s.uow.Do(ctx, func(repo repository.EntityRepository) error {
+				return apply(repo, i)
+			})
+			if err != nil && result.Results[i].Error == nil {
+				// The unit of work itself could not be started or committed
+				result.Results[i].Data = nil
+				fail(i, errors.HandleError(err))
+			}
+		}
+	case !failed:
+		err := s.uow.Do(ctx, func(repo repository.EntityRepository) error {
+			for i := range result.Results {
+				if err := apply(repo, i); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil && !failed {
+			// The unit of work itself could not be started or committed
+			return nil, err
+		}
+	}
+
+	for i := range result.Results {
+		item := &result.Results[i]
+		if atomic && failed && item.Error == nil {
+			// Nothing from a failed atomic batch was committed
+			aborted := errors.New(errors.CodeBatchAborted)
+			item.Status = aborted.Status()
+			item.Data = nil
+			item.Error = aborted
+		}
+
+		if item.Error == nil {
+			result.Succeeded++
+		} else {
+			result.Failed++
+		}
+	}
+
+	return result, nil
+}
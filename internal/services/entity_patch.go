@@ -0,0 +1,109 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+
+	"learn-api/internal/models"
+	"learn-api/pkg/errors"
+	"learn-api/pkg/validation"
+)
+
+// applyPatch applies a JSON Patch or JSON Merge Patch to the JSON representation
+// of entity and returns the writable fields whose values changed.
+// Read-only fields may appear in the patch (e.g. in a "test" operation) but must
+// not be modified.
+func applyPatch(entity *models.Entity, patch *models.EntityPatch) (map[string]interface{}, error) {
+	doc, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	switch patch.ContentType {
+	case models.MergePatchContentType:
+		patched, err = jsonpatch.MergePatch(doc, patch.Document)
+		if err != nil {
+			return nil, errors.New(errors.CodeInvalidPatch)
+		}
+	case models.JSONPatchContentType:
+		operations, err := jsonpatch.DecodePatch(patch.Document)
+		if err != nil {
+			return nil, errors.New(errors.CodeInvalidPatch)
+		}
+		patched, err = operations.Apply(doc)
+		if err != nil {
+			return nil, errors.New(errors.CodePatchConflict)
+		}
+	default:
+		return nil, errors.New(errors.CodeUnsupportedMediaType)
+	}
+
+	var original, result map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &original); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patched, &result); err != nil || result == nil {
+		return nil, errors.New(errors.CodeInvalidPatch)
+	}
+
+	var validationErrors []validation.ValidationError
+
+	// Every field other than name is read-only
+	keys := make([]string, 0, len(original)+len(result))
+	for key := range original {
+		keys = append(keys, key)
+	}
+	for key := range result {
+		if _, ok := original[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key == "name" {
+			continue
+		}
+		before, known := original[key]
+		after, present := result[key]
+		switch {
+		case !known:
+			validationErrors = append(validationErrors, validation.NewError(key, validation.CodeFieldUnknown, nil))
+		case !present || !jsonEqual(before, after):
+			validationErrors = append(validationErrors, validation.NewError(key, validation.CodeFieldReadOnly, nil))
+		}
+	}
+
+	var name string
+	if raw, ok := result["name"]; ok {
+		if err := json.Unmarshal(raw, &name); err != nil {
+			validationErrors = append(validationErrors, validation.NewError("name", validation.CodeNameNotString, nil))
+		}
+	}
+
+	req := models.EntityRequest{Name: name}
+	validationErrors = append(validationErrors, validation.Struct(&req)...)
+	if len(validationErrors) > 0 {
+		return nil, validation.ToAPIError(validationErrors)
+	}
+
+	changes := map[string]interface{}{}
+	if req.Name != entity.Name {
+		changes["name"] = req.Name
+	}
+
+	return changes, nil
+}
+
+// jsonEqual reports whether two JSON values are semantically equal
+func jsonEqual(a, b json.RawMessage) bool {
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
@@ -1,6 +1,10 @@
 package services
 
 import (
+	"context"
+	"log/slog"
+	"strings"
+
 	"learn-api/internal/models"
 	"learn-api/internal/repository"
 	"learn-api/pkg/errors"
@@ -8,32 +12,82 @@ import (
 
 // EntityService interface defines the methods for entity service operations
 type EntityService interface {
-	CreateEntity(req *models.EntityRequest) (*models.Entity, error)
-	GetEntityByID(id int) (*models.Entity, error)
-	GetAllEntities() ([]*models.Entity, error)
-	UpdateEntity(id int, req *models.EntityRequest) (*models.Entity, error)
-	DeleteEntity(id int) error
+	CreateEntity(ctx context.Context, req *models.EntityRequest) (*models.Entity, error)
+	GetEntityByID(ctx context.Context, id int) (*models.Entity, error)
+	GetAllEntities(ctx context.Context, params models.ListParams) (*models.EntityPage, error)
+	SearchEntities(ctx context.Context, query string, limit int) ([]*models.EntitySearchResult, error)
+	UpdateEntity(ctx context.Context, id int, req *models.EntityRequest, version int) (*models.Entity, error)
+	PatchEntity(ctx context.Context, id int, patch *models.EntityPatch, version int) (*models.Entity, error)
+	DeleteEntity(ctx context.Context, id int, version int) error
+	GetDeletedEntities(ctx context.Context, params models.ListParams) (*models.EntityPage, error)
+	RestoreEntity(ctx context.Context, id int) (*models.Entity, error)
+	PurgeEntity(ctx context.Context, id int) error
+	CreateEntities(ctx context.Context, req *models.BatchCreateRequest) (*models.BatchResult, error)
+	UpdateEntities(ctx context.Context, req *models.BatchUpdateRequest) (*models.BatchResult, error)
+	DeleteEntities(ctx context.Context, req *models.BatchDeleteRequest) (*models.BatchResult, error)
 }
 
 // entityService implements EntityService interface
 type entityService struct {
 	repo repository.EntityRepository
+	uow  repository.UnitOfWork
 }
 
-// NewEntityService creates a new entity service
-func NewEntityService(repo repository.EntityRepository) EntityService {
+// NewEntityService creates a new entity service.
+// Operations that read an entity before writing it run in a unit of work from uow.
+func NewEntityService(repo repository.EntityRepository, uow repository.UnitOfWork) EntityService {
 	return &entityService{
 		repo: repo,
+		uow:  uow,
 	}
 }
 
 // CreateEntity creates a new entity
-func (s *entityService) CreateEntity(req *models.EntityRequest) (*models.Entity, error) {
-	entity := &models.Entity{
-		Name: req.Name,
+func (s *entityService) CreateEntity(ctx context.Context, req *models.EntityRequest) (*models.Entity, error) {
+	return createEntity(ctx, s.repo, req)
+}
+
+// GetEntityByID retrieves an entity by its ID
+func (s *entityService) GetEntityByID(ctx context.Context, id int) (*models.Entity, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// GetAllEntities retrieves a page of entities
+func (s *entityService) GetAllEntities(ctx context.Context, params models.ListParams) (*models.EntityPage, error) {
+	return s.repo.GetAll(ctx, params)
+}
+
+// SearchEntities finds entities by name ranked by relevance
+func (s *entityService) SearchEntities(ctx context.Context, query string, limit int) ([]*models.EntitySearchResult, error) {
+	return s.repo.Search(ctx, strings.TrimSpace(query), limit)
+}
+
+// UpdateEntity updates an existing entity.
+// A non-zero version makes the update conditional on the entity still being at that version.
+func (s *entityService) UpdateEntity(ctx context.Context, id int, req *models.EntityRequest, version int) (*models.Entity, error) {
+	var entity *models.Entity
+	err := s.uow.Do(ctx, func(repo repository.EntityRepository) error {
+		var err error
+		entity, err = updateEntity(ctx, repo, id, req, version)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	err := s.repo.Create(entity)
+	return entity, nil
+}
+
+// PatchEntity applies a JSON Patch or JSON Merge Patch to an entity and persists
+// only the columns that changed. A non-zero version makes the patch conditional,
+// as in UpdateEntity.
+func (s *entityService) PatchEntity(ctx context.Context, id int, patch *models.EntityPatch, version int) (*models.Entity, error) {
+	var entity *models.Entity
+	err := s.uow.Do(ctx, func(repo repository.EntityRepository) error {
+		var err error
+		entity, err = patchEntity(ctx, repo, id, patch, version)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -41,32 +95,93 @@ func (s *entityService) CreateEntity(req *models.EntityRequest) (*models.Entity,
 	return entity, nil
 }
 
-// GetEntityByID retrieves an entity by its ID
-func (s *entityService) GetEntityByID(id int) (*models.Entity, error) {
-	return s.repo.GetByID(id)
+// DeleteEntity soft-deletes an entity by its ID, moving it to the trash.
+// A non-zero version makes the delete conditional, as in UpdateEntity.
+func (s *entityService) DeleteEntity(ctx context.Context, id int, version int) error {
+	return s.uow.Do(ctx, func(repo repository.EntityRepository) error {
+		return deleteEntity(ctx, repo, id, version)
+	})
+}
+
+// GetDeletedEntities retrieves a page of soft-deleted entities
+func (s *entityService) GetDeletedEntities(ctx context.Context, params models.ListParams) (*models.EntityPage, error) {
+	return s.repo.GetDeleted(ctx, params)
+}
+
+// RestoreEntity restores a soft-deleted entity and returns it
+func (s *entityService) RestoreEntity(ctx context.Context, id int) (*models.Entity, error) {
+	var entity *models.Entity
+	err := s.uow.Do(ctx, func(repo repository.EntityRepository) error {
+		err := repo.Restore(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		entity, err = repo.GetByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+// PurgeEntity permanently removes a soft-deleted entity
+func (s *entityService) PurgeEntity(ctx context.Context, id int) error {
+	if err := s.repo.Purge(ctx, id); err != nil {
+		return err
+	}
+
+	// Purging cannot be undone, so it is kept in the logs
+	slog.InfoContext(ctx, "Entity purged", "entity_id", id)
+	return nil
 }
 
-// GetAllEntities retrieves all entities
-func (s *entityService) GetAllEntities() ([]*models.Entity, error) {
-	return s.repo.GetAll()
+// createEntity creates an entity using repo
+func createEntity(ctx context.Context, repo repository.EntityRepository, req *models.EntityRequest) (*models.Entity, error) {
+	entity := &models.Entity{
+		Name: req.Name,
+	}
+
+	err := repo.Create(ctx, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	return entity, nil
 }
 
-// UpdateEntity updates an existing entity
-func (s *entityService) UpdateEntity(id int, req *models.EntityRequest) (*models.Entity, error) {
-	// First, check if entity exists
-	entity, err := s.repo.GetByID(id)
+// lockEntity reads and locks a live entity, checking it against the version
+// the caller expects when one is given
+func lockEntity(ctx context.Context, repo repository.EntityRepository, id int, version int) (*models.Entity, error) {
+	entity, err := repo.GetByIDForUpdate(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	if entity == nil {
-		return nil, errors.ErrEntityNotFound
+		return nil, errors.New(errors.CodeEntityNotFound)
+	}
+
+	if version != 0 && entity.Version != version {
+		return nil, errors.New(errors.CodePreconditionFailed)
+	}
+
+	return entity, nil
+}
+
+// updateEntity updates an entity using repo, which must be bound to a unit of work
+func updateEntity(ctx context.Context, repo repository.EntityRepository, id int, req *models.EntityRequest, version int) (*models.Entity, error) {
+	entity, err := lockEntity(ctx, repo, id, version)
+	if err != nil {
+		return nil, err
 	}
 
 	// Update entity fields
 	entity.Name = req.Name
 
-	err = s.repo.Update(id, entity)
+	err = repo.Update(ctx, id, entity)
 	if err != nil {
 		return nil, err
 	}
@@ -74,17 +189,31 @@ func (s *entityService) UpdateEntity(id int, req *models.EntityRequest) (*models
 	return entity, nil
 }
 
-// DeleteEntity deletes an entity by its ID
-func (s *entityService) DeleteEntity(id int) error {
-	// First, check if entity exists
-	entity, err := s.repo.GetByID(id)
+// patchEntity patches an entity using repo, which must be bound to a unit of work
+func patchEntity(ctx context.Context, repo repository.EntityRepository, id int, patch *models.EntityPatch, version int) (*models.Entity, error) {
+	entity, err := lockEntity(ctx, repo, id, version)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	
-	if entity == nil {
-		return errors.ErrEntityNotFound
+
+	changes, err := applyPatch(entity, patch)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.repo.Delete(id)
-}
\ No newline at end of file
+	if len(changes) == 0 {
+		return entity, nil
+	}
+
+	return repo.UpdateFields(ctx, id, changes, entity.Version)
+}
+
+// deleteEntity soft-deletes an entity using repo, which must be bound to a unit of work
+func deleteEntity(ctx context.Context, repo repository.EntityRepository, id int, version int) error {
+	entity, err := lockEntity(ctx, repo, id, version)
+	if err != nil {
+		return err
+	}
+
+	return repo.Delete(ctx, id, entity.Version)
+}
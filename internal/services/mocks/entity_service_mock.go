@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"context"
+
 	"learn-api/internal/models"
 
 	"github.com/stretchr/testify/mock"
@@ -12,8 +14,8 @@ type EntityServiceMock struct {
 }
 
 // CreateEntity mocks the CreateEntity method
-func (m *EntityServiceMock) CreateEntity(req *models.EntityRequest) (*models.Entity, error) {
-	args := m.Called(req)
+func (m *EntityServiceMock) CreateEntity(ctx context.Context, req *models.EntityRequest) (*models.Entity, error) {
+	args := m.Called(ctx, req)
 	entity, ok := args.Get(0).(*models.Entity)
 	if ok {
 		return entity, args.Error(1)
@@ -22,8 +24,8 @@ func (m *EntityServiceMock) CreateEntity(req *models.EntityRequest) (*models.Ent
 }
 
 // GetEntityByID mocks the GetEntityByID method
-func (m *EntityServiceMock) GetEntityByID(id int) (*models.Entity, error) {
-	args := m.Called(id)
+func (m *EntityServiceMock) GetEntityByID(ctx context.Context, id int) (*models.Entity, error) {
+	args := m.Called(ctx, id)
 	entity, ok := args.Get(0).(*models.Entity)
 	if ok {
 		return entity, args.Error(1)
@@ -32,18 +34,38 @@ func (m *EntityServiceMock) GetEntityByID(id int) (*models.Entity, error) {
 }
 
 // GetAllEntities mocks the GetAllEntities method
-func (m *EntityServiceMock) GetAllEntities() ([]*models.Entity, error) {
-	args := m.Called()
-	entities, ok := args.Get(0).([]*models.Entity)
+func (m *EntityServiceMock) GetAllEntities(ctx context.Context, params models.ListParams) (*models.EntityPage, error) {
+	args := m.Called(ctx, params)
+	page, ok := args.Get(0).(*models.EntityPage)
+	if ok {
+		return page, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// SearchEntities mocks the SearchEntities method
+func (m *EntityServiceMock) SearchEntities(ctx context.Context, query string, limit int) ([]*models.EntitySearchResult, error) {
+	args := m.Called(ctx, query, limit)
+	results, ok := args.Get(0).([]*models.EntitySearchResult)
 	if ok {
-		return entities, args.Error(1)
+		return results, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
 // UpdateEntity mocks the UpdateEntity method
-func (m *EntityServiceMock) UpdateEntity(id int, req *models.EntityRequest) (*models.Entity, error) {
-	args := m.Called(id, req)
+func (m *EntityServiceMock) UpdateEntity(ctx context.Context, id int, req *models.EntityRequest, version int) (*models.Entity, error) {
+	args := m.Called(ctx, id, req, version)
+	entity, ok := args.Get(0).(*models.Entity)
+	if ok {
+		return entity, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// PatchEntity mocks the PatchEntity method
+func (m *EntityServiceMock) PatchEntity(ctx context.Context, id int, patch *models.EntityPatch, version int) (*models.Entity, error) {
+	args := m.Called(ctx, id, patch, version)
 	entity, ok := args.Get(0).(*models.Entity)
 	if ok {
 		return entity, args.Error(1)
@@ -52,11 +74,67 @@ func (m *EntityServiceMock) UpdateEntity(id int, req *models.EntityRequest) (*mo
 }
 
 // DeleteEntity mocks the DeleteEntity method
-func (m *EntityServiceMock) DeleteEntity(id int) error {
-	args := m.Called(id)
+func (m *EntityServiceMock) DeleteEntity(ctx context.Context, id int, version int) error {
+	args := m.Called(ctx, id, version)
 	return args.Error(0)
 }
 
+// GetDeletedEntities mocks the GetDeletedEntities method
+func (m *EntityServiceMock) GetDeletedEntities(ctx context.Context, params models.ListParams) (*models.EntityPage, error) {
+	args := m.Called(ctx, params)
+	page, ok := args.Get(0).(*models.EntityPage)
+	if ok {
+		return page, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// RestoreEntity mocks the RestoreEntity method
+func (m *EntityServiceMock) RestoreEntity(ctx context.Context, id int) (*models.Entity, error) {
+	args := m.Called(ctx, id)
+	entity, ok := args.Get(0).(*models.Entity)
+	if ok {
+		return entity, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// PurgeEntity mocks the PurgeEntity method
+func (m *EntityServiceMock) PurgeEntity(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// CreateEntities mocks the CreateEntities method
+func (m *EntityServiceMock) CreateEntities(ctx context.Context, req *models.BatchCreateRequest) (*models.BatchResult, error) {
+	args := m.Called(ctx, req)
+	result, ok := args.Get(0).(*models.BatchResult)
+	if ok {
+		return result, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// UpdateEntities mocks the UpdateEntities method
+func (m *EntityServiceMock) UpdateEntities(ctx context.Context, req *models.BatchUpdateRequest) (*models.BatchResult, error) {
+	args := m.Called(ctx, req)
+	result, ok := args.Get(0).(*models.BatchResult)
+	if ok {
+		return result, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// DeleteEntities mocks the DeleteEntities method
+func (m *EntityServiceMock) DeleteEntities(ctx context.Context, req *models.BatchDeleteRequest) (*models.BatchResult, error) {
+	args := m.Called(ctx, req)
+	result, ok := args.Get(0).(*models.BatchResult)
+	if ok {
+		return result, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 // AssertExpectations asserts that everything was in fact called as expected
 func (m *EntityServiceMock) AssertExpectations(t mock.TestingT) bool {
 	return m.Mock.AssertExpectations(t)
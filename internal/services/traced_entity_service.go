@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"learn-api/internal/models"
+	"learn-api/internal/tracing"
+)
+
+// tracer records the spans of the entity service
+var tracer = tracing.Tracer("learn-api/internal/services")
+
+// tracedEntityService wraps every call of an EntityService in a span
+type tracedEntityService struct {
+	service EntityService
+}
+
+// NewTracedEntityService wraps service so that every call is traced as a span
+// named after the method, such as EntityService.GetEntityByID
+func NewTracedEntityService(service EntityService) EntityService {
+	return &tracedEntityService{service: service}
+}
+
+// entityID is the span attribute identifying the entity a call works on
+func entityID(id int) attribute.KeyValue {
+	return attribute.Int("entity.id", id)
+}
+
+// CreateEntity creates a new entity in a span
+func (s *tracedEntityService) CreateEntity(ctx context.Context, req *models.EntityRequest) (_ *models.Entity, err error) {
+	ctx, span := tracer.Start(ctx, "EntityService.CreateEntity")
+	defer func() { tracing.End(span, err) }()
+	return s.service.CreateEntity(ctx, req)
+}
+
+// GetEntityByID retrieves an entity by its ID in a span
+func (s *tracedEntityService) GetEntityByID(ctx context.Context, id int) (_ *models.Entity, err error) {
+	ctx, span := tracer.Start(ctx, "EntityService.GetEntityByID")
+	span.SetAttributes(entityID(id))
+	defer func() { tracing.End(span, err) }()
+	return s.service.GetEntityByID(ctx, id)
+}
+
+// GetAllEntities retrieves a page of entities in a span
+func (s *tracedEntityService) GetAllEntities(ctx context.Context, params models.ListParams) (_ *models.EntityPage, err error) {
+	ctx, span := tracer.Start(ctx, "EntityService.GetAllEntities")
+	defer func() { tracing.End(span, err) }()
+	return s.service.GetAllEntities(ctx, params)
+}
+
+// SearchEntities finds entities by name in a span
+func (s *tracedEntityService) SearchEntities(ctx context.Context, query string, limit int) (_ []*models.EntitySearchResult, err error) {
+	ctx, span := tracer.Start(ctx, "EntityService.SearchEntities")
+	defer func() { tracing.End(span, err) }()
+	return s.service.SearchEntities(ctx, query, limit)
+}
+
+// UpdateEntity updates an existing entity in a span
+func (s *tracedEntityService) UpdateEntity(ctx context.Context, id int, req *models.EntityRequest, version int) (_ *models.Entity, err error) {
+	ctx, span := tracer.Start(ctx, "EntityService.UpdateEntity")
+	span.SetAttributes(entityID(id))
+	defer func() { tracing.End(span, err) }()
+	return s.service.UpdateEntity(ctx, id, req, version)
+}
+
+// PatchEntity applies a patch to an entity in a span
+func (s *tracedEntityService) PatchEntity(ctx context.Context, id int, patch *models.EntityPatch, version int) (_ *models.Entity, err error) {
+	ctx, span := tracer.Start(ctx, "EntityService.PatchEntity")
+	span.SetAttributes(entityID(id))
+	defer func() { tracing.End(span, err) }()
+	return s.service.PatchEntity(ctx, id, patch, version)
+}
+
+// DeleteEntity soft-deletes an entity in a span
+func (s *tracedEntityService) DeleteEntity(ctx context.Context, id int, version int) (err error) {
+	ctx, span := tracer.Start(ctx, "EntityService.DeleteEntity")
+	span.SetAttributes(entityID(id))
+	defer func() { tracing.End(span, err) }()
+	return s.service.DeleteEntity(ctx, id, version)
+}
+
+// GetDeletedEntities retrieves a page of soft-deleted entities in a span
+func (s *tracedEntityService) GetDeletedEntities(ctx context.Context, params models.ListParams) (_ *models.EntityPage, err error) {
+	ctx, span := tracer.Start(ctx, "EntityService.GetDeletedEntities")
+	defer func() { tracing.End(span, err) }()
+	return s.service.GetDeletedEntities(ctx, params)
+}
+
+// RestoreEntity restores a soft-deleted entity in a span
+func (s *tracedEntityService) RestoreEntity(ctx context.Context, id int) (_ *models.Entity, err error) {
+	ctx, span := tracer.Start(ctx, "EntityService.RestoreEntity")
+	span.SetAttributes(entityID(id))
+	defer func() { tracing.End(span, err) }()
+	return s.service.RestoreEntity(ctx, id)
+}
+
+// PurgeEntity permanently removes a soft-deleted entity in a span
+func (s *tracedEntityService) PurgeEntity(ctx context.Context, id int) (err error) {
+	ctx, span := tracer.Start(ctx, "EntityService.PurgeEntity")
+	span.SetAttributes(entityID(id))
+	defer func() { tracing.End(span, err) }()
+	return s.service.PurgeEntity(ctx, id)
+}
+
+// CreateEntities creates a batch of entities in a span
+func (s *tracedEntityService) CreateEntities(ctx context.Context, req *models.BatchCreateRequest) (_ *models.BatchResult, err error) {
+	ctx, span := tracer.Start(ctx, "EntityService.CreateEntities")
+	defer func() { tracing.End(span, err) }()
+	return s.service.CreateEntities(ctx, req)
+}
+
+// UpdateEntities updates a batch of entities in a span
+func (s *tracedEntityService) UpdateEntities(ctx context.Context, req *models.BatchUpdateRequest) (_ *models.BatchResult, err error) {
+	ctx, span := tracer.Start(ctx, "EntityService.UpdateEntities")
+	defer func() { tracing.End(span, err) }()
+	return s.service.UpdateEntities(ctx, req)
+}
+
+// DeleteEntities soft-deletes a batch of entities in a span
+func (s *tracedEntityService) DeleteEntities(ctx context.Context, req *models.BatchDeleteRequest) (_ *models.BatchResult, err error) {
+	ctx, span := tracer.Start(ctx, "EntityService.DeleteEntities")
+	defer func() { tracing.End(span, err) }()
+	return s.service.DeleteEntities(ctx, req)
+}
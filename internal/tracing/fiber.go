@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"learn-api/internal/httpx"
+)
+
+// httpTracerName names the tracer of the HTTP spans
+const httpTracerName = "learn-api/internal/app"
+
+// headerCarrier reads and writes trace context in the request headers
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+// Get returns a header value; it is copied because Fiber reuses the memory behind it
+func (h headerCarrier) Get(key string) string {
+	return strings.Clone(h.c.Get(key))
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0)
+	for key := range h.c.GetReqHeaders() {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Middleware starts a server span for every request, continuing the caller's
+// trace when the request carries a W3C traceparent header. The span is named
+// after the method and route template, and the request's user context carries it
+// to the handlers, services and repository.
+func Middleware() fiber.Handler {
+	tracer := Tracer(httpTracerName)
+	return func(c *fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+
+		method := strings.Clone(c.Method())
+		ctx, span := tracer.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(strings.Clone(c.Path())),
+				semconv.UserAgentOriginal(strings.Clone(c.Get(fiber.HeaderUserAgent))),
+			),
+		)
+		defer span.End()
+
+		own := c.Route()
+		c.SetUserContext(ctx)
+		err := c.Next()
+
+		status := httpx.ResponseStatus(c, err)
+		if err != nil {
+			span.RecordError(err)
+		}
+
+		if route, ok := httpx.MatchedRoute(c, own); ok {
+			span.SetName(method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		return err
+	}
+}
+
+// Handler wraps a Fiber handler in a span called name, such as
+// EntityHandler.GetEntityByID
+func Handler(name string, handler fiber.Handler) fiber.Handler {
+	tracer := Tracer("learn-api/internal/handlers")
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracer.Start(c.UserContext(), name)
+		defer span.End()
+
+		c.SetUserContext(ctx)
+		err := handler(c)
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else if status := c.Response().StatusCode(); status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		return err
+	}
+}
@@ -0,0 +1,129 @@
+// Package tracing sets up OpenTelemetry tracing: W3C trace-context propagation,
+// an OTLP exporter and helpers to trace the layers of a request.
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"learn-api/pkg/errors"
+)
+
+// Exporters
+const (
+	// ExporterNone keeps propagating trace context but records no spans
+	ExporterNone = "none"
+	// ExporterOTLP sends spans to an OpenTelemetry collector over OTLP/HTTP
+	ExporterOTLP = "otlp"
+)
+
+// Config describes where spans go
+type Config struct {
+	// Exporter is none or otlp
+	Exporter string
+	// Endpoint is the collector's OTLP/HTTP URL; an http:// URL disables TLS
+	Endpoint string
+	// ServiceName identifies the service in the traces
+	ServiceName string
+	// SampleRatio is the fraction of new traces recorded, from 0 to 1. Requests
+	// continuing a trace follow the caller's sampling decision.
+	SampleRatio float64
+}
+
+// DefaultConfig returns the configuration used for settings that are not provided
+func DefaultConfig() Config {
+	return Config{
+		Exporter:    ExporterNone,
+		Endpoint:    "http://localhost:4318",
+		ServiceName: "learn-api",
+		SampleRatio: 1,
+	}
+}
+
+// Validate reports settings that can never work
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case ExporterNone:
+		return nil
+	case ExporterOTLP:
+	default:
+		return fmt.Errorf("unsupported tracing exporter %q; use %s or %s", c.Exporter, ExporterNone, ExporterOTLP)
+	}
+
+	if u, err := url.Parse(c.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid tracing endpoint %q: expected an http:// or https:// URL", c.Endpoint)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+// Setup installs the W3C trace-context and baggage propagators and, unless the
+// exporter is none, a tracer provider exporting spans as cfg describes. The
+// returned function flushes pending spans and stops the exporter.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("creating trace exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("describing trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of an instrumented package from the global provider,
+// so spans follow whatever Setup installed
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// End ends span, recording err. Only failures mark the span as an error: a
+// missing entity or an invalid request is an expected outcome.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+
+		var apiErr *errors.APIError
+		expected := stderrors.Is(err, sql.ErrNoRows) || (stderrors.As(err, &apiErr) && apiErr.Status() < 500)
+		if !expected {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,207 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+//go:generate go run ../../cmd/errcatalog -o ../../docs/errors.md
+
+// Code identifies a kind of API error for programs. Clients program against
+// codes, so a code is never renamed, reused or given another status.
+type Code string
+
+// Error codes; docs/errors.md documents each one and is generated from the catalog
+const (
+	CodeInvalidRequest       Code = "INVALID_REQUEST"
+	CodeValidationFailed     Code = "VALIDATION_FAILED"
+	CodeInvalidCursor        Code = "INVALID_CURSOR"
+	CodeInvalidPatch         Code = "INVALID_PATCH"
+	CodeEntityNotFound       Code = "ENTITY_NOT_FOUND"
+	CodeConflict             Code = "CONFLICT"
+	CodePatchConflict        Code = "PATCH_CONFLICT"
+	CodePreconditionFailed   Code = "PRECONDITION_FAILED"
+	CodeUnsupportedMediaType Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodeConstraintViolation  Code = "CONSTRAINT_VIOLATION"
+	CodeBatchAborted         Code = "BATCH_ABORTED"
+	CodeRequestCanceled      Code = "REQUEST_CANCELED"
+	CodeDatabaseError        Code = "DATABASE_ERROR"
+	CodeUnavailable          Code = "UNAVAILABLE"
+	CodeTimeout              Code = "TIMEOUT"
+)
+
+// Definition describes a kind of API error in the catalog
+type Definition struct {
+	Code Code
+	// Status is the HTTP status code
+	Status int
+	// Title summarizes the kind of error; it is the message of the error envelope
+	Title string
+	// Detail explains the error to the client unless a more specific detail is given
+	Detail string
+	// Description tells API users when the error occurs and what to do about it
+	Description string
+	// RetryAfter is the number of seconds after which the request may be retried,
+	// zero when retrying will not help
+	RetryAfter int
+}
+
+// catalog lists every kind of API error, in the order they are documented
+var catalog = []Definition{
+	{
+		Code:        CodeInvalidRequest,
+		Status:      http.StatusBadRequest,
+		Title:       "Invalid request",
+		Detail:      "The request body is invalid or missing required fields",
+		Description: "The request could not be read, such as a body that is not JSON, or an ID or value the database cannot read as its type, such as a number out of range.",
+	},
+	{
+		Code:        CodeValidationFailed,
+		Status:      http.StatusBadRequest,
+		Title:       "Validation failed",
+		Detail:      "The request data failed validation",
+		Description: "The request was read but some fields are invalid. Problem details list every field in `errors`.",
+	},
+	{
+		Code:        CodeInvalidCursor,
+		Status:      http.StatusBadRequest,
+		Title:       "Invalid cursor",
+		Detail:      "The pagination cursor is malformed or has expired",
+		Description: "A pagination cursor was altered, or belongs to a listing with another sort order. Start again from the first page.",
+	},
+	{
+		Code:        CodeInvalidPatch,
+		Status:      http.StatusBadRequest,
+		Title:       "Invalid patch",
+		Detail:      "The patch document is not valid JSON Patch or JSON Merge Patch",
+		Description: "The body of a PATCH request does not follow its Content-Type.",
+	},
+	{
+		Code:        CodeEntityNotFound,
+		Status:      http.StatusNotFound,
+		Title:       "Entity not found",
+		Detail:      "The requested entity could not be found",
+		Description: "No entity has the ID, or it is in the trash. Deleted entities are listed under `/entities/trash`.",
+	},
+	{
+		Code:        CodeConflict,
+		Status:      http.StatusConflict,
+		Title:       "Conflict",
+		Detail:      "The entity conflicts with an existing one",
+		Description: "The database rejected the entity because another one already holds a unique value.",
+	},
+	{
+		Code:        CodePatchConflict,
+		Status:      http.StatusConflict,
+		Title:       "Patch conflict",
+		Detail:      "The patch could not be applied to the current state of the entity",
+		Description: "A JSON Patch test operation failed or a path does not exist. Fetch the entity and build the patch again.",
+	},
+	{
+		Code:        CodePreconditionFailed,
+		Status:      http.StatusPreconditionFailed,
+		Title:       "Precondition failed",
+		Detail:      "The entity has been modified since it was last retrieved",
+		Description: "The If-Match ETag or version is stale. Fetch the entity again and reapply the change.",
+	},
+	{
+		Code:        CodeUnsupportedMediaType,
+		Status:      http.StatusUnsupportedMediaType,
+		Title:       "Unsupported media type",
+		Detail:      "PATCH requires application/merge-patch+json or application/json-patch+json",
+		Description: "A PATCH request was sent with another Content-Type.",
+	},
+	{
+		Code:        CodeConstraintViolation,
+		Status:      http.StatusUnprocessableEntity,
+		Title:       "Constraint violation",
+		Detail:      "The entity breaks a rule of the database, such as a required or checked value",
+		Description: "The database rejected a value as missing, too long or referring to nothing.",
+	},
+	{
+		Code:        CodeBatchAborted,
+		Status:      http.StatusFailedDependency,
+		Title:       "Batch aborted",
+		Detail:      "The item was not applied because another item in the atomic batch failed",
+		Description: "Reported for the items of an atomic batch that were rolled back because of another item's error.",
+	},
+	{
+		Code:        CodeRequestCanceled,
+		Status:      StatusClientClosedRequest,
+		Title:       "Request canceled",
+		Detail:      "The client closed the connection before the request completed",
+		Description: "Only seen in logs and metrics: the client went away, so nothing received the response.",
+	},
+	{
+		Code:        CodeDatabaseError,
+		Status:      http.StatusInternalServerError,
+		Title:       "Database error",
+		Detail:      "An error occurred while accessing the database",
+		Description: "An unexpected failure. Quote the `request_id` when reporting it.",
+	},
+	{
+		Code:        CodeUnavailable,
+		Status:      http.StatusServiceUnavailable,
+		Title:       "Service unavailable",
+		Detail:      "The database could not complete the request right now; retry it",
+		Description: "A concurrent transaction, deadlock, lock timeout or lost connection stopped the request. Retry after the Retry-After header.",
+		RetryAfter:  1,
+	},
+	{
+		Code:        CodeTimeout,
+		Status:      http.StatusGatewayTimeout,
+		Title:       "Request timed out",
+		Detail:      "The request could not be completed within the allowed time",
+		Description: "The request took longer than the server's request or statement timeout.",
+	},
+}
+
+// Lookup returns the definition of code, and whether the catalog has it
+func Lookup(code Code) (Definition, bool) {
+	for _, def := range catalog {
+		if def.Code == code {
+			return def, true
+		}
+	}
+	return Definition{}, false
+}
+
+// Catalog returns the definition of every kind of API error
+func Catalog() []Definition {
+	return append([]Definition(nil), catalog...)
+}
+
+// problemType returns the problem details type URI of code, such as
+// /problems/entity-not-found
+func problemType(code Code) string {
+	return problemTypePrefix + strings.ReplaceAll(strings.ToLower(string(code)), "_", "-")
+}
+
+// WriteCatalog writes the catalog as the Markdown page docs/errors.md
+func WriteCatalog(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("<!-- Code generated by cmd/errcatalog from pkg/errors/catalog.go. DO NOT EDIT. -->\n\n")
+	b.WriteString("# Error Catalog\n\n")
+	b.WriteString("Every kind of error has a stable code, reported as `code` in problem details\n")
+	b.WriteString("(`Accept: application/problem+json`). The error envelope keeps its original shape:\n")
+	b.WriteString("its `code` is the HTTP status and its `message` the title.\n\n")
+	b.WriteString("| Code | Status | Title | Type |\n")
+	b.WriteString("|------|--------|-------|------|\n")
+	for _, def := range catalog {
+		fmt.Fprintf(&b, "| [`%s`](#%s) | %d | %s | `%s` |\n",
+			def.Code, strings.ToLower(string(def.Code)), def.Status, def.Title, problemType(def.Code))
+	}
+	for _, def := range catalog {
+		fmt.Fprintf(&b, "\n## %s\n\n", def.Code)
+		fmt.Fprintf(&b, "%d %s. %s\n\n", def.Status, def.Title, def.Description)
+		fmt.Fprintf(&b, "Detail: %s.\n", def.Detail)
+		if def.RetryAfter > 0 {
+			fmt.Fprintf(&b, "\nSent with `Retry-After: %d`.\n", def.RetryAfter)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -1,67 +1,240 @@
+// Package errors defines the errors the API reports to its clients. Every error
+// has a stable code from the catalog, by which Is recognizes it; the errors are
+// immutable, and they can wrap the error they were made from.
 package errors
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
-	"net/http"
+	"fmt"
+	"strings"
 )
 
-// APIError represents a structured API error
+// StatusClientClosedRequest is the non-standard status reported when the
+// client goes away before its request completes
+const StatusClientClosedRequest = 499
+
+// APIError is an error reported to a client. It is immutable: the With methods
+// return copies, so an error can be shared once made.
 type APIError struct {
-	Code    int    `json:"code"`
+	def       Definition
+	details   string
+	requestID string
+	fields    []FieldError
+	cause     error
+}
+
+// FieldError reports why one field of a request is invalid
+type FieldError struct {
+	Field   string `json:"field"`
 	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	// Code identifies the message for programs and translations, such as name_required
+	Code string `json:"code,omitempty"`
+	// Params fill the placeholders of the message when it is translated
+	Params map[string]string `json:"-"`
+}
+
+// New returns the API error of code with its catalog detail. It panics if the
+// catalog has no such code, so a mistyped code fails when the package loads.
+func New(code Code) *APIError {
+	def, ok := Lookup(code)
+	if !ok {
+		panic(fmt.Sprintf("errors: code %q is not in the catalog", code))
+	}
+	return &APIError{def: def, details: def.Detail}
+}
+
+// Wrap returns the API error of code made from cause, which errors.Is and
+// errors.As find and logs report; the client never sees it
+func Wrap(code Code, cause error) *APIError {
+	e := New(code)
+	e.cause = cause
+	return e
+}
+
+// Code returns the catalog code of e, such as ENTITY_NOT_FOUND
+func (e *APIError) Code() Code {
+	return e.def.Code
+}
+
+// Status returns the HTTP status code of e
+func (e *APIError) Status() int {
+	return e.def.Status
+}
+
+// Message returns the title of e's kind of error
+func (e *APIError) Message() string {
+	return e.def.Title
+}
+
+// Details explains this occurrence of the error
+func (e *APIError) Details() string {
+	return e.details
+}
+
+// RequestID returns the ID of the request that failed, if known
+func (e *APIError) RequestID() string {
+	return e.requestID
+}
+
+// FieldErrors returns the invalid fields of a validation error
+func (e *APIError) FieldErrors() []FieldError {
+	return append([]FieldError(nil), e.fields...)
+}
+
+// RetryAfter returns the number of seconds after which the request may be
+// retried, or zero when retrying will not help
+func (e *APIError) RetryAfter() int {
+	return e.def.RetryAfter
 }
 
 // Error implements the error interface
 func (e *APIError) Error() string {
-	return e.Message
+	if e.cause != nil {
+		return e.def.Title + ": " + e.cause.Error()
+	}
+	return e.def.Title
 }
 
-// Common API errors
-var (
-	ErrEntityNotFound = &APIError{
-		Code:    http.StatusNotFound,
-		Message: "Entity not found",
-		Details: "The requested entity could not be found",
-	}
+// Unwrap returns the error e was made from, if any
+func (e *APIError) Unwrap() error {
+	return e.cause
+}
 
-	ErrInvalidRequest = &APIError{
-		Code:    http.StatusBadRequest,
-		Message: "Invalid request",
-		Details: "The request body is invalid or missing required fields",
-	}
+// Is reports whether target is an API error with the same code, so that
+// errors.Is holds between any two errors of a code
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.def.Code == e.def.Code
+}
 
-	ErrDatabase = &APIError{
-		Code:    http.StatusInternalServerError,
-		Message: "Database error",
-		Details: "An error occurred while accessing the database",
-	}
+// Is reports whether err, or any error it wraps, is an API error of code
+func Is(err error, code Code) bool {
+	return errors.Is(err, &APIError{def: Definition{Code: code}})
+}
 
-	ErrValidation = &APIError{
-		Code:    http.StatusBadRequest,
-		Message: "Validation error",
-		Details: "The request data failed validation",
-	}
-)
+// WithDetails returns a copy of e explaining this occurrence by details
+func (e *APIError) WithDetails(details string) *APIError {
+	copied := *e
+	copied.details = details
+	return &copied
+}
+
+// WithRequestID returns a copy of e reporting the request ID id
+func (e *APIError) WithRequestID(id string) *APIError {
+	copied := *e
+	copied.requestID = id
+	return &copied
+}
 
-// HandleError converts errors to appropriate HTTP responses
+// WithFieldErrors returns a copy of e reporting the invalid fields
+func (e *APIError) WithFieldErrors(fields ...FieldError) *APIError {
+	copied := *e
+	copied.fields = append([]FieldError(nil), fields...)
+	return &copied
+}
+
+// MarshalJSON writes e in the error envelope's shape, with the HTTP status as code
+func (e *APIError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code      int    `json:"code"`
+		Message   string `json:"message"`
+		Details   string `json:"details,omitempty"`
+		RequestID string `json:"request_id,omitempty"`
+	}{
+		Code:      e.def.Status,
+		Message:   e.def.Title,
+		Details:   e.details,
+		RequestID: e.requestID,
+	})
+}
+
+// HandleError converts errors to appropriate HTTP responses. Errors reported by
+// the database are mapped by their SQLSTATE code and kept as the cause.
 func HandleError(err error) *APIError {
-	// Check if it's already an APIError
-	if apiErr, ok := err.(*APIError); ok {
+	// Check if it's already an APIError, possibly wrapped
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
 		return apiErr
 	}
 
 	// Handle standard errors
 	if errors.Is(err, sql.ErrNoRows) {
-		return ErrEntityNotFound
+		return New(CodeEntityNotFound)
 	}
 
-	// Handle validation errors
+	// Handle deadlines and cancellation
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Wrap(CodeTimeout, err)
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return Wrap(CodeRequestCanceled, err)
+	}
+
+	// Handle validation errors, keeping the field they are about
 	if _, ok := err.(interface{ Validation() bool }); ok {
-		return ErrValidation
+		apiErr := Wrap(CodeValidationFailed, err)
+		if f, ok := err.(interface{ FieldError() FieldError }); ok {
+			field := f.FieldError()
+			return apiErr.WithDetails(field.Field + ": " + field.Message).WithFieldErrors(field)
+		}
+		return apiErr
+	}
+
+	// Handle errors reported by PostgreSQL, such as *pq.Error
+	if state, ok := SQLState(err); ok {
+		return Wrap(fromSQLState(state), err)
 	}
 
 	// Default to internal server error
-	return ErrDatabase
-}
\ No newline at end of file
+	return Wrap(CodeDatabaseError, err)
+}
+
+// SQLState returns the SQLSTATE code of an error reported by PostgreSQL, such
+// as *pq.Error, and whether err is one
+func SQLState(err error) (string, bool) {
+	var sqlErr interface{ SQLState() string }
+	if !errors.As(err, &sqlErr) {
+		return "", false
+	}
+	return sqlErr.SQLState(), true
+}
+
+// fromSQLState maps a PostgreSQL SQLSTATE code to an API error code, see
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+func fromSQLState(state string) Code {
+	switch state {
+	case "23505", // unique_violation
+		"23P01": // exclusion_violation
+		return CodeConflict
+	case "23502", // not_null_violation
+		"23503", // foreign_key_violation
+		"23514": // check_violation
+		return CodeConstraintViolation
+	case "40001", // serialization_failure
+		"40P01", // deadlock_detected
+		"55P03", // lock_not_available
+		"53300", // too_many_connections
+		"57P01", // admin_shutdown
+		"57P02", // crash_shutdown
+		"57P03": // cannot_connect_now
+		return CodeUnavailable
+	case "57014": // query_canceled, by statement_timeout
+		return CodeTimeout
+	case "22P02", // invalid_text_representation
+		"22003", // numeric_value_out_of_range
+		"22007": // invalid_datetime_format
+		return CodeInvalidRequest
+	}
+
+	switch {
+	case strings.HasPrefix(state, "22"): // data_exception, such as a value too long
+		return CodeConstraintViolation
+	case strings.HasPrefix(state, "08"): // connection_exception
+		return CodeUnavailable
+	}
+	return CodeDatabaseError
+}
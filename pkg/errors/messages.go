@@ -0,0 +1,94 @@
+package errors
+
+import "learn-api/pkg/i18n"
+
+// translation is the title and detail of a kind of error in another language
+type translation struct {
+	Title  string
+	Detail string
+}
+
+// translations holds the title and detail of every kind of error in the
+// languages other than English, whose text is the catalog's own
+var translations = map[i18n.Language]map[Code]translation{
+	i18n.Thai: {
+		CodeInvalidRequest: {
+			Title:  "คำขอไม่ถูกต้อง",
+			Detail: "เนื้อหาของคำขอไม่ถูกต้องหรือขาดฟิลด์ที่จำเป็น",
+		},
+		CodeValidationFailed: {
+			Title:  "ข้อมูลไม่ผ่านการตรวจสอบ",
+			Detail: "ข้อมูลในคำขอไม่ผ่านการตรวจสอบ",
+		},
+		CodeInvalidCursor: {
+			Title:  "cursor ไม่ถูกต้อง",
+			Detail: "cursor สำหรับแบ่งหน้ามีรูปแบบไม่ถูกต้องหรือหมดอายุแล้ว",
+		},
+		CodeInvalidPatch: {
+			Title:  "patch ไม่ถูกต้อง",
+			Detail: "เอกสาร patch ไม่ใช่ JSON Patch หรือ JSON Merge Patch ที่ถูกต้อง",
+		},
+		CodeEntityNotFound: {
+			Title:  "ไม่พบเอนทิตี",
+			Detail: "ไม่พบเอนทิตีที่ร้องขอ",
+		},
+		CodeConflict: {
+			Title:  "ข้อมูลขัดแย้ง",
+			Detail: "เอนทิตีขัดแย้งกับเอนทิตีที่มีอยู่แล้ว",
+		},
+		CodePatchConflict: {
+			Title:  "patch ขัดแย้ง",
+			Detail: "ไม่สามารถนำ patch ไปใช้กับสถานะปัจจุบันของเอนทิตีได้",
+		},
+		CodePreconditionFailed: {
+			Title:  "เงื่อนไขล่วงหน้าไม่ผ่าน",
+			Detail: "เอนทิตีถูกแก้ไขไปแล้วหลังจากที่ดึงข้อมูลครั้งล่าสุด",
+		},
+		CodeUnsupportedMediaType: {
+			Title:  "ไม่รองรับชนิดข้อมูลนี้",
+			Detail: "PATCH ต้องใช้ application/merge-patch+json หรือ application/json-patch+json",
+		},
+		CodeConstraintViolation: {
+			Title:  "ละเมิดข้อจำกัดของข้อมูล",
+			Detail: "เอนทิตีละเมิดกฎของฐานข้อมูล เช่น ค่าที่จำเป็นหรือค่าที่ถูกตรวจสอบ",
+		},
+		CodeBatchAborted: {
+			Title:  "ยกเลิกชุดคำสั่ง",
+			Detail: "รายการนี้ไม่ถูกนำไปใช้เพราะรายการอื่นในชุดคำสั่งแบบ atomic ล้มเหลว",
+		},
+		CodeRequestCanceled: {
+			Title:  "คำขอถูกยกเลิก",
+			Detail: "ไคลเอนต์ปิดการเชื่อมต่อก่อนที่คำขอจะเสร็จสิ้น",
+		},
+		CodeDatabaseError: {
+			Title:  "ฐานข้อมูลผิดพลาด",
+			Detail: "เกิดข้อผิดพลาดขณะเข้าถึงฐานข้อมูล",
+		},
+		CodeUnavailable: {
+			Title:  "บริการไม่พร้อมใช้งาน",
+			Detail: "ฐานข้อมูลไม่สามารถดำเนินการตามคำขอได้ในขณะนี้ โปรดลองใหม่อีกครั้ง",
+		},
+		CodeTimeout: {
+			Title:  "คำขอหมดเวลา",
+			Detail: "ไม่สามารถดำเนินการตามคำขอให้เสร็จภายในเวลาที่กำหนด",
+		},
+	},
+}
+
+// Localize returns a copy of e with the title and detail of its kind of error
+// in lang, or e itself when lang has no translation. Details given with
+// WithDetails are left as they are; whoever gave them localizes them.
+func (e *APIError) Localize(lang i18n.Language) *APIError {
+	t, ok := translations[lang][e.def.Code]
+	if !ok {
+		return e
+	}
+
+	copied := *e
+	if e.details == e.def.Detail {
+		copied.details = t.Detail
+	}
+	copied.def.Title = t.Title
+	copied.def.Detail = t.Detail
+	return &copied
+}
@@ -0,0 +1,41 @@
+package errors
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix starts the type URI of every problem; the error code completes it
+const problemTypePrefix = "/problems/"
+
+// Problem is an error response in the RFC 7807 problem details format
+type Problem struct {
+	// Type is a URI reference identifying the kind of problem
+	Type string `json:"type"`
+	// Title is a short summary of the kind of problem
+	Title string `json:"title"`
+	// Status is the HTTP status code
+	Status int `json:"status"`
+	// Detail explains this occurrence of the problem
+	Detail string `json:"detail,omitempty"`
+	// Instance is a URI reference identifying this occurrence, the request path
+	Instance string `json:"instance,omitempty"`
+	// Code identifies the kind of problem for programs, such as ENTITY_NOT_FOUND
+	Code Code `json:"code"`
+	// RequestID identifies the request that failed, for support tickets
+	RequestID string `json:"request_id,omitempty"`
+	// Errors lists the invalid fields of a validation problem
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// Problem describes e as problem details about the request for instance
+func (e *APIError) Problem(instance string) *Problem {
+	return &Problem{
+		Type:      problemType(e.def.Code),
+		Title:     e.def.Title,
+		Status:    e.def.Status,
+		Detail:    e.details,
+		Instance:  instance,
+		Code:      e.def.Code,
+		RequestID: e.requestID,
+		Errors:    e.FieldErrors(),
+	}
+}
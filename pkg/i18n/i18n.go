@@ -0,0 +1,83 @@
+// Package i18n picks the language of the messages the API sends to its clients
+// and formats those messages from catalogs keyed by message code.
+package i18n
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Language is a supported language, named by its ISO 639-1 code
+type Language string
+
+// Supported languages
+const (
+	English Language = "en"
+	Thai    Language = "th"
+)
+
+// Default is the language of messages when the client accepts no supported
+// language, and the one a catalog falls back to when it lacks a message
+const Default = English
+
+// Languages returns the supported languages, the default first
+func Languages() []Language {
+	return []Language{English, Thai}
+}
+
+// Negotiate picks the supported language the client prefers from the value of
+// an Accept-Language header, such as "th-TH,th;q=0.9,en;q=0.8". A tag matches
+// a language by its primary subtag and "*" matches the default; the default is
+// returned when nothing matches.
+func Negotiate(header string) Language {
+	best, bestQ := Default, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= bestQ {
+			continue
+		}
+
+		primary, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+		if primary == "*" {
+			best, bestQ = Default, q
+			continue
+		}
+		for _, lang := range Languages() {
+			if strings.EqualFold(primary, string(lang)) {
+				best, bestQ = lang, q
+				break
+			}
+		}
+	}
+	return best
+}
+
+// Catalog holds the messages of each language keyed by message code. Messages
+// may contain placeholders such as {max}, replaced by the parameter of that name.
+type Catalog map[Language]map[string]string
+
+// Format returns the message code in lang with its placeholders replaced by
+// params, falling back to the default language. It reports false when neither
+// has the message.
+func (c Catalog) Format(lang Language, code string, params map[string]string) (string, bool) {
+	message, ok := c[lang][code]
+	if !ok {
+		message, ok = c[Default][code]
+	}
+	if !ok {
+		return "", false
+	}
+
+	for name, value := range params {
+		message = strings.ReplaceAll(message, "{"+name+"}", value)
+	}
+	return message, true
+}
@@ -0,0 +1,159 @@
+package validation
+
+import (
+	"sync"
+
+	"learn-api/pkg/errors"
+	"learn-api/pkg/i18n"
+)
+
+// Codes of the messages of the rules of validate tags; {label} is the field's
+// name in English, such as "Name", and {field} its JSON path
+const (
+	CodeRequired  = "required"
+	CodeNotEmpty  = "not_empty"
+	CodeMin       = "min"
+	CodeMax       = "max"
+	CodeMinLength = "min_length"
+	CodeMaxLength = "max_length"
+	CodeMinItems  = "min_items"
+	CodeMaxItems  = "max_items"
+	CodePattern   = "pattern"
+)
+
+// Validation message codes, reported as the code of each invalid field
+const (
+	CodeNameNotString             = "name_not_string"
+	CodeLimitNotInteger           = "limit_not_integer"
+	CodeLimitOutOfRange           = "limit_out_of_range"
+	CodeOffsetNotInteger          = "offset_not_integer"
+	CodeOffsetNegative            = "offset_negative"
+	CodeOffsetWithCursor          = "offset_with_cursor"
+	CodeCursorConflict            = "cursor_conflict"
+	CodeFilterMalformed           = "filter_malformed"
+	CodeFilterUnknownField        = "filter_unknown_field"
+	CodeFilterUnsupportedOperator = "filter_unsupported_operator"
+	CodeValueNotInteger           = "value_not_integer"
+	CodeValueNotTimestamp         = "value_not_timestamp"
+	CodeSortUnsupported           = "sort_unsupported"
+	CodeSortDuplicate             = "sort_duplicate"
+	CodeQueryRequired             = "query_required"
+	CodeQueryTooLong              = "query_too_long"
+	CodeFieldUnknown              = "field_unknown"
+	CodeFieldReadOnly             = "field_read_only"
+)
+
+// messages holds the text of every validation message code in each language;
+// RegisterRule adds the messages of custom rules
+var (
+	messages = i18n.Catalog{
+		i18n.English: {
+			CodeRequired:                  "{label} is required",
+			CodeNotEmpty:                  "{label} must not be empty",
+			CodeMin:                       "{label} must be at least {min}",
+			CodeMax:                       "{label} must be at most {max}",
+			CodeMinLength:                 "{label} must be at least {min} characters",
+			CodeMaxLength:                 "{label} must be at most {max} characters",
+			CodeMinItems:                  "{label} must contain at least {min} entries",
+			CodeMaxItems:                  "{label} must contain at most {max} entries",
+			CodePattern:                   "{label} has an invalid format",
+			CodeNameNotString:             "Name must be a string",
+			CodeLimitNotInteger:           "Limit must be an integer",
+			CodeLimitOutOfRange:           "Limit must be between 1 and {max}",
+			CodeOffsetNotInteger:          "Offset must be an integer",
+			CodeOffsetNegative:            "Offset must not be negative",
+			CodeOffsetWithCursor:          "Offset cannot be combined with a cursor",
+			CodeCursorConflict:            "After and before cannot be used together",
+			CodeFilterMalformed:           "Filter must have the form filter[field][operator]",
+			CodeFilterUnknownField:        "Unknown filter field '{field}'",
+			CodeFilterUnsupportedOperator: "Unsupported operator '{operator}' for field '{field}'",
+			CodeValueNotInteger:           "Value must be a 32-bit integer",
+			CodeValueNotTimestamp:         "Value must be an RFC 3339 timestamp",
+			CodeSortUnsupported:           "Cannot sort by '{field}'",
+			CodeSortDuplicate:             "Duplicate sort field '{field}'",
+			CodeQueryRequired:             "Search query is required",
+			CodeQueryTooLong:              "Search query must be at most {max} characters",
+			CodeFieldUnknown:              "Unknown field",
+			CodeFieldReadOnly:             "Field is read-only",
+		},
+		i18n.Thai: {
+			CodeRequired:                  "ต้องระบุ {field}",
+			CodeNotEmpty:                  "{field} ต้องไม่ว่างเปล่า",
+			CodeMin:                       "{field} ต้องมีค่าอย่างน้อย {min}",
+			CodeMax:                       "{field} ต้องมีค่าไม่เกิน {max}",
+			CodeMinLength:                 "{field} ต้องมีความยาวอย่างน้อย {min} ตัวอักษร",
+			CodeMaxLength:                 "{field} ต้องมีความยาวไม่เกิน {max} ตัวอักษร",
+			CodeMinItems:                  "{field} ต้องมีอย่างน้อย {min} รายการ",
+			CodeMaxItems:                  "{field} ต้องมีไม่เกิน {max} รายการ",
+			CodePattern:                   "{field} มีรูปแบบไม่ถูกต้อง",
+			CodeNameNotString:             "ชื่อต้องเป็นข้อความ",
+			CodeLimitNotInteger:           "limit ต้องเป็นจำนวนเต็ม",
+			CodeLimitOutOfRange:           "limit ต้องอยู่ระหว่าง 1 ถึง {max}",
+			CodeOffsetNotInteger:          "offset ต้องเป็นจำนวนเต็ม",
+			CodeOffsetNegative:            "offset ต้องไม่ติดลบ",
+			CodeOffsetWithCursor:          "ไม่สามารถใช้ offset ร่วมกับ cursor ได้",
+			CodeCursorConflict:            "ไม่สามารถใช้ after และ before พร้อมกันได้",
+			CodeFilterMalformed:           "ตัวกรองต้องอยู่ในรูปแบบ filter[field][operator]",
+			CodeFilterUnknownField:        "ไม่รู้จักฟิลด์ตัวกรอง '{field}'",
+			CodeFilterUnsupportedOperator: "ฟิลด์ '{field}' ไม่รองรับตัวดำเนินการ '{operator}'",
+			CodeValueNotInteger:           "ค่าต้องเป็นจำนวนเต็ม 32 บิต",
+			CodeValueNotTimestamp:         "ค่าต้องเป็นเวลาในรูปแบบ RFC 3339",
+			CodeSortUnsupported:           "ไม่สามารถเรียงลำดับตาม '{field}' ได้",
+			CodeSortDuplicate:             "ฟิลด์เรียงลำดับ '{field}' ซ้ำกัน",
+			CodeQueryRequired:             "ต้องระบุคำค้นหา",
+			CodeQueryTooLong:              "คำค้นหาต้องมีความยาวไม่เกิน {max} ตัวอักษร",
+			CodeFieldUnknown:              "ไม่รู้จักฟิลด์นี้",
+			CodeFieldReadOnly:             "ฟิลด์นี้เป็นแบบอ่านอย่างเดียว",
+		},
+	}
+	messagesMu sync.RWMutex
+)
+
+// addMessages adds the messages of code in each language to the catalog
+func addMessages(code string, translations map[i18n.Language]string) {
+	messagesMu.Lock()
+	defer messagesMu.Unlock()
+
+	for lang, message := range translations {
+		if messages[lang] == nil {
+			messages[lang] = map[string]string{}
+		}
+		messages[lang][code] = message
+	}
+}
+
+// format returns the message code in lang, as i18n.Catalog.Format does
+func format(lang i18n.Language, code string, params map[string]string) (string, bool) {
+	messagesMu.RLock()
+	defer messagesMu.RUnlock()
+	return messages.Format(lang, code, params)
+}
+
+// NewError returns the validation error of field with the English message of
+// code, its placeholders filled by params
+func NewError(field, code string, params map[string]string) ValidationError {
+	message, _ := format(i18n.Default, code, params)
+	return ValidationError{
+		Field:   field,
+		Message: message,
+		Code:    code,
+		Params:  params,
+	}
+}
+
+// Localize returns err with its title, details and invalid fields in lang,
+// keeping English for anything lang has no message for
+func Localize(err *errors.APIError, lang i18n.Language) *errors.APIError {
+	localized := err.Localize(lang)
+
+	fields := localized.FieldErrors()
+	if len(fields) == 0 {
+		return localized
+	}
+	for i, field := range fields {
+		if message, ok := format(lang, field.Code, field.Params); ok {
+			fields[i].Message = message
+		}
+	}
+	return localized.WithDetails(fieldDetails(fields)).WithFieldErrors(fields...)
+}
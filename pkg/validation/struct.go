@@ -0,0 +1,353 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"learn-api/pkg/i18n"
+)
+
+// Rule reports whether value satisfies a rule, given the parameter of its tag
+// such as "255" in max=255. Pointers are dereferenced before a rule is checked,
+// and nil pointers are left to required.
+type Rule func(value reflect.Value, param string) bool
+
+var (
+	// customRules holds the rules added with RegisterRule
+	customRules   = map[string]Rule{}
+	customRulesMu sync.RWMutex
+
+	// patterns caches the compiled expressions of pattern rules
+	patterns sync.Map
+)
+
+// builtinRules are the rules Struct knows without registration
+var builtinRules = map[string]bool{
+	"required": true,
+	"min":      true,
+	"max":      true,
+	"trim":     true,
+	"pattern":  true,
+	"dive":     true,
+}
+
+// initialisms are the words of JSON names written in capitals in field labels
+var initialisms = map[string]bool{
+	"id":  true,
+	"ip":  true,
+	"url": true,
+}
+
+// RegisterRule makes the rule name usable in validate tags. Fields breaking it
+// are reported with name as their code and the message of each language in
+// messages, where {field}, {label} and {param} are filled in. It panics if the
+// name is taken, so it is meant to be called from init functions.
+func RegisterRule(name string, rule Rule, messages map[i18n.Language]string) {
+	customRulesMu.Lock()
+	defer customRulesMu.Unlock()
+
+	if _, ok := customRules[name]; ok || builtinRules[name] {
+		panic(fmt.Sprintf("validation: rule %q is already registered", name))
+	}
+	if messages[i18n.Default] == "" {
+		panic(fmt.Sprintf("validation: rule %q has no %s message", name, i18n.Default))
+	}
+	customRules[name] = rule
+	addMessages(name, messages)
+}
+
+// Struct validates the struct v points to against the validate tags of its
+// fields and reports every invalid field by its JSON path, such as
+// items[2].name. Tags list rules separated by commas, checked in order until
+// one fails:
+//
+//	required     the value is not empty: not zero, nil or of length zero
+//	min=n, max=n bound the length of strings, in characters, and of slices and
+//	             maps, and the value of numbers
+//	trim         removes the spaces around a string, in v itself
+//	pattern=re   a non-empty string matches the regular expression re; it must
+//	             be the last rule as re may contain commas
+//	dive         applies the rules after it to every element of a slice
+//
+// Nested structs are validated too, as are the struct elements of slices tagged
+// dive. Structs implementing Validator are asked for their own errors after
+// their fields, for rules across fields. Struct panics on a malformed tag,
+// which is a programming error.
+func Struct(v interface{}) []ValidationError {
+	var errs []ValidationError
+	validateStruct(reflect.ValueOf(v), "", &errs)
+	return errs
+}
+
+// validateStruct validates the fields of the struct value, or the struct it
+// points to, whose JSON path is path
+func validateStruct(value reflect.Value, path string, errs *[]ValidationError) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return
+	}
+
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, tagged := jsonName(field)
+		if name == "-" {
+			continue
+		}
+
+		// Embedded structs share the path of their parent, as in JSON
+		if field.Anonymous && !tagged {
+			validateStruct(value.Field(i), path, errs)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		validateField(value.Field(i), joinPath(path, name), name, splitRules(field.Tag.Get("validate")), errs)
+	}
+
+	// Structs embedded unexported cannot be asked
+	var validator Validator
+	switch {
+	case !value.CanInterface():
+	case value.CanAddr():
+		validator, _ = value.Addr().Interface().(Validator)
+	default:
+		validator, _ = value.Interface().(Validator)
+	}
+	if validator != nil {
+		for _, err := range validator.Validate() {
+			err.Field = joinPath(path, err.Field)
+			*errs = append(*errs, err)
+		}
+	}
+}
+
+// validateField checks value, the field name at path, against rules and then
+// validates it as a nested struct
+func validateField(value reflect.Value, path, name string, rules []string, errs *[]ValidationError) {
+	for i, rule := range rules {
+		rule, param, _ := strings.Cut(rule, "=")
+		switch rule {
+		case "trim":
+			value = trim(value)
+		case "dive":
+			elements := indirect(value)
+			if elements.Kind() != reflect.Slice && elements.Kind() != reflect.Array {
+				panic(fmt.Sprintf("validation: dive on %s, which is not a slice", path))
+			}
+			for j := 0; j < elements.Len(); j++ {
+				validateField(elements.Index(j), path+"["+strconv.Itoa(j)+"]", name, rules[i+1:], errs)
+			}
+			return
+		default:
+			if code, params, ok := check(rule, param, value); !ok {
+				params["field"] = path
+				params["label"] = label(name)
+				*errs = append(*errs, NewError(path, code, params))
+				return
+			}
+		}
+	}
+
+	if indirect(value).Kind() == reflect.Struct {
+		validateStruct(value, path, errs)
+	}
+}
+
+// check reports whether value satisfies rule, or else the code and parameters
+// of its message
+func check(rule, param string, value reflect.Value) (string, map[string]string, bool) {
+	if rule == "required" {
+		if isEmpty(value) {
+			if kind := indirect(value).Kind(); kind == reflect.Slice || kind == reflect.Map || kind == reflect.Array {
+				return CodeNotEmpty, map[string]string{}, false
+			}
+			return CodeRequired, map[string]string{}, false
+		}
+		return "", nil, true
+	}
+
+	value = indirect(value)
+	if !value.IsValid() {
+		return "", nil, true
+	}
+
+	switch rule {
+	case "min", "max":
+		return checkBound(rule, param, value)
+	case "pattern":
+		if value.Kind() != reflect.String {
+			panic(fmt.Sprintf("validation: pattern on a %s", value.Kind()))
+		}
+		if value.String() == "" || pattern(param).MatchString(value.String()) {
+			return "", nil, true
+		}
+		return CodePattern, map[string]string{"pattern": param}, false
+	}
+
+	customRulesMu.RLock()
+	custom, ok := customRules[rule]
+	customRulesMu.RUnlock()
+	if !ok {
+		panic(fmt.Sprintf("validation: unknown rule %q", rule))
+	}
+	if custom(value, param) {
+		return "", nil, true
+	}
+	return rule, map[string]string{"param": param}, false
+}
+
+// checkBound checks the min or max rule against the length of strings, slices
+// and maps, or the value of numbers
+func checkBound(rule, param string, value reflect.Value) (string, map[string]string, bool) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validation: %s=%s is not a number", rule, param))
+	}
+
+	var n float64
+	codes := [2]string{CodeMin, CodeMax}
+	switch value.Kind() {
+	case reflect.String:
+		n = float64(utf8.RuneCountInString(value.String()))
+		codes = [2]string{CodeMinLength, CodeMaxLength}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n = float64(value.Len())
+		codes = [2]string{CodeMinItems, CodeMaxItems}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = value.Float()
+	default:
+		panic(fmt.Sprintf("validation: %s on a %s", rule, value.Kind()))
+	}
+
+	if rule == "min" && n < limit {
+		return codes[0], map[string]string{"min": param}, false
+	}
+	if rule == "max" && n > limit {
+		return codes[1], map[string]string{"max": param}, false
+	}
+	return "", nil, true
+}
+
+// trim removes the spaces around the string value, in place when it can be set,
+// and returns the trimmed value
+func trim(value reflect.Value) reflect.Value {
+	target := indirect(value)
+	if target.Kind() != reflect.String {
+		if target.IsValid() {
+			panic(fmt.Sprintf("validation: trim on a %s", target.Kind()))
+		}
+		return value
+	}
+
+	trimmed := strings.TrimSpace(target.String())
+	if target.CanSet() {
+		target.SetString(trimmed)
+		return value
+	}
+	return reflect.ValueOf(trimmed)
+}
+
+// pattern returns the compiled regular expression expr
+func pattern(expr string) *regexp.Regexp {
+	if re, ok := patterns.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(expr)
+	patterns.Store(expr, re)
+	return re
+}
+
+// indirect follows pointers and interfaces to the value they hold, returning
+// the zero Value for nil
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+// isEmpty reports whether value is nil, zero or of length zero
+func isEmpty(value reflect.Value) bool {
+	value = indirect(value)
+	if !value.IsValid() {
+		return true
+	}
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+// splitRules splits a validate tag into its rules; pattern takes the rest of
+// the tag as its expression may contain commas
+func splitRules(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+
+	var rules []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "pattern=") {
+			return append(rules, tag)
+		}
+		rule, rest, _ := strings.Cut(tag, ",")
+		rules = append(rules, strings.TrimSpace(rule))
+		tag = rest
+	}
+	return rules
+}
+
+// jsonName returns the name of field in JSON, and whether its json tag gives one
+func jsonName(field reflect.StructField) (string, bool) {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name, false
+	}
+	return name, true
+}
+
+// joinPath appends the field name to the JSON path of its struct
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// label turns a JSON name into the label starting English messages, such as
+// "Created at" for created_at
+func label(name string) string {
+	words := strings.Split(name, "_")
+	for i, word := range words {
+		switch {
+		case initialisms[strings.ToLower(word)]:
+			words[i] = strings.ToUpper(word)
+		case i == 0 && word != "":
+			r, size := utf8.DecodeRuneInString(word)
+			words[i] = string(unicode.ToUpper(r)) + word[size:]
+		}
+	}
+	return strings.Join(words, " ")
+}
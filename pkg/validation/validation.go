@@ -1,6 +1,11 @@
 package validation
 
 import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
 	"learn-api/pkg/errors"
 )
 
@@ -8,6 +13,10 @@ import (
 type ValidationError struct {
 	Field   string `json:"field"`
 	Message string `json:"message"`
+	// Code identifies the message, see NewError
+	Code string `json:"code,omitempty"`
+	// Params fill the placeholders of the message
+	Params map[string]string `json:"-"`
 }
 
 // Validation implements the validation interface for ValidationError
@@ -20,49 +29,142 @@ func (v ValidationError) Error() string {
 	return v.Message
 }
 
-// Validator interface for validation rules
+// FieldError returns v as the field error of an API error
+func (v ValidationError) FieldError() errors.FieldError {
+	return errors.FieldError{Field: v.Field, Message: v.Message, Code: v.Code, Params: v.Params}
+}
+
+// Validator is implemented by structs with rules across their fields, which
+// Struct checks after the validate tags of the fields. Fields are reported by
+// their JSON path relative to the struct.
 type Validator interface {
 	Validate() []ValidationError
 }
 
-// ValidateEntityRequest validates an entity request
-func ValidateEntityRequest(name string) []ValidationError {
+// ToAPIError converts validation errors to API errors
+func ToAPIError(validationErrors []ValidationError) *errors.APIError {
+	if len(validationErrors) == 0 {
+		return nil
+	}
+
+	fieldErrors := make([]errors.FieldError, len(validationErrors))
+	for i, err := range validationErrors {
+		fieldErrors[i] = err.FieldError()
+	}
+
+	return errors.New(errors.CodeValidationFailed).WithDetails(fieldDetails(fieldErrors)).WithFieldErrors(fieldErrors...)
+}
+
+// fieldDetails joins the messages of the invalid fields into the error details
+func fieldDetails(fields []errors.FieldError) string {
+	details := ""
+	for i, field := range fields {
+		if i > 0 {
+			details += "; "
+		}
+		details += field.Field + ": " + field.Message
+	}
+	return details
+}
+
+// ValidateListParams validates paging options for list endpoints
+func ValidateListParams(limit, offset, maxLimit int, after, before string) []ValidationError {
 	var errors []ValidationError
 
-	if name == "" {
-		errors = append(errors, ValidationError{
-			Field:   "name",
-			Message: "Name is required",
-		})
+	if limit < 1 || limit > maxLimit {
+		errors = append(errors, NewError("limit", CodeLimitOutOfRange, map[string]string{"max": strconv.Itoa(maxLimit)}))
+	}
+
+	if offset < 0 {
+		errors = append(errors, NewError("offset", CodeOffsetNegative, nil))
 	}
 
-	if len(name) > 255 {
-		errors = append(errors, ValidationError{
-			Field:   "name",
-			Message: "Name must be less than 255 characters",
-		})
+	if after != "" && before != "" {
+		errors = append(errors, NewError("before", CodeCursorConflict, nil))
+	}
+
+	if (after != "" || before != "") && offset > 0 {
+		errors = append(errors, NewError("offset", CodeOffsetWithCursor, nil))
 	}
 
 	return errors
 }
 
-// ToAPIError converts validation errors to API errors
-func ToAPIError(validationErrors []ValidationError) *errors.APIError {
-	if len(validationErrors) == 0 {
-		return nil
+// FieldKind identifies the value type of a filterable field
+type FieldKind int
+
+// Supported field kinds for list filters
+const (
+	KindString FieldKind = iota
+	KindInt
+	KindTime
+)
+
+// FieldRule describes how a field may be filtered and sorted in list queries
+type FieldRule struct {
+	Kind      FieldKind
+	Operators []string
+	Sortable  bool
+}
+
+// ValidateFilter validates a single list filter against the allowed field rules
+func ValidateFilter(field, operator, value string, rules map[string]FieldRule) []ValidationError {
+	var errors []ValidationError
+	path := "filter[" + field + "][" + operator + "]"
+
+	rule, ok := rules[field]
+	if !ok {
+		return append(errors, NewError(path, CodeFilterUnknownField, map[string]string{"field": field}))
 	}
 
-	details := ""
-	for i, err := range validationErrors {
-		if i > 0 {
-			details += "; "
+	allowed := false
+	for _, op := range rule.Operators {
+		if op == operator {
+			allowed = true
+			break
 		}
-		details += err.Field + ": " + err.Message
+	}
+	if !allowed {
+		return append(errors, NewError(path, CodeFilterUnsupportedOperator, map[string]string{"field": field, "operator": operator}))
 	}
 
-	return &errors.APIError{
-		Code:    400,
-		Message: "Validation failed",
-		Details: details,
+	switch rule.Kind {
+	case KindInt:
+		// Integer fields are int32 columns, so values out of their range are invalid too
+		if _, err := strconv.ParseInt(value, 10, 32); err != nil {
+			errors = append(errors, NewError(path, CodeValueNotInteger, nil))
+		}
+	case KindTime:
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			errors = append(errors, NewError(path, CodeValueNotTimestamp, nil))
+		}
 	}
-}
\ No newline at end of file
+
+	return errors
+}
+
+// ValidateSort validates a single sort field against the allowed field rules
+func ValidateSort(field string, rules map[string]FieldRule) []ValidationError {
+	var errors []ValidationError
+
+	if rule, ok := rules[field]; !ok || !rule.Sortable {
+		errors = append(errors, NewError("sort", CodeSortUnsupported, map[string]string{"field": field}))
+	}
+
+	return errors
+}
+
+// ValidateSearchQuery validates a free-text search query
+func ValidateSearchQuery(query string) []ValidationError {
+	var errors []ValidationError
+
+	if strings.TrimSpace(query) == "" {
+		errors = append(errors, NewError("q", CodeQueryRequired, nil))
+	}
+
+	if utf8.RuneCountInString(query) > 255 {
+		errors = append(errors, NewError("q", CodeQueryTooLong, map[string]string{"max": "255"}))
+	}
+
+	return errors
+}
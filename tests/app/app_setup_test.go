@@ -1,18 +1,29 @@
 package app_test
 
 import (
+    "context"
+    "database/sql"
+    "encoding/json"
     "net/http"
+    "strconv"
+    "strings"
     "testing"
+    "time"
 
     apppkg "learn-api/internal/app"
+    "learn-api/internal/database"
+    "learn-api/internal/health"
     "learn-api/internal/models"
+    "learn-api/internal/repository"
     "learn-api/internal/services/mocks"
+
+    "github.com/stretchr/testify/mock"
 )
 
 func TestNewFiberApp_HealthAndRoutes(t *testing.T) {
     // Arrange: mock service
     mockService := &mocks.EntityServiceMock{}
-    mockService.On("GetAllEntities").Return([]*models.Entity{}, nil)
+    mockService.On("GetAllEntities", mock.Anything, models.ListParams{Limit: models.DefaultPageLimit}).Return(&models.EntityPage{}, nil)
 
     // Act: build app
     app := apppkg.NewFiberApp(mockService)
@@ -39,3 +50,236 @@ func TestNewFiberApp_HealthAndRoutes(t *testing.T) {
 
     mockService.AssertExpectations(t)
 }
+
+func TestNewFiberApp_BatchRoute(t *testing.T) {
+    // Arrange: mock service
+    mockService := &mocks.EntityServiceMock{}
+    batch := &models.BatchCreateRequest{Items: []models.EntityRequest{{Name: "Imported"}}}
+    mockService.On("CreateEntities", mock.Anything, batch).Return(&models.BatchResult{
+        Succeeded: 1,
+        Results:   []models.BatchItemResult{{Index: 0, Status: http.StatusCreated}},
+    }, nil)
+
+    // Act: build app
+    app := apppkg.NewFiberApp(mockService)
+
+    // Assert: the colon in the batch path is matched literally
+    req, _ := http.NewRequest("POST", "/api/v1/entities:batch", strings.NewReader(`{"items":[{"name":"Imported"}]}`))
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := app.Test(req)
+    if err != nil {
+        t.Fatalf("batch request failed: %v", err)
+    }
+    if resp.StatusCode != http.StatusCreated {
+        t.Fatalf("expected batch 201, got %d", resp.StatusCode)
+    }
+
+    mockService.AssertExpectations(t)
+}
+
+func TestNewFiberApp_RequestTimeout(t *testing.T) {
+    // Arrange: the service only answers when the request carries a deadline
+    mockService := &mocks.EntityServiceMock{}
+    hasDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+        _, ok := ctx.Deadline()
+        return ok
+    })
+    mockService.On("GetEntityByID", hasDeadline, 1).Return(nil, context.DeadlineExceeded)
+
+    // Act: build app with a request timeout
+    app := apppkg.NewFiberApp(mockService, apppkg.WithRequestTimeout(time.Second))
+
+    // Assert: the deadline reaches the service and a timeout maps to 504
+    req, _ := http.NewRequest("GET", "/api/v1/entities/1", nil)
+    resp, err := app.Test(req)
+    if err != nil {
+        t.Fatalf("entity request failed: %v", err)
+    }
+    if resp.StatusCode != http.StatusGatewayTimeout {
+        t.Fatalf("expected entity 504, got %d", resp.StatusCode)
+    }
+
+    mockService.AssertExpectations(t)
+}
+
+func TestNewFiberApp_HealthReportsDatabase(t *testing.T) {
+    // Arrange: a pool whose database never answers
+    db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+    if err != nil {
+        t.Fatalf("open failed: %v", err)
+    }
+    defer db.Close()
+
+    // Act: build app with the database
+    app := apppkg.NewFiberApp(&mocks.EntityServiceMock{}, apppkg.WithDatabase(&database.Pool{DB: db}))
+
+    // Assert: the health check fails with the database
+    req, _ := http.NewRequest("GET", "/health", nil)
+    resp, err := app.Test(req, 5000)
+    if err != nil {
+        t.Fatalf("health request failed: %v", err)
+    }
+    if resp.StatusCode != http.StatusServiceUnavailable {
+        t.Fatalf("expected health 503, got %d", resp.StatusCode)
+    }
+}
+
+func TestNewFiberApp_LivenessAndReadiness(t *testing.T) {
+    // Arrange: a pool whose database never answers, plus a healthy custom check
+    db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+    if err != nil {
+        t.Fatalf("open failed: %v", err)
+    }
+    defer db.Close()
+
+    cache := health.CheckerFunc(func(ctx context.Context) (interface{}, error) {
+        return nil, nil
+    })
+    var lifecycle apppkg.Lifecycle
+    app := apppkg.NewFiberApp(&mocks.EntityServiceMock{},
+        apppkg.WithDatabase(database.NewPool(db)),
+        apppkg.WithLifecycle(&lifecycle),
+        apppkg.WithReadinessCheck("cache", cache),
+    )
+
+    // Assert: liveness does not depend on the database
+    req, _ := http.NewRequest("GET", "/livez", nil)
+    resp, err := app.Test(req)
+    if err != nil {
+        t.Fatalf("livez request failed: %v", err)
+    }
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected livez 200, got %d", resp.StatusCode)
+    }
+
+    // Assert: readiness reports every check
+    req, _ = http.NewRequest("GET", "/readyz", nil)
+    resp, err = app.Test(req, 5000)
+    if err != nil {
+        t.Fatalf("readyz request failed: %v", err)
+    }
+    if resp.StatusCode != http.StatusServiceUnavailable {
+        t.Fatalf("expected readyz 503, got %d", resp.StatusCode)
+    }
+
+    var report health.Report
+    if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+        t.Fatalf("decoding readiness report failed: %v", err)
+    }
+    if report.Status != health.StatusDown || len(report.Checks) != 3 {
+        t.Fatalf("expected 3 checks and status down, got %+v", report)
+    }
+    if report.Checks["database"].Status != health.StatusDown || report.Checks["database"].Details == nil {
+        t.Errorf("expected the database down with pool stats, got %+v", report.Checks["database"])
+    }
+    if report.Checks["cache"].Status != health.StatusUp || report.Checks["shutdown"].Status != health.StatusUp {
+        t.Errorf("expected cache and shutdown up, got %+v", report.Checks)
+    }
+}
+
+func TestNewFiberApp_ReadinessWhileDraining(t *testing.T) {
+    // Arrange: no dependencies besides the lifecycle
+    var lifecycle apppkg.Lifecycle
+    app := apppkg.NewFiberApp(&mocks.EntityServiceMock{}, apppkg.WithLifecycle(&lifecycle))
+
+    req, _ := http.NewRequest("GET", "/readyz", nil)
+    resp, err := app.Test(req)
+    if err != nil {
+        t.Fatalf("readyz request failed: %v", err)
+    }
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected readyz 200, got %d", resp.StatusCode)
+    }
+
+    // Act: start shutting down
+    lifecycle.Drain()
+
+    // Assert: readiness fails with the shutdown check while liveness holds
+    req, _ = http.NewRequest("GET", "/readyz", nil)
+    resp, err = app.Test(req)
+    if err != nil {
+        t.Fatalf("readyz request failed: %v", err)
+    }
+    var report health.Report
+    json.NewDecoder(resp.Body).Decode(&report)
+    if resp.StatusCode != http.StatusServiceUnavailable || report.Checks["shutdown"].Error != "shutting down" {
+        t.Fatalf("expected readyz 503 while draining, got %d %+v", resp.StatusCode, report)
+    }
+
+    req, _ = http.NewRequest("GET", "/livez", nil)
+    resp, err = app.Test(req)
+    if err != nil {
+        t.Fatalf("livez request failed: %v", err)
+    }
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected livez 200 while draining, got %d", resp.StatusCode)
+    }
+}
+
+func TestNewFiberApp_ReadYourWrites(t *testing.T) {
+    // Arrange: reads are told apart by whether they must use the primary
+    mockService := &mocks.EntityServiceMock{}
+    mockService.On("CreateEntity", mock.Anything, mock.Anything).Return(&models.Entity{ID: 1, Name: "Fresh"}, nil)
+    mockService.On("GetEntityByID", mock.MatchedBy(repository.ReadsFromPrimary), 1).Return(&models.Entity{ID: 1}, nil).Once()
+    mockService.On("GetEntityByID", mock.Anything, 2).Return(&models.Entity{ID: 2}, nil).Once()
+
+    app := apppkg.NewFiberApp(mockService, apppkg.WithReadYourWrites(5*time.Second))
+
+    // Act: write an entity
+    req, _ := http.NewRequest("POST", "/api/v1/entities", strings.NewReader(`{"name":"Fresh"}`))
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := app.Test(req)
+    if err != nil {
+        t.Fatalf("create request failed: %v", err)
+    }
+
+    // Assert: the write is stamped in a header and a cookie
+    lastWrite := resp.Header.Get("X-Last-Write")
+    if lastWrite == "" {
+        t.Fatal("expected X-Last-Write header on a write")
+    }
+    if cookie := resp.Header.Get("Set-Cookie"); !strings.Contains(cookie, "last_write="+lastWrite) {
+        t.Fatalf("expected last_write cookie, got %q", cookie)
+    }
+
+    // Act: read it back within the window
+    req, _ = http.NewRequest("GET", "/api/v1/entities/1", nil)
+    req.Header.Set("X-Last-Write", lastWrite)
+    resp, err = app.Test(req)
+    if err != nil {
+        t.Fatalf("read request failed: %v", err)
+    }
+    if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Last-Write") != "" {
+        t.Fatalf("expected an unstamped 200 from the primary, got %d", resp.StatusCode)
+    }
+
+    // Act: read with a write from long ago
+    stale := strconv.FormatInt(time.Now().Add(-time.Hour).UnixMilli(), 10)
+    req, _ = http.NewRequest("GET", "/api/v1/entities/2", nil)
+    req.Header.Set("Cookie", "last_write="+stale)
+    resp, err = app.Test(req)
+    if err != nil {
+        t.Fatalf("read request failed: %v", err)
+    }
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected read 200, got %d", resp.StatusCode)
+    }
+
+    // Act: write to a route that does not exist
+    req, _ = http.NewRequest("POST", "/api/v1/unknown", strings.NewReader(`{}`))
+    resp, err = app.Test(req)
+    if err != nil {
+        t.Fatalf("unmatched request failed: %v", err)
+    }
+
+    // Assert: failed writes are not stamped
+    if resp.StatusCode != http.StatusNotFound {
+        t.Fatalf("expected unmatched write 404, got %d", resp.StatusCode)
+    }
+    if resp.Header.Get("X-Last-Write") != "" || resp.Header.Get("Set-Cookie") != "" {
+        t.Fatalf("expected no stamp on a failed write, got %q and %q", resp.Header.Get("X-Last-Write"), resp.Header.Get("Set-Cookie"))
+    }
+
+    mockService.AssertExpectations(t)
+    mockService.AssertNotCalled(t, "GetEntityByID", mock.MatchedBy(repository.ReadsFromPrimary), 2)
+}
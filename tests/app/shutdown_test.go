@@ -0,0 +1,88 @@
+package app_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	apppkg "learn-api/internal/app"
+	"learn-api/internal/models"
+	"learn-api/internal/services/mocks"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestServe_GracefulShutdown(t *testing.T) {
+	// Arrange: a slow request that is in flight when the shutdown starts
+	started := make(chan struct{})
+	mockService := &mocks.EntityServiceMock{}
+	mockService.On("GetAllEntities", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+	}).Return(&models.EntityPage{}, nil)
+
+	var lifecycle apppkg.Lifecycle
+	app := apppkg.NewFiberApp(mockService, apppkg.WithLifecycle(&lifecycle))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	base := "http://" + ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- apppkg.Serve(ctx, app, ln, &lifecycle, apppkg.ShutdownConfig{
+			Delay:   200 * time.Millisecond,
+			Timeout: 5 * time.Second,
+		})
+	}()
+
+	slow := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(base + "/api/v1/entities/")
+		if err != nil {
+			slow <- 0
+			return
+		}
+		resp.Body.Close()
+		slow <- resp.StatusCode
+	}()
+	<-started
+
+	// Act: signal the shutdown
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+
+	// Assert: during the delay the health check reports not ready
+	resp, err := http.Get(base + "/health")
+	if err != nil {
+		t.Fatalf("health request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected health 503 while draining, got %d", resp.StatusCode)
+	}
+
+	// Assert: the in-flight request completes and the server stops
+	if status := <-slow; status != http.StatusOK {
+		t.Errorf("expected the in-flight request to finish with 200, got %d", status)
+	}
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+
+	if _, err := net.DialTimeout("tcp", ln.Addr().String(), time.Second); err == nil {
+		t.Error("expected new connections to be refused after shutdown")
+	}
+}
@@ -0,0 +1,106 @@
+package database_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"learn-api/internal/database"
+)
+
+func TestConfigDSN(t *testing.T) {
+	cfg := database.DefaultConfig()
+	cfg.Password = "it's secret"
+	cfg.StatementTimeout = 1500 * time.Millisecond
+
+	dsn, err := cfg.DSN()
+	if err != nil {
+		t.Fatalf("Error building DSN: %v", err)
+	}
+
+	want := `host=localhost port=5432 user=postgres password='it\'s secret' dbname=learnapi sslmode=disable application_name=learn-api statement_timeout=1500`
+	if dsn != want {
+		t.Errorf("Expected %s, got %s", want, dsn)
+	}
+}
+
+func TestConfigDSN_URL(t *testing.T) {
+	cfg := database.DefaultConfig()
+	cfg.URL = "postgres://app:pw@db.example.com:6432/prod?sslmode=require"
+	cfg.Host = "ignored"
+	cfg.StatementTimeout = time.Second
+
+	dsn, err := cfg.DSN()
+	if err != nil {
+		t.Fatalf("Error building DSN: %v", err)
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("Expected a URL, got %s", dsn)
+	}
+
+	if u.Host != "db.example.com:6432" || u.Path != "/prod" || u.User.String() != "app:pw" {
+		t.Errorf("Expected the URL's connection details to be kept, got %s", dsn)
+	}
+
+	query := u.Query()
+	if query.Get("sslmode") != "require" {
+		t.Errorf("Expected the URL's sslmode to win, got %s", query.Get("sslmode"))
+	}
+
+	if query.Get("statement_timeout") != "1000" || query.Get("application_name") != "learn-api" {
+		t.Errorf("Expected session settings to be added, got %s", dsn)
+	}
+
+	if strings.Contains(dsn, "ignored") {
+		t.Errorf("Expected individual fields to be ignored, got %s", dsn)
+	}
+}
+
+func TestOpen_RetriesThenFails(t *testing.T) {
+	cfg := database.DefaultConfig()
+	cfg.Host = "127.0.0.1"
+	cfg.Port = "1" // nothing listens here
+	cfg.ConnectAttempts = 3
+	cfg.ConnectBackoff = 10 * time.Millisecond
+
+	start := time.Now()
+	db, err := database.Open(context.Background(), cfg)
+	if err == nil {
+		db.Close()
+		t.Fatal("Expected connecting to fail")
+	}
+
+	if !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("Expected the attempts to be reported, got %v", err)
+	}
+
+	// Two waits of 10ms and 20ms between the three attempts
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected exponential backoff between attempts, took %s", elapsed)
+	}
+}
+
+func TestOpen_StopsWhenContextEnds(t *testing.T) {
+	cfg := database.DefaultConfig()
+	cfg.Host = "127.0.0.1"
+	cfg.Port = "1"
+	cfg.ConnectAttempts = 100
+	cfg.ConnectBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	db, err := database.Open(ctx, cfg)
+	if err == nil {
+		db.Close()
+		t.Fatal("Expected connecting to fail")
+	}
+
+	if ctx.Err() == nil {
+		t.Errorf("Expected Open to wait for the context, got %v", err)
+	}
+}
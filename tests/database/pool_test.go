@@ -0,0 +1,74 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"learn-api/internal/database"
+)
+
+// unreachable opens a handle to a database that never answers
+func unreachable(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	return db
+}
+
+func TestPool_ReplicaRoundRobin(t *testing.T) {
+	primary, first, second := unreachable(t), unreachable(t), unreachable(t)
+	pool := database.NewPool(primary, first, second)
+	defer pool.Close()
+
+	want := []*sql.DB{first, second, first, second}
+	for i, db := range want {
+		if got := pool.Replica(); got != db {
+			t.Errorf("Read %d: expected replica %p, got %p", i, db, got)
+		}
+	}
+
+	if pool.ReplicaCount() != 2 {
+		t.Errorf("Expected 2 replicas, got %d", pool.ReplicaCount())
+	}
+}
+
+func TestPool_UnhealthyReplicasFallBackToPrimary(t *testing.T) {
+	primary := unreachable(t)
+	pool := database.NewPool(primary, unreachable(t), unreachable(t))
+	defer pool.Close()
+
+	if pool.HealthyReplicaCount() != 2 {
+		t.Errorf("Expected replicas to start healthy, got %d", pool.HealthyReplicaCount())
+	}
+
+	pool.CheckReplicas(context.Background())
+
+	if pool.HealthyReplicaCount() != 0 {
+		t.Errorf("Expected no healthy replicas, got %d", pool.HealthyReplicaCount())
+	}
+
+	if got := pool.Replica(); got != primary {
+		t.Errorf("Expected the primary once every replica failed its health check, got %p", got)
+	}
+}
+
+func TestPool_WithoutReplicas(t *testing.T) {
+	primary := unreachable(t)
+	pool := database.NewPool(primary)
+
+	if got := pool.Replica(); got != primary {
+		t.Errorf("Expected the primary, got %p", got)
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Errorf("Error closing pool: %v", err)
+	}
+
+	if err := primary.Ping(); err == nil || err.Error() != "sql: database is closed" {
+		t.Errorf("Expected the primary to be closed, got %v", err)
+	}
+}
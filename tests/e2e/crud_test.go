@@ -63,41 +63,42 @@ func waitForService(timeout time.Duration) error {
 func cleanup() {
 	client := &http.Client{}
 
-	// Get all entities
-	resp, err := client.Get(baseURL + "/api/v1/entities")
-	if err != nil {
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return
-	}
-
-	var response map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return
-	}
-
-	data, ok := response["data"].([]interface{})
-	if !ok {
-		return
-	}
+	// Collect the ids of all entities, following the pagination links
+	var ids []float64
+	next := "/api/v1/entities?limit=100"
+	for next != "" {
+		resp, err := client.Get(baseURL + next)
+		if err != nil {
+			return
+		}
 
-	// Delete each entity
-	for _, item := range data {
-		entity, ok := item.(map[string]interface{})
-		if !ok {
-			continue
+		var response struct {
+			Data  []map[string]interface{} `json:"data"`
+			Links struct {
+				Next string `json:"next"`
+			} `json:"links"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || err != nil {
+			return
 		}
 
-		id, ok := entity["id"].(float64)
-		if !ok {
-			continue
+		for _, entity := range response.Data {
+			if id, ok := entity["id"].(float64); ok {
+				ids = append(ids, id)
+			}
 		}
+		next = response.Links.Next
+	}
 
+	// Delete each entity and purge it from the trash
+	for _, id := range ids {
 		req, _ := http.NewRequest("DELETE", fmt.Sprintf("%s/api/v1/entities/%.0f", baseURL, id), nil)
 		client.Do(req)
+
+		req, _ = http.NewRequest("DELETE", fmt.Sprintf("%s/api/v1/entities/trash/%.0f", baseURL, id), nil)
+		client.Do(req)
 	}
 }
 
@@ -238,6 +239,15 @@ func TestCRUDOperations(t *testing.T) {
 		if int(count) != len(data) {
 			t.Errorf("Expected count to be %d, got %v", len(data), count)
 		}
+
+		total, ok := response["total"].(float64)
+		if !ok {
+			t.Fatal("Expected total field in response")
+		}
+
+		if int(total) < len(data) {
+			t.Errorf("Expected total to be at least %d, got %v", len(data), total)
+		}
 	})
 
 	// Test 4: Update Entity
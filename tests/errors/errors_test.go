@@ -0,0 +1,234 @@
+package errors_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"learn-api/pkg/errors"
+	"learn-api/pkg/i18n"
+	"learn-api/pkg/validation"
+
+	"github.com/lib/pq"
+)
+
+func TestHandleError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		want       *errors.APIError
+		retryAfter int
+	}{
+		{name: "api error", err: errors.New(errors.CodePreconditionFailed), want: errors.New(errors.CodePreconditionFailed)},
+		{name: "no rows", err: sql.ErrNoRows, want: errors.New(errors.CodeEntityNotFound)},
+		{name: "deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: errors.New(errors.CodeTimeout)},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}, want: errors.New(errors.CodeConflict)},
+		{name: "wrapped unique violation", err: fmt.Errorf("creating entity: %w", &pq.Error{Code: "23505"}), want: errors.New(errors.CodeConflict)},
+		{name: "not null violation", err: &pq.Error{Code: "23502"}, want: errors.New(errors.CodeConstraintViolation)},
+		{name: "check violation", err: &pq.Error{Code: "23514"}, want: errors.New(errors.CodeConstraintViolation)},
+		{name: "value too long", err: &pq.Error{Code: "22001"}, want: errors.New(errors.CodeConstraintViolation)},
+		{name: "invalid text representation", err: &pq.Error{Code: "22P02"}, want: errors.New(errors.CodeInvalidRequest)},
+		{name: "value out of range", err: &pq.Error{Code: "22003"}, want: errors.New(errors.CodeInvalidRequest)},
+		{name: "invalid datetime format", err: &pq.Error{Code: "22007"}, want: errors.New(errors.CodeInvalidRequest)},
+		{name: "serialization failure", err: &pq.Error{Code: "40001"}, want: errors.New(errors.CodeUnavailable), retryAfter: 1},
+		{name: "deadlock", err: &pq.Error{Code: "40P01"}, want: errors.New(errors.CodeUnavailable), retryAfter: 1},
+		{name: "connection failure", err: &pq.Error{Code: "08006"}, want: errors.New(errors.CodeUnavailable), retryAfter: 1},
+		{name: "statement timeout", err: &pq.Error{Code: "57014"}, want: errors.New(errors.CodeTimeout)},
+		{name: "syntax error", err: &pq.Error{Code: "42601"}, want: errors.New(errors.CodeDatabaseError)},
+		{name: "unknown", err: stderrors.New("boom"), want: errors.New(errors.CodeDatabaseError)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := errors.HandleError(tt.err)
+			if !stderrors.Is(got, tt.want) || got.Status() != tt.want.Status() {
+				t.Errorf("HandleError() = %d %s, want %d %s", got.Status(), got.Code(), tt.want.Status(), tt.want.Code())
+			}
+			if got.RetryAfter() != tt.retryAfter {
+				t.Errorf("Expected Retry-After %d, got %d", tt.retryAfter, got.RetryAfter())
+			}
+		})
+	}
+}
+
+func TestHandleError_KeepsCause(t *testing.T) {
+	cause := &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"}
+
+	apiErr := errors.HandleError(fmt.Errorf("creating entity: %w", cause))
+	if apiErr.Status() != http.StatusConflict {
+		t.Fatalf("Expected status 409, got %d", apiErr.Status())
+	}
+
+	var pqErr *pq.Error
+	if !stderrors.As(apiErr, &pqErr) || pqErr != cause {
+		t.Error("Expected the database error to be kept as the cause")
+	}
+
+	if errors.New(errors.CodeConflict).Unwrap() != nil {
+		t.Error("Expected a new error to carry no cause")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	cause := stderrors.New("connection reset")
+	err := fmt.Errorf("loading entity: %w", errors.Wrap(errors.CodeUnavailable, cause))
+
+	if !errors.Is(err, errors.CodeUnavailable) {
+		t.Error("Expected the wrapped error to be an unavailable error")
+	}
+	if errors.Is(err, errors.CodeDatabaseError) {
+		t.Error("Expected the wrapped error not to match another code")
+	}
+	if !stderrors.Is(err, cause) {
+		t.Error("Expected the cause to be found through the API error")
+	}
+
+	var apiErr *errors.APIError
+	if !stderrors.As(err, &apiErr) || apiErr.Code() != errors.CodeUnavailable {
+		t.Fatalf("Expected to find the API error, got %v", err)
+	}
+	if apiErr.Error() != "Service unavailable: connection reset" {
+		t.Errorf("Expected the cause in the error text, got %q", apiErr.Error())
+	}
+
+	// HandleError keeps an API error found anywhere in the chain
+	if got := errors.HandleError(err); got != apiErr {
+		t.Errorf("Expected HandleError to return the wrapped API error, got %v", got)
+	}
+}
+
+func TestHandleError_ValidationError(t *testing.T) {
+	cause := validation.NewError("name", validation.CodeRequired, map[string]string{"label": "Name"})
+
+	// A bare validation error keeps its field, so problem details can list it
+	apiErr := errors.HandleError(cause)
+	if !errors.Is(apiErr, errors.CodeValidationFailed) {
+		t.Fatalf("Expected VALIDATION_FAILED, got %s", apiErr.Code())
+	}
+
+	fields := apiErr.Problem("").Errors
+	if len(fields) != 1 || fields[0].Field != "name" || fields[0].Code != validation.CodeRequired {
+		t.Errorf("Expected the name field error, got %+v", fields)
+	}
+	if apiErr.Details() != "name: "+cause.Message {
+		t.Errorf("Expected the field in the details, got %q", apiErr.Details())
+	}
+}
+
+func TestAPIError_Immutable(t *testing.T) {
+	fields := []errors.FieldError{{Field: "name", Message: "Name is required"}}
+	base := errors.New(errors.CodeValidationFailed)
+	err := base.
+		WithDetails("name: Name is required").
+		WithRequestID("req-1").
+		WithFieldErrors(fields...)
+
+	// The copies carry the metadata; the original is left unchanged
+	if err.Details() != "name: Name is required" || err.RequestID() != "req-1" || len(err.FieldErrors()) != 1 {
+		t.Errorf("Expected the metadata on the copy, got %+v", err.Problem(""))
+	}
+	if base.Details() != "The request data failed validation" || base.RequestID() != "" {
+		t.Error("Expected the original error to be left unchanged")
+	}
+
+	// Neither the given nor the returned fields alias the error's own
+	fields[0].Field = "changed"
+	err.FieldErrors()[0].Message = "changed"
+	if got := err.FieldErrors()[0]; got.Field != "name" || got.Message != "Name is required" {
+		t.Errorf("Expected the field errors to be copied, got %+v", got)
+	}
+
+	if !errors.Is(err, errors.CodeValidationFailed) {
+		t.Error("Expected the copy to keep its code")
+	}
+}
+
+func TestAPIError_MarshalJSON(t *testing.T) {
+	body, err := json.Marshal(errors.New(errors.CodeEntityNotFound).WithRequestID("req-1"))
+	if err != nil {
+		t.Fatalf("Error marshaling: %v", err)
+	}
+
+	// Clients of the error envelope keep its shape
+	want := `{"code":404,"message":"Entity not found","details":"The requested entity could not be found","request_id":"req-1"}`
+	if string(body) != want {
+		t.Errorf("Expected %s, got %s", want, body)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	seen := map[errors.Code]bool{}
+	for _, def := range errors.Catalog() {
+		if seen[def.Code] {
+			t.Errorf("Code %s is defined twice", def.Code)
+		}
+		seen[def.Code] = true
+
+		if def.Code != errors.Code(strings.ToUpper(string(def.Code))) || def.Status < 400 {
+			t.Errorf("Code %s: expected an upper case code and an error status, got %d", def.Code, def.Status)
+		}
+		if def.Title == "" || def.Detail == "" || def.Description == "" {
+			t.Errorf("Code %s is not fully documented", def.Code)
+		}
+		if got := errors.New(def.Code); got.Status() != def.Status || got.Message() != def.Title {
+			t.Errorf("Code %s: New does not follow the catalog", def.Code)
+		}
+	}
+
+	// The catalog cannot be changed through the returned definitions
+	errors.Catalog()[0].Status = 200
+	if errors.Catalog()[0].Status == 200 {
+		t.Error("Expected Catalog to return a copy")
+	}
+}
+
+func TestAPIError_Localize(t *testing.T) {
+	// Every kind of error is translated into every language
+	for _, lang := range i18n.Languages() {
+		for _, def := range errors.Catalog() {
+			localized := errors.New(def.Code).Localize(lang)
+			if lang != i18n.Default && (localized.Message() == def.Title || localized.Details() == def.Detail) {
+				t.Errorf("Code %s is not translated into %s", def.Code, lang)
+			}
+			if localized.Code() != def.Code || localized.Status() != def.Status {
+				t.Errorf("Code %s: expected the code and status to stay the same in %s", def.Code, lang)
+			}
+		}
+	}
+
+	// Details given for the occurrence are kept
+	base := errors.New(errors.CodeInvalidRequest).WithDetails("custom")
+	err := base.Localize(i18n.Thai)
+	if err.Details() != "custom" || err.Message() != "คำขอไม่ถูกต้อง" {
+		t.Errorf("Expected the Thai title and the given details, got %q and %q", err.Message(), err.Details())
+	}
+
+	// The error localized is left in English
+	if base.Message() != "Invalid request" {
+		t.Error("Expected the original error to be left untranslated")
+	}
+}
+
+// TestCatalogDoc fails when docs/errors.md is out of date; run go generate ./pkg/errors
+func TestCatalogDoc(t *testing.T) {
+	committed, err := os.ReadFile("../../docs/errors.md")
+	if err != nil {
+		t.Fatalf("Error reading the catalog page: %v", err)
+	}
+
+	var generated bytes.Buffer
+	if err := errors.WriteCatalog(&generated); err != nil {
+		t.Fatalf("Error writing the catalog: %v", err)
+	}
+
+	if !bytes.Equal(committed, generated.Bytes()) {
+		t.Error("docs/errors.md is out of date; run go generate ./pkg/errors")
+	}
+}
@@ -1,16 +1,24 @@
-package handlers_test
+package handlers_test
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/mock"
 
 	"learn-api/internal/handlers"
 	"learn-api/internal/models"
+	"learn-api/internal/repository"
 	"learn-api/internal/services/mocks"
+	"learn-api/pkg/errors"
 )
 
 func TestCreateEntityFiber(t *testing.T) {
@@ -32,7 +40,7 @@ func TestCreateEntityFiber(t *testing.T) {
 		ID:   1,
 		Name: "Test Entity",
 	}
-	mockService.On("CreateEntity", entityReq).Return(expectedEntity, nil)
+	mockService.On("CreateEntity", mock.Anything, entityReq).Return(expectedEntity, nil)
 
 	// Create request body
 	body, _ := json.Marshal(entityReq)
@@ -72,7 +80,7 @@ func TestGetEntityByIDFiber(t *testing.T) {
 		ID:   1,
 		Name: "Test Entity",
 	}
-	mockService.On("GetEntityByID", 1).Return(expectedEntity, nil)
+	mockService.On("GetEntityByID", mock.Anything, 1).Return(expectedEntity, nil)
 
 	// Make request
 	req, _ := http.NewRequest("GET", "/entities/1", nil)
@@ -92,6 +100,35 @@ func TestGetEntityByIDFiber(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestGetEntityByIDFiber_InvalidID(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Get("/entities/:id", entityHandler.GetEntityByIDFiber)
+
+	// IDs that are not numbers or do not fit the id column are rejected before the service
+	for _, id := range []string{"abc", "3000000000", "-3000000000"} {
+		req, _ := http.NewRequest("GET", "/entities/"+id, nil)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("ID %s: expected status code %d, got %d", id, fiber.StatusBadRequest, resp.StatusCode)
+		}
+	}
+
+	// The service must not be called for invalid IDs
+	mockService.AssertExpectations(t)
+}
+
 func TestGetAllEntitiesFiber(t *testing.T) {
 	// Create a mock service
 	mockService := &mocks.EntityServiceMock{}
@@ -108,7 +145,8 @@ func TestGetAllEntitiesFiber(t *testing.T) {
 		{ID: 1, Name: "Entity 1"},
 		{ID: 2, Name: "Entity 2"},
 	}
-	mockService.On("GetAllEntities").Return(expectedEntities, nil)
+	expectedParams := models.ListParams{Limit: models.DefaultPageLimit}
+	mockService.On("GetAllEntities", mock.Anything, expectedParams).Return(&models.EntityPage{Entities: expectedEntities, Total: 2}, nil)
 
 	// Make request
 	req, _ := http.NewRequest("GET", "/entities", nil)
@@ -147,7 +185,7 @@ func TestUpdateEntityFiber(t *testing.T) {
 		ID:   1,
 		Name: "Updated Name",
 	}
-	mockService.On("UpdateEntity", 1, entityReq).Return(expectedEntity, nil)
+	mockService.On("UpdateEntity", mock.Anything, 1, entityReq, 0).Return(expectedEntity, nil)
 
 	// Create request body
 	body, _ := json.Marshal(entityReq)
@@ -183,7 +221,7 @@ func TestDeleteEntityFiber(t *testing.T) {
 	app.Delete("/entities/:id", entityHandler.DeleteEntityFiber)
 
 	// Set up the mock expectation
-	mockService.On("DeleteEntity", 1).Return(nil)
+	mockService.On("DeleteEntity", mock.Anything, 1, 0).Return(nil)
 
 	// Make request
 	req, _ := http.NewRequest("DELETE", "/entities/1", nil)
@@ -215,7 +253,7 @@ func TestGetEntityByIDNotFoundFiber(t *testing.T) {
 	app.Get("/entities/:id", entityHandler.GetEntityByIDFiber)
 
 	// Set up the mock expectation for non-existent entity
-	mockService.On("GetEntityByID", 999).Return(nil, nil)
+	mockService.On("GetEntityByID", mock.Anything, 999).Return(nil, nil)
 
 	// Make request
 	req, _ := http.NewRequest("GET", "/entities/999", nil)
@@ -234,3 +272,1021 @@ func TestGetEntityByIDNotFoundFiber(t *testing.T) {
 	// Verify mock was called
 	mockService.AssertExpectations(t)
 }
+
+func TestGetAllEntitiesFiber_PaginationLinks(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Get("/entities", entityHandler.GetAllEntitiesFiber)
+
+	// Set up the mock expectation for the second page of an offset listing
+	expectedParams := models.ListParams{Limit: 2, Offset: 2}
+	mockService.On("GetAllEntities", mock.Anything, expectedParams).Return(&models.EntityPage{
+		Entities: []*models.Entity{{ID: 3, Name: "Entity 3"}, {ID: 4, Name: "Entity 4"}},
+		Total:    6,
+		HasNext:  true,
+		HasPrev:  true,
+	}, nil)
+
+	// Make request
+	req, _ := http.NewRequest("GET", "/entities?limit=2&offset=2", nil)
+
+	// Perform request
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	// Check status code
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", fiber.StatusOK, resp.StatusCode)
+	}
+
+	var response struct {
+		Count int `json:"count"`
+		Total int `json:"total"`
+		Links struct {
+			Next string `json:"next"`
+			Prev string `json:"prev"`
+		} `json:"links"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Count != 2 || response.Total != 6 {
+		t.Errorf("Expected count 2 and total 6, got %d and %d", response.Count, response.Total)
+	}
+
+	if response.Links.Next != "/entities?limit=2&offset=4" {
+		t.Errorf("Unexpected next link: %s", response.Links.Next)
+	}
+
+	if response.Links.Prev != "/entities?limit=2&offset=0" {
+		t.Errorf("Unexpected prev link: %s", response.Links.Prev)
+	}
+
+	// Verify mock was called
+	mockService.AssertExpectations(t)
+}
+
+func TestGetAllEntitiesFiber_InvalidPagination(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Get("/entities", entityHandler.GetAllEntitiesFiber)
+
+	for _, query := range []string{"limit=0", "limit=abc", "limit=1000", "offset=-1", "after=a&before=b", "after=a&offset=5"} {
+		// Make request
+		req, _ := http.NewRequest("GET", "/entities?"+query, nil)
+
+		// Perform request
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+
+		// Check status code
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", query, fiber.StatusBadRequest, resp.StatusCode)
+		}
+	}
+
+	// The service must not be called for invalid requests
+	mockService.AssertNotCalled(t, "GetAllEntities", mock.Anything, mock.Anything)
+}
+
+func TestGetAllEntitiesFiber_FilterAndSort(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Get("/entities", entityHandler.GetAllEntitiesFiber)
+
+	// Set up the mock expectation
+	expectedParams := models.ListParams{
+		Limit: models.DefaultPageLimit,
+		Filters: []models.Filter{
+			{Field: "created_at", Operator: "gte", Value: "2024-01-01T00:00:00Z"},
+			{Field: "name", Operator: "contains", Value: "foo"},
+		},
+		Sort: []models.SortField{
+			{Field: "updated_at", Desc: true},
+			{Field: "name"},
+		},
+	}
+	mockService.On("GetAllEntities", mock.Anything, expectedParams).Return(&models.EntityPage{}, nil)
+
+	// Make request
+	req, _ := http.NewRequest("GET", "/entities?filter%5Bname%5D%5Bcontains%5D=foo&filter%5Bcreated_at%5D%5Bgte%5D=2024-01-01T00:00:00Z&sort=-updated_at,name", nil)
+
+	// Perform request
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	// Check status code
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected status code %d, got %d", fiber.StatusOK, resp.StatusCode)
+	}
+
+	// Verify mock was called
+	mockService.AssertExpectations(t)
+}
+
+func TestGetAllEntitiesFiber_InvalidFilterAndSort(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Get("/entities", entityHandler.GetAllEntitiesFiber)
+
+	queries := []string{
+		"filter%5Bpassword%5D=x",
+		"filter%5Bname%5D%5Bgte%5D=x",
+		"filter%5Bid%5D%5Beq%5D=abc",
+		"filter%5Bid%5D%5Beq%5D=3000000000",
+		"filter%5Bcreated_at%5D%5Bgt%5D=yesterday",
+		"filter=x",
+		"sort=secret",
+		"sort=name,-name",
+	}
+
+	for _, query := range queries {
+		// Make request
+		req, _ := http.NewRequest("GET", "/entities?"+query, nil)
+
+		// Perform request
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+
+		// Check status code
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", query, fiber.StatusBadRequest, resp.StatusCode)
+		}
+	}
+
+	// The service must not be called for invalid requests
+	mockService.AssertNotCalled(t, "GetAllEntities", mock.Anything, mock.Anything)
+}
+
+func TestSearchEntitiesFiber(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Get("/entities/search", entityHandler.SearchEntitiesFiber)
+
+	// Set up the mock expectation
+	expectedResults := []*models.EntitySearchResult{
+		{Entity: models.Entity{ID: 1, Name: "Widget"}, Score: 0.8},
+	}
+	mockService.On("SearchEntities", mock.Anything, "widgte", 5).Return(expectedResults, nil)
+
+	// Make request
+	req, _ := http.NewRequest("GET", "/entities/search?q=widgte&limit=5", nil)
+
+	// Perform request
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	// Check status code
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", fiber.StatusOK, resp.StatusCode)
+	}
+
+	var response struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(response.Data) != 1 || response.Data[0]["name"] != "Widget" || response.Data[0]["score"] != 0.8 {
+		t.Errorf("Unexpected search results: %v", response.Data)
+	}
+
+	// Verify mock was called
+	mockService.AssertExpectations(t)
+}
+
+func TestSearchEntitiesFiber_MissingQuery(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Get("/entities/search", entityHandler.SearchEntitiesFiber)
+
+	// Make request
+	req, _ := http.NewRequest("GET", "/entities/search?q=%20", nil)
+
+	// Perform request
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	// Check status code
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", fiber.StatusBadRequest, resp.StatusCode)
+	}
+
+	// The service must not be called for invalid requests
+	mockService.AssertNotCalled(t, "SearchEntities", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetDeletedEntitiesFiber(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Get("/entities/trash", entityHandler.GetDeletedEntitiesFiber)
+
+	// Set up the mock expectation
+	expectedParams := models.ListParams{Limit: models.DefaultPageLimit}
+	mockService.On("GetDeletedEntities", mock.Anything, expectedParams).Return(&models.EntityPage{
+		Entities: []*models.Entity{{ID: 1, Name: "Deleted Entity"}},
+		Total:    1,
+	}, nil)
+
+	// Make request
+	req, _ := http.NewRequest("GET", "/entities/trash", nil)
+
+	// Perform request
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	// Check status code
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected status code %d, got %d", fiber.StatusOK, resp.StatusCode)
+	}
+
+	// Verify mock was called
+	mockService.AssertExpectations(t)
+}
+
+func TestRestoreEntityFiber(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Post("/entities/:id/restore", entityHandler.RestoreEntityFiber)
+
+	// Set up the mock expectations
+	mockService.On("RestoreEntity", mock.Anything, 1).Return(&models.Entity{ID: 1, Name: "Restored"}, nil)
+	mockService.On("RestoreEntity", mock.Anything, 2).Return(nil, sql.ErrNoRows)
+
+	// Restoring a trashed entity succeeds
+	req, _ := http.NewRequest("POST", "/entities/1/restore", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected status code %d, got %d", fiber.StatusOK, resp.StatusCode)
+	}
+
+	// Restoring an entity that is not in the trash is not found
+	req, _ = http.NewRequest("POST", "/entities/2/restore", nil)
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", fiber.StatusNotFound, resp.StatusCode)
+	}
+
+	// Verify mock was called
+	mockService.AssertExpectations(t)
+}
+
+func TestPurgeEntityFiber(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Delete("/entities/trash/:id", entityHandler.PurgeEntityFiber)
+
+	// Set up the mock expectation
+	mockService.On("PurgeEntity", mock.Anything, 1).Return(nil)
+
+	// Make request
+	req, _ := http.NewRequest("DELETE", "/entities/trash/1", nil)
+
+	// Perform request
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	// Check status code
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", fiber.StatusNoContent, resp.StatusCode)
+	}
+
+	// Verify mock was called
+	mockService.AssertExpectations(t)
+}
+
+func TestGetEntityByIDFiber_ETag(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Get("/entities/:id", entityHandler.GetEntityByIDFiber)
+
+	// Set up the mock expectation
+	mockService.On("GetEntityByID", mock.Anything, 1).Return(&models.Entity{ID: 1, Name: "Test Entity", Version: 3}, nil)
+
+	cases := []struct {
+		ifNoneMatch string
+		status      int
+	}{
+		{"", fiber.StatusOK},
+		{`"2"`, fiber.StatusOK},
+		{`"3"`, fiber.StatusNotModified},
+		{`W/"3"`, fiber.StatusNotModified},
+		{`"1", "3"`, fiber.StatusNotModified},
+		{"*", fiber.StatusNotModified},
+	}
+
+	for _, tc := range cases {
+		// Make request
+		req, _ := http.NewRequest("GET", "/entities/1", nil)
+		if tc.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", tc.ifNoneMatch)
+		}
+
+		// Perform request
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+
+		// Check status code and entity tag
+		if resp.StatusCode != tc.status {
+			t.Errorf("If-None-Match %s: expected status code %d, got %d", tc.ifNoneMatch, tc.status, resp.StatusCode)
+		}
+
+		if etag := resp.Header.Get("ETag"); etag != `"3"` {
+			t.Errorf("Expected ETag \"3\", got %s", etag)
+		}
+	}
+}
+
+func TestUpdateEntityFiber_IfMatch(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Put("/entities/:id", entityHandler.UpdateEntityFiber)
+
+	// Set up the mock expectations
+	entityReq := &models.EntityRequest{Name: "Updated Name"}
+	mockService.On("UpdateEntity", mock.Anything, 1, entityReq, 2).Return(&models.Entity{ID: 1, Name: "Updated Name", Version: 3}, nil)
+	mockService.On("UpdateEntity", mock.Anything, 1, entityReq, 1).Return(nil, errors.New(errors.CodePreconditionFailed))
+
+	body, _ := json.Marshal(entityReq)
+
+	cases := []struct {
+		ifMatch string
+		status  int
+		etag    string
+	}{
+		{`"2"`, fiber.StatusOK, `"3"`},
+		{`"1"`, fiber.StatusPreconditionFailed, ""},
+		{`W/"2"`, fiber.StatusPreconditionFailed, ""},
+		{`"0"`, fiber.StatusPreconditionFailed, ""},
+	}
+
+	for _, tc := range cases {
+		// Make request
+		req, _ := http.NewRequest("PUT", "/entities/1", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", tc.ifMatch)
+
+		// Perform request
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+
+		// Check status code and entity tag
+		if resp.StatusCode != tc.status {
+			t.Errorf("If-Match %s: expected status code %d, got %d", tc.ifMatch, tc.status, resp.StatusCode)
+		}
+
+		if etag := resp.Header.Get("ETag"); etag != tc.etag {
+			t.Errorf("If-Match %s: expected ETag %q, got %q", tc.ifMatch, tc.etag, etag)
+		}
+	}
+
+	// Verify mock was called
+	mockService.AssertExpectations(t)
+}
+
+func TestDeleteEntityFiber_IfMatch(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Delete("/entities/:id", entityHandler.DeleteEntityFiber)
+
+	// Set up the mock expectations; with several tags the current version is looked up on the primary
+	mockService.On("GetEntityByID", mock.MatchedBy(repository.ReadsFromPrimary), 1).Return(&models.Entity{ID: 1, Version: 4}, nil)
+	mockService.On("DeleteEntity", mock.Anything, 1, 4).Return(nil)
+
+	cases := []struct {
+		ifMatch string
+		status  int
+	}{
+		{`"3", "4"`, fiber.StatusNoContent},
+		{`"0"`, fiber.StatusPreconditionFailed},
+	}
+
+	for _, tc := range cases {
+		// Make request
+		req, _ := http.NewRequest("DELETE", "/entities/1", nil)
+		req.Header.Set("If-Match", tc.ifMatch)
+
+		// Perform request
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+
+		// Check status code
+		if resp.StatusCode != tc.status {
+			t.Errorf("If-Match %s: expected status code %d, got %d", tc.ifMatch, tc.status, resp.StatusCode)
+		}
+	}
+
+	// Verify mock was called
+	mockService.AssertExpectations(t)
+}
+
+func TestPatchEntityFiber(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Patch("/entities/:id", entityHandler.PatchEntityFiber)
+
+	// Set up the mock expectation
+	document := []byte(`{"name":"Patched Name"}`)
+	patch := &models.EntityPatch{ContentType: models.MergePatchContentType, Document: document}
+	mockService.On("PatchEntity", mock.Anything, 1, patch, 2).Return(&models.Entity{ID: 1, Name: "Patched Name", Version: 3}, nil)
+
+	// Make request
+	req, _ := http.NewRequest("PATCH", "/entities/1", bytes.NewBuffer(document))
+	req.Header.Set("Content-Type", "application/merge-patch+json; charset=utf-8")
+	req.Header.Set("If-Match", `"2"`)
+
+	// Perform request
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	// Check status code and entity tag
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected status code %d, got %d", fiber.StatusOK, resp.StatusCode)
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != `"3"` {
+		t.Errorf("Expected ETag %q, got %q", `"3"`, etag)
+	}
+
+	// Assert that the mock expectations were met
+	mockService.AssertExpectations(t)
+}
+
+func TestPatchEntityFiber_UnsupportedMediaType(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Patch("/entities/:id", entityHandler.PatchEntityFiber)
+
+	for _, contentType := range []string{"application/json", "text/plain", ""} {
+		// Make request
+		req, _ := http.NewRequest("PATCH", "/entities/1", bytes.NewBufferString(`{"name":"Patched Name"}`))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		// Perform request
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+
+		// Check status code
+		if resp.StatusCode != fiber.StatusUnsupportedMediaType {
+			t.Errorf("Content-Type %q: expected status code %d, got %d", contentType, fiber.StatusUnsupportedMediaType, resp.StatusCode)
+		}
+	}
+
+	// The service is never reached
+	mockService.AssertNotCalled(t, "PatchEntity", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPatchEntityFiber_Conflict(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Patch("/entities/:id", entityHandler.PatchEntityFiber)
+
+	// Set up the mock expectation; the patch's test operation does not hold
+	document := []byte(`[{"op":"test","path":"/name","value":"Old Name"},{"op":"replace","path":"/name","value":"New Name"}]`)
+	patch := &models.EntityPatch{ContentType: models.JSONPatchContentType, Document: document}
+	mockService.On("PatchEntity", mock.Anything, 1, patch, 0).Return(nil, errors.New(errors.CodePatchConflict))
+
+	// Make request
+	req, _ := http.NewRequest("PATCH", "/entities/1", bytes.NewBuffer(document))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+
+	// Perform request
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	// Check status code
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", fiber.StatusConflict, resp.StatusCode)
+	}
+
+	// Assert that the mock expectations were met
+	mockService.AssertExpectations(t)
+}
+
+func TestBatchCreateEntitiesFiber(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Post("/entities\\:batch", entityHandler.BatchCreateEntitiesFiber)
+
+	// Set up the mock expectations; one best-effort batch partially fails, one atomic batch is rolled back
+	invalid := errors.New(errors.CodeValidationFailed).WithFieldErrors(errors.FieldError{Field: "name", Message: "Name is required", Code: "required"})
+	bestEffort := &models.BatchCreateRequest{Items: []models.EntityRequest{{Name: "First"}, {Name: ""}}}
+	mockService.On("CreateEntities", mock.Anything, bestEffort).Return(&models.BatchResult{
+		Succeeded: 1,
+		Failed:    1,
+		Results: []models.BatchItemResult{
+			{Index: 0, Status: fiber.StatusCreated, Data: &models.Entity{ID: 1, Name: "First", Version: 1}},
+			{Index: 1, Status: fiber.StatusBadRequest, Error: invalid},
+		},
+	}, nil)
+
+	atomic := &models.BatchCreateRequest{Atomic: true, Items: []models.EntityRequest{{Name: "First"}, {Name: ""}}}
+	mockService.On("CreateEntities", mock.Anything, atomic).Return(&models.BatchResult{
+		Atomic: true,
+		Failed: 2,
+		Results: []models.BatchItemResult{
+			{Index: 0, Status: errors.New(errors.CodeBatchAborted).Status(), Error: errors.New(errors.CodeBatchAborted)},
+			{Index: 1, Status: fiber.StatusBadRequest, Error: invalid},
+		},
+	}, nil)
+
+	cases := []struct {
+		body   string
+		status int
+		failed float64
+		codes  []string
+	}{
+		{`{"items":[{"name":"First"},{"name":""}]}`, fiber.StatusMultiStatus, 1, []string{"", "VALIDATION_FAILED"}},
+		{`{"atomic":true,"items":[{"name":"First"},{"name":""}]}`, fiber.StatusBadRequest, 2, []string{"BATCH_ABORTED", "VALIDATION_FAILED"}},
+	}
+
+	for _, tc := range cases {
+		// Make request
+		req, _ := http.NewRequest("POST", "/entities:batch", bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+
+		// Perform request
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+
+		// Check status code
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s: expected status code %d, got %d", tc.body, tc.status, resp.StatusCode)
+		}
+
+		// Check response body
+		var response struct {
+			Failed float64 `json:"failed"`
+			Data   []struct {
+				Error *struct {
+					Code   string              `json:"code"`
+					Status int                 `json:"status"`
+					Errors []map[string]string `json:"errors"`
+				} `json:"error"`
+			} `json:"data"`
+		}
+		json.NewDecoder(resp.Body).Decode(&response)
+
+		if response.Failed != tc.failed {
+			t.Errorf("%s: expected %v failed items, got %v", tc.body, tc.failed, response.Failed)
+		}
+
+		if len(response.Data) != len(tc.codes) {
+			t.Fatalf("%s: expected %d item results, got %d", tc.body, len(tc.codes), len(response.Data))
+		}
+
+		// Each failed item reports its catalog code, and an invalid one its fields
+		for i, code := range tc.codes {
+			itemErr := response.Data[i].Error
+			if code == "" {
+				if itemErr != nil {
+					t.Errorf("%s: expected item %d to succeed, got %+v", tc.body, i, itemErr)
+				}
+				continue
+			}
+			if itemErr == nil || itemErr.Code != code {
+				t.Errorf("%s: expected item %d to fail with %s, got %+v", tc.body, i, code, itemErr)
+			}
+		}
+
+		fields := response.Data[1].Error.Errors
+		if len(fields) != 1 || fields[0]["field"] != "name" || fields[0]["code"] != "required" {
+			t.Errorf("%s: expected the name field to be reported, got %v", tc.body, fields)
+		}
+	}
+
+	// Assert that the mock expectations were met
+	mockService.AssertExpectations(t)
+}
+
+func TestBatchEntitiesFiber_InvalidSize(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Post("/entities\\:batch", entityHandler.BatchCreateEntitiesFiber)
+	app.Put("/entities\\:batch", entityHandler.BatchUpdateEntitiesFiber)
+	app.Delete("/entities\\:batch", entityHandler.BatchDeleteEntitiesFiber)
+
+	items := make([]models.BatchDeleteItem, models.MaxBatchSize+1)
+	oversized, _ := json.Marshal(models.BatchDeleteRequest{Items: items})
+
+	cases := []struct {
+		method string
+		body   string
+	}{
+		{"POST", `{"items":[]}`},
+		{"PUT", `{"atomic":true}`},
+		{"DELETE", string(oversized)},
+		{"POST", `{"items":`},
+	}
+
+	for _, tc := range cases {
+		// Make request
+		req, _ := http.NewRequest(tc.method, "/entities:batch", bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+
+		// Perform request
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+
+		// Check status code
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("%s batch: expected status code %d, got %d", tc.method, fiber.StatusBadRequest, resp.StatusCode)
+		}
+	}
+
+	// The service is never reached
+	mockService.AssertNotCalled(t, "CreateEntities", mock.Anything, mock.Anything)
+	mockService.AssertNotCalled(t, "UpdateEntities", mock.Anything, mock.Anything)
+	mockService.AssertNotCalled(t, "DeleteEntities", mock.Anything, mock.Anything)
+}
+
+func TestGetEntityByIDFiber_Canceled(t *testing.T) {
+	// Create a mock service
+	mockService := &mocks.EntityServiceMock{}
+
+	// Create handler with mock service
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	// Create Fiber app for testing
+	app := fiber.New()
+	app.Get("/entities/:id", entityHandler.GetEntityByIDFiber)
+
+	// Set up the mock expectation; the client went away while the query ran
+	mockService.On("GetEntityByID", mock.Anything, 1).Return(nil, context.Canceled)
+
+	// Make request
+	req, _ := http.NewRequest("GET", "/entities/1", nil)
+
+	// Perform request
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	// Check status code
+	if resp.StatusCode != errors.StatusClientClosedRequest {
+		t.Errorf("Expected status code %d, got %d", errors.StatusClientClosedRequest, resp.StatusCode)
+	}
+
+	// Assert that the mock expectations were met
+	mockService.AssertExpectations(t)
+}
+
+func TestGetEntityByIDFiber_ProblemDetails(t *testing.T) {
+	tests := []struct {
+		name    string
+		accept  string
+		problem bool
+	}{
+		{name: "no accept header", accept: ""},
+		{name: "json", accept: "application/json"},
+		{name: "anything", accept: "*/*"},
+		{name: "problem", accept: "application/problem+json", problem: true},
+		{name: "problem preferred", accept: "application/json;q=0.5, application/problem+json", problem: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mocks.EntityServiceMock{}
+			entityHandler := handlers.NewEntityHandler(mockService)
+
+			app := fiber.New()
+			app.Get("/entities/:id", entityHandler.GetEntityByIDFiber)
+
+			mockService.On("GetEntityByID", mock.Anything, 999).Return(nil, errors.New(errors.CodeEntityNotFound))
+
+			req, _ := http.NewRequest("GET", "/entities/999", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to perform request: %v", err)
+			}
+
+			if resp.StatusCode != fiber.StatusNotFound {
+				t.Errorf("Expected status code %d, got %d", fiber.StatusNotFound, resp.StatusCode)
+			}
+
+			var body map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if !tt.problem {
+				// Existing clients keep the error envelope
+				if resp.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("Expected application/json, got %q", resp.Header.Get("Content-Type"))
+				}
+				if _, ok := body["error"]; !ok {
+					t.Errorf("Expected an error envelope, got %v", body)
+				}
+				return
+			}
+
+			if resp.Header.Get("Content-Type") != errors.ProblemContentType {
+				t.Errorf("Expected %s, got %q", errors.ProblemContentType, resp.Header.Get("Content-Type"))
+			}
+			expected := map[string]interface{}{
+				"type":     "/problems/entity-not-found",
+				"title":    "Entity not found",
+				"status":   float64(fiber.StatusNotFound),
+				"detail":   "The requested entity could not be found",
+				"instance": "/entities/999",
+				"code":     "ENTITY_NOT_FOUND",
+			}
+			for key, value := range expected {
+				if body[key] != value {
+					t.Errorf("Expected %s %v, got %v", key, value, body[key])
+				}
+			}
+		})
+	}
+}
+
+func TestCreateEntityFiber_ValidationProblem(t *testing.T) {
+	mockService := &mocks.EntityServiceMock{}
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	app := fiber.New()
+	app.Post("/entities", entityHandler.CreateEntityFiber)
+
+	body, _ := json.Marshal(&models.EntityRequest{Name: ""})
+	req, _ := http.NewRequest("POST", "/entities", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", errors.ProblemContentType)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", fiber.StatusBadRequest, resp.StatusCode)
+	}
+
+	var problem errors.Problem
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if problem.Code != errors.CodeValidationFailed {
+		t.Errorf("Expected code %s, got %q", errors.CodeValidationFailed, problem.Code)
+	}
+
+	// Each invalid field is reported on its own
+	expected := []errors.FieldError{{Field: "name", Message: "Name is required", Code: "required"}}
+	if !reflect.DeepEqual(problem.Errors, expected) {
+		t.Errorf("Expected errors %v, got %v", expected, problem.Errors)
+	}
+
+	mockService.AssertNotCalled(t, "CreateEntity", mock.Anything, mock.Anything)
+}
+
+func TestCreateEntityFiber_LocalizedValidation(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		language       string
+		title          string
+		message        string
+	}{
+		{
+			name:           "thai",
+			acceptLanguage: "th-TH,th;q=0.9,en;q=0.8",
+			language:       "th",
+			title:          "ข้อมูลไม่ผ่านการตรวจสอบ",
+			message:        "ต้องระบุ name",
+		},
+		{
+			name:           "unsupported falls back to english",
+			acceptLanguage: "fr-FR",
+			language:       "en",
+			title:          "Validation failed",
+			message:        "Name is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mocks.EntityServiceMock{}
+			entityHandler := handlers.NewEntityHandler(mockService)
+
+			app := fiber.New()
+			app.Post("/entities", entityHandler.CreateEntityFiber)
+
+			body, _ := json.Marshal(&models.EntityRequest{Name: ""})
+			req, _ := http.NewRequest("POST", "/entities", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", errors.ProblemContentType)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to perform request: %v", err)
+			}
+
+			if resp.Header.Get("Content-Language") != tt.language {
+				t.Errorf("Expected Content-Language %s, got %q", tt.language, resp.Header.Get("Content-Language"))
+			}
+			if !strings.Contains(resp.Header.Get("Vary"), "Accept-Language") {
+				t.Errorf("Expected Vary to name Accept-Language, got %q", resp.Header.Get("Vary"))
+			}
+
+			var problem errors.Problem
+			if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if problem.Title != tt.title {
+				t.Errorf("Expected title %q, got %q", tt.title, problem.Title)
+			}
+			if len(problem.Errors) != 1 || problem.Errors[0].Message != tt.message {
+				t.Fatalf("Expected the message %q, got %v", tt.message, problem.Errors)
+			}
+			// The code stays the same in every language
+			if problem.Errors[0].Code != "required" {
+				t.Errorf("Expected code required, got %q", problem.Errors[0].Code)
+			}
+			if problem.Detail != "name: "+tt.message {
+				t.Errorf("Expected the detail to repeat the message, got %q", problem.Detail)
+			}
+		})
+	}
+}
+
+func TestUpdateEntityFiber_SerializationFailure(t *testing.T) {
+	mockService := &mocks.EntityServiceMock{}
+	entityHandler := handlers.NewEntityHandler(mockService)
+
+	app := fiber.New()
+	app.Put("/entities/:id", entityHandler.UpdateEntityFiber)
+
+	entityReq := &models.EntityRequest{Name: "Updated Entity"}
+	mockService.On("UpdateEntity", mock.Anything, 1, entityReq, 0).Return(nil, &pq.Error{Code: "40001"})
+
+	body, _ := json.Marshal(entityReq)
+	req, _ := http.NewRequest("PUT", "/entities/1", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+
+	// A concurrent transaction won; the client may simply retry
+	if resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", fiber.StatusServiceUnavailable, resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", resp.Header.Get("Retry-After"))
+	}
+
+	mockService.AssertExpectations(t)
+}
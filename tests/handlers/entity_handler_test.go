@@ -8,9 +8,12 @@ import (
 	"testing"
 
 	"learn-api/internal/handlers"
+	"learn-api/internal/logging"
 	"learn-api/internal/models"
 	"learn-api/internal/services/mocks"
 	"learn-api/pkg/errors"
+
+	"github.com/stretchr/testify/mock"
 )
 
 func TestCreateEntity(t *testing.T) {
@@ -40,7 +43,7 @@ func TestCreateEntity(t *testing.T) {
 		ID:   1,
 		Name: "Test Entity",
 	}
-	mockService.On("CreateEntity", &entityReq).Return(expectedEntity, nil)
+	mockService.On("CreateEntity", mock.Anything, &entityReq).Return(expectedEntity, nil)
 
 	// Call the handler
 	entityHandler.CreateEntity(rr, req)
@@ -96,7 +99,7 @@ func TestGetEntityByID(t *testing.T) {
 		ID:   1,
 		Name: "Test Entity",
 	}
-	mockService.On("GetEntityByID", 1).Return(expectedEntity, nil)
+	mockService.On("GetEntityByID", mock.Anything, 1).Return(expectedEntity, nil)
 
 	// Call the handler with a request that has the ID in the path
 	req.URL.Path = "/api/v1/entities/1"
@@ -153,7 +156,8 @@ func TestGetAllEntities(t *testing.T) {
 		{ID: 1, Name: "Entity 1"},
 		{ID: 2, Name: "Entity 2"},
 	}
-	mockService.On("GetAllEntities").Return(expectedEntities, nil)
+	expectedParams := models.ListParams{Limit: models.DefaultPageLimit}
+	mockService.On("GetAllEntities", mock.Anything, expectedParams).Return(&models.EntityPage{Entities: expectedEntities, Total: 2}, nil)
 
 	// Call the handler
 	entityHandler.GetAllEntities(rr, req)
@@ -220,7 +224,7 @@ func TestUpdateEntity(t *testing.T) {
 		ID:   1,
 		Name: "Updated Entity",
 	}
-	mockService.On("UpdateEntity", 1, &entityReq).Return(expectedEntity, nil)
+	mockService.On("UpdateEntity", mock.Anything, 1, &entityReq, 0).Return(expectedEntity, nil)
 
 	// Call the handler with a request that has the ID in the path
 	req.URL.Path = "/api/v1/entities/1"
@@ -273,7 +277,7 @@ func TestDeleteEntity(t *testing.T) {
 	rr := httptest.NewRecorder()
 
 	// Set up the mock expectation
-	mockService.On("DeleteEntity", 1).Return(nil)
+	mockService.On("DeleteEntity", mock.Anything, 1, 0).Return(nil)
 
 	// Call the handler with a request that has the ID in the path
 	req.URL.Path = "/api/v1/entities/1"
@@ -306,7 +310,7 @@ func TestGetEntityByID_NotFound(t *testing.T) {
 	rr := httptest.NewRecorder()
 
 	// Set up the mock expectation for not found
-	mockService.On("GetEntityByID", 999).Return(nil, errors.ErrEntityNotFound)
+	mockService.On("GetEntityByID", mock.Anything, 999).Return(nil, errors.New(errors.CodeEntityNotFound))
 
 	// Call the handler with a request that has the ID in the path
 	req.URL.Path = "/api/v1/entities/999"
@@ -321,3 +325,83 @@ func TestGetEntityByID_NotFound(t *testing.T) {
 	// Verify mock was called
 	mockService.AssertExpectations(t)
 }
+
+func TestGetEntityByID_ErrorFormats(t *testing.T) {
+	tests := []struct {
+		name           string
+		accept         string
+		acceptLanguage string
+		contentType    string
+		language       string
+		title          string
+	}{
+		{name: "envelope by default", contentType: "application/json", language: "en", title: "Entity not found"},
+		{
+			name:           "problem details in thai",
+			accept:         errors.ProblemContentType,
+			acceptLanguage: "th-TH",
+			contentType:    errors.ProblemContentType,
+			language:       "th",
+			title:          "ไม่พบเอนทิตี",
+		},
+		{
+			name:        "json preferred",
+			accept:      "application/json, application/problem+json;q=0.5",
+			contentType: "application/json",
+			language:    "en",
+			title:       "Entity not found",
+		},
+		{
+			name:        "problem preferred over wildcard",
+			accept:      "*/*;q=0.1, application/problem+json",
+			contentType: errors.ProblemContentType,
+			language:    "en",
+			title:       "Entity not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mocks.EntityServiceMock{}
+			mockService.On("GetEntityByID", mock.Anything, 999).Return(nil, errors.New(errors.CodeEntityNotFound))
+			entityHandler := handlers.NewEntityHandler(mockService)
+			handler := logging.HTTPMiddleware(http.HandlerFunc(entityHandler.GetEntityByID))
+
+			req := httptest.NewRequest("GET", "/api/v1/entities/999", nil)
+			req.Header.Set(logging.HeaderRequestID, "req-1")
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusNotFound {
+				t.Errorf("Expected status %d, got %d", http.StatusNotFound, rr.Code)
+			}
+			if got := rr.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Expected Content-Type %s, got %q", tt.contentType, got)
+			}
+			if got := rr.Header().Get("Content-Language"); got != tt.language {
+				t.Errorf("Expected Content-Language %s, got %q", tt.language, got)
+			}
+			if got := rr.Header().Values("Vary"); len(got) != 2 {
+				t.Errorf("Expected Vary to name Accept and Accept-Language, got %v", got)
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			title, requestID := body["title"], body["request_id"]
+			if envelope, ok := body["error"].(map[string]interface{}); ok {
+				title, requestID = envelope["message"], envelope["request_id"]
+			}
+			if title != tt.title || requestID != "req-1" {
+				t.Errorf("Expected %q for req-1, got %v", tt.title, body)
+			}
+		})
+	}
+}
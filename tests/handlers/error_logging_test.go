@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"context"
+	stderrors "errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/mock"
+
+	"learn-api/internal/handlers"
+	"learn-api/internal/services/mocks"
+)
+
+// logRecord is a log line captured by recordingHandler
+type logRecord struct {
+	level slog.Level
+	msg   string
+	attrs map[string]slog.Value
+}
+
+// recordingHandler is a slog.Handler keeping every record it handles
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []logRecord
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	record := logRecord{level: r.Level, msg: r.Message, attrs: map[string]slog.Value{}}
+	r.Attrs(func(attr slog.Attr) bool {
+		record.attrs[attr.Key] = attr.Value
+		return true
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, record)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *recordingHandler) WithGroup(string) slog.Handler { return h }
+
+// recordLogs makes a recordingHandler the default logger for the test
+func recordLogs(t *testing.T) *recordingHandler {
+	t.Helper()
+
+	handler := &recordingHandler{}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(handler))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return handler
+}
+
+func TestHandleError_Logging(t *testing.T) {
+	cause := stderrors.New("connection reset by peer")
+
+	tests := []struct {
+		name     string
+		err      error
+		status   int
+		level    slog.Level
+		msg      string
+		sqlstate string
+	}{
+		{
+			name:     "unique violation",
+			err:      &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"},
+			status:   http.StatusConflict,
+			level:    slog.LevelWarn,
+			msg:      "Request rejected by the database",
+			sqlstate: "23505",
+		},
+		{
+			name:   "server error",
+			err:    cause,
+			status: http.StatusInternalServerError,
+			level:  slog.LevelError,
+			msg:    "Request failed",
+		},
+	}
+
+	// Both the net/http and the Fiber handlers log the cause
+	transports := map[string]func(t *testing.T, mockService *mocks.EntityServiceMock) int{
+		"net/http": func(t *testing.T, mockService *mocks.EntityServiceMock) int {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/entities/1", nil)
+			handlers.NewEntityHandler(mockService).GetEntityByID(rr, req)
+			return rr.Code
+		},
+		"fiber": func(t *testing.T, mockService *mocks.EntityServiceMock) int {
+			app := fiber.New()
+			app.Get("/entities/:id", handlers.NewEntityHandler(mockService).GetEntityByIDFiber)
+
+			req, _ := http.NewRequest("GET", "/entities/1", nil)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to perform request: %v", err)
+			}
+			return resp.StatusCode
+		},
+	}
+
+	for _, tt := range tests {
+		for transport, serve := range transports {
+			t.Run(tt.name+" over "+transport, func(t *testing.T) {
+				logs := recordLogs(t)
+
+				mockService := &mocks.EntityServiceMock{}
+				mockService.On("GetEntityByID", mock.Anything, 1).Return(nil, tt.err)
+
+				if status := serve(t, mockService); status != tt.status {
+					t.Errorf("Expected status %d, got %d", tt.status, status)
+				}
+
+				if len(logs.records) != 1 {
+					t.Fatalf("Expected one log record, got %+v", logs.records)
+				}
+				record := logs.records[0]
+				if record.level != tt.level || record.msg != tt.msg {
+					t.Errorf("Expected %s %q, got %s %q", tt.level, tt.msg, record.level, record.msg)
+				}
+
+				logged, _ := record.attrs["error"].Any().(error)
+				if !stderrors.Is(logged, tt.err) {
+					t.Errorf("Expected the cause %v to be logged, got %v", tt.err, record.attrs["error"])
+				}
+				if got := record.attrs["sqlstate"].String(); tt.sqlstate != "" && got != tt.sqlstate {
+					t.Errorf("Expected sqlstate %s, got %q", tt.sqlstate, got)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package health_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"learn-api/internal/database"
+	"learn-api/internal/database/migrate"
+	"learn-api/internal/health"
+
+	_ "github.com/lib/pq"
+)
+
+// pendingMigrator reports fixed pending migrations
+type pendingMigrator struct {
+	migrate.Migrator
+	pending []migrate.Migration
+	err     error
+}
+
+func (m pendingMigrator) Pending(ctx context.Context) ([]migrate.Migration, error) {
+	return m.pending, m.err
+}
+
+func up(ctx context.Context) (interface{}, error) {
+	return map[string]int{"answer": 42}, nil
+}
+
+func TestRegistry_Empty(t *testing.T) {
+	report := health.NewRegistry().Check(context.Background())
+
+	if !report.Up() || len(report.Checks) != 0 {
+		t.Errorf("Expected an empty registry to be up, got %+v", report)
+	}
+}
+
+func TestRegistry_Check(t *testing.T) {
+	registry := health.NewRegistry()
+	registry.Register("cache", health.CheckerFunc(up))
+	registry.Register("queue", health.CheckerFunc(func(ctx context.Context) (interface{}, error) {
+		return "backlog 3", errors.New("queue unreachable")
+	}))
+
+	report := registry.Check(context.Background())
+	if report.Up() || report.Status != health.StatusDown {
+		t.Errorf("Expected the report to be down, got %s", report.Status)
+	}
+
+	cache := report.Checks["cache"]
+	if cache.Status != health.StatusUp || cache.Error != "" || !reflect.DeepEqual(cache.Details, map[string]int{"answer": 42}) {
+		t.Errorf("Unexpected cache result: %+v", cache)
+	}
+
+	// Details are reported for failing checks too
+	queue := report.Checks["queue"]
+	if queue.Status != health.StatusDown || queue.Error != "queue unreachable" || queue.Details != "backlog 3" {
+		t.Errorf("Unexpected queue result: %+v", queue)
+	}
+
+	if !reflect.DeepEqual(registry.Names(), []string{"cache", "queue"}) {
+		t.Errorf("Expected sorted names, got %v", registry.Names())
+	}
+}
+
+func TestRegistry_RegisterReplaces(t *testing.T) {
+	registry := health.NewRegistry()
+	registry.Register("cache", health.CheckerFunc(func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("down")
+	}))
+	registry.Register("cache", health.CheckerFunc(up))
+
+	if report := registry.Check(context.Background()); !report.Up() || len(report.Checks) != 1 {
+		t.Errorf("Expected the replacement check only, got %+v", report)
+	}
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	registry := health.NewRegistry(health.WithTimeout(20 * time.Millisecond))
+	release := make(chan struct{})
+	defer close(release)
+	registry.Register("stuck", health.CheckerFunc(func(ctx context.Context) (interface{}, error) {
+		<-release // ignores its context
+		return nil, nil
+	}))
+
+	start := time.Now()
+	report := registry.Check(context.Background())
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the check to be abandoned after the timeout, took %s", elapsed)
+	}
+
+	if result := report.Checks["stuck"]; result.Status != health.StatusDown || result.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("Expected a timed out check, got %+v", result)
+	}
+}
+
+func TestDatabaseChecker_Unavailable(t *testing.T) {
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	pool := database.NewPool(db)
+	defer pool.Close()
+
+	details, err := health.NewDatabaseChecker(pool).Check(context.Background())
+	if err == nil {
+		t.Error("Expected an unreachable database to fail the check")
+	}
+
+	stats, ok := details.(health.PoolStats)
+	if !ok || stats.Replicas != 0 || stats.WaitDuration == "" {
+		t.Errorf("Expected pool statistics, got %#v", details)
+	}
+}
+
+func TestMigrationChecker(t *testing.T) {
+	migrations := []migrate.Migration{{Version: 1, Name: "a"}, {Version: 2, Name: "b"}}
+
+	details, err := health.NewMigrationChecker(pendingMigrator{}, migrations).Check(context.Background())
+	if err != nil {
+		t.Errorf("Expected a migrated schema to pass, got %v", err)
+	}
+	if !reflect.DeepEqual(details, health.MigrationStats{Latest: 2, Pending: []int{}}) {
+		t.Errorf("Unexpected details: %#v", details)
+	}
+
+	details, err = health.NewMigrationChecker(pendingMigrator{pending: migrations[1:]}, migrations).Check(context.Background())
+	if err == nil {
+		t.Error("Expected pending migrations to fail the check")
+	}
+	if !reflect.DeepEqual(details, health.MigrationStats{Latest: 2, Pending: []int{2}}) {
+		t.Errorf("Unexpected details: %#v", details)
+	}
+
+	if _, err := health.NewMigrationChecker(pendingMigrator{err: sql.ErrConnDone}, migrations).Check(context.Background()); !errors.Is(err, sql.ErrConnDone) {
+		t.Errorf("Expected the migrator's error, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package httpx_test
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"learn-api/internal/httpx"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// probe records what the helpers report about each request it wraps
+type probe struct {
+	status int
+	route  string
+	ok     bool
+}
+
+// middleware captures the response status and matched route of every request
+func (p *probe) middleware(c *fiber.Ctx) error {
+	own := c.Route()
+	err := c.Next()
+	p.status = httpx.ResponseStatus(c, err)
+	p.route, p.ok = httpx.MatchedRoute(c, own)
+	return err
+}
+
+func TestResponseStatusAndMatchedRoute(t *testing.T) {
+	p := &probe{}
+	app := fiber.New()
+	app.Use(p.middleware)
+	app.Get("/ok/:id", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusAccepted) })
+	app.Get("/fiber-error", func(c *fiber.Ctx) error { return fmt.Errorf("wrapped: %w", fiber.ErrConflict) })
+	app.Get("/error", func(c *fiber.Ctx) error { return stderrors.New("boom") })
+
+	tests := []struct {
+		path   string
+		status int
+		route  string
+		ok     bool
+	}{
+		{path: "/ok/1", status: fiber.StatusAccepted, route: "/ok/:id", ok: true},
+		{path: "/fiber-error", status: fiber.StatusConflict, route: "/fiber-error", ok: true},
+		{path: "/error", status: fiber.StatusInternalServerError, route: "/error", ok: true},
+		{path: "/missing", status: fiber.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		if _, err := app.Test(req); err != nil {
+			t.Fatalf("Failed to perform request: %v", err)
+		}
+
+		if p.status != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, p.status)
+		}
+		if p.route != tt.route || p.ok != tt.ok {
+			t.Errorf("%s: expected route %q (%v), got %q (%v)", tt.path, tt.route, tt.ok, p.route, p.ok)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package i18n_test
+
+import (
+	"testing"
+
+	"learn-api/pkg/i18n"
+	"learn-api/pkg/validation"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   i18n.Language
+	}{
+		{name: "missing", header: "", want: i18n.English},
+		{name: "exact", header: "th", want: i18n.Thai},
+		{name: "region", header: "th-TH", want: i18n.Thai},
+		{name: "case", header: "TH-th", want: i18n.Thai},
+		{name: "quality", header: "en;q=0.5, th;q=0.8", want: i18n.Thai},
+		{name: "order breaks ties", header: "en, th", want: i18n.English},
+		{name: "unsupported skipped", header: "fr-FR, th;q=0.7", want: i18n.Thai},
+		{name: "unsupported only", header: "fr-FR, de", want: i18n.English},
+		{name: "refused", header: "th;q=0", want: i18n.English},
+		{name: "wildcard", header: "*", want: i18n.English},
+		{name: "malformed quality", header: "th;q=high, en;q=0.1", want: i18n.English},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := i18n.Negotiate(tt.header); got != tt.want {
+				t.Errorf("Negotiate(%q) = %s, want %s", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalog_Format(t *testing.T) {
+	catalog := i18n.Catalog{
+		i18n.English: {"too_long": "Must be less than {max} characters", "english_only": "Only in English"},
+		i18n.Thai:    {"too_long": "ต้องมีความยาวน้อยกว่า {max} ตัวอักษร"},
+	}
+
+	tests := []struct {
+		name string
+		lang i18n.Language
+		code string
+		want string
+		ok   bool
+	}{
+		{name: "translated", lang: i18n.Thai, code: "too_long", want: "ต้องมีความยาวน้อยกว่า 255 ตัวอักษร", ok: true},
+		{name: "default", lang: i18n.English, code: "too_long", want: "Must be less than 255 characters", ok: true},
+		{name: "fallback", lang: i18n.Thai, code: "english_only", want: "Only in English", ok: true},
+		{name: "unknown", lang: i18n.Thai, code: "missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := catalog.Format(tt.lang, tt.code, map[string]string{"max": "255"})
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Format() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidationLocalize(t *testing.T) {
+	validationErrors := validation.ValidateListParams(0, -1, 100, "", "")
+	if validationErrors[0].Message != "Limit must be between 1 and 100" {
+		t.Fatalf("Expected the English message by default, got %q", validationErrors[0].Message)
+	}
+
+	apiErr := validation.Localize(validation.ToAPIError(validationErrors), i18n.Thai)
+
+	want := []string{"limit ต้องอยู่ระหว่าง 1 ถึง 100", "offset ต้องไม่ติดลบ"}
+	fields := apiErr.FieldErrors()
+	if len(fields) != len(want) {
+		t.Fatalf("Expected %d invalid fields, got %v", len(want), fields)
+	}
+	for i, message := range want {
+		if fields[i].Message != message {
+			t.Errorf("Expected %q, got %q", message, fields[i].Message)
+		}
+	}
+	if apiErr.Details() != "limit: "+want[0]+"; offset: "+want[1] {
+		t.Errorf("Expected the details in Thai, got %q", apiErr.Details())
+	}
+}
@@ -0,0 +1,262 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"learn-api/internal/app"
+	"learn-api/internal/logging"
+	"learn-api/internal/services/mocks"
+	"learn-api/pkg/errors"
+	"learn-api/pkg/validation"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// captureLogs makes the default logger write JSON lines to the returned buffer
+// for the rest of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.Config{Level: "debug", Format: logging.FormatJSON})
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// logLines decodes the JSON log lines in buf
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Fatalf("Expected a JSON log line, got %q: %v", line, err)
+		}
+		lines = append(lines, fields)
+	}
+	return lines
+}
+
+func TestMiddleware_RequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		keep     bool
+		generate bool
+	}{
+		{name: "from client", header: "abc-123", keep: true},
+		{name: "missing", generate: true},
+		{name: "not printable", header: "abc\tdef", generate: true},
+		{name: "too long", header: strings.Repeat("a", 129), generate: true},
+	}
+	notFound := errors.New(errors.CodeEntityNotFound)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+
+			mockService := &mocks.EntityServiceMock{}
+			mockService.On("GetEntityByID", mock.Anything, 1).Return(nil, notFound)
+			fiberApp := app.NewFiberApp(mockService)
+
+			req, _ := http.NewRequest("GET", "/api/v1/entities/1", nil)
+			if tt.header != "" {
+				req.Header.Set(logging.HeaderRequestID, tt.header)
+			}
+			resp, err := fiberApp.Test(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			id := resp.Header.Get(logging.HeaderRequestID)
+			if tt.keep && id != tt.header {
+				t.Errorf("Expected the client's request ID %q, got %q", tt.header, id)
+			}
+			if tt.generate {
+				if _, err := uuid.Parse(id); err != nil {
+					t.Errorf("Expected a generated UUID, got %q", id)
+				}
+			}
+
+			// The error body names the request for support tickets
+			body, _ := io.ReadAll(resp.Body)
+			var payload struct {
+				Error struct {
+					RequestID string `json:"request_id"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(body, &payload); err != nil {
+				t.Fatalf("Error decoding body %s: %v", body, err)
+			}
+			if payload.Error.RequestID != id {
+				t.Errorf("Expected request_id %q in the error body, got %q", id, payload.Error.RequestID)
+			}
+
+			// The access log line carries it too
+			lines := logLines(t, logs)
+			if len(lines) != 1 {
+				t.Fatalf("Expected one access log line, got %d", len(lines))
+			}
+			line := lines[0]
+			if line["request_id"] != id {
+				t.Errorf("Expected request_id %q in the log line, got %v", id, line["request_id"])
+			}
+			if line["route"] != "/api/v1/entities/:id" || line["status"] != float64(http.StatusNotFound) {
+				t.Errorf("Expected the route and status in the log line, got %v", line)
+			}
+		})
+	}
+
+	// The error the service returned is not changed by the responses
+	if notFound.RequestID() != "" {
+		t.Error("Expected the service's error to be left without a request ID")
+	}
+}
+
+func TestMiddleware_LogsServerErrors(t *testing.T) {
+	logs := captureLogs(t)
+
+	mockService := &mocks.EntityServiceMock{}
+	mockService.On("GetEntityByID", mock.Anything, 1).Return(nil, io.ErrUnexpectedEOF)
+	fiberApp := app.NewFiberApp(mockService)
+
+	req, _ := http.NewRequest("GET", "/api/v1/entities/1", nil)
+	req.Header.Set(logging.HeaderRequestID, "req-1")
+	if _, err := fiberApp.Test(req); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	lines := logLines(t, logs)
+	if len(lines) != 2 {
+		t.Fatalf("Expected the cause and the access log line, got %d lines", len(lines))
+	}
+	for _, line := range lines {
+		if line["level"] != "ERROR" || line["request_id"] != "req-1" {
+			t.Errorf("Expected an error line for req-1, got %v", line)
+		}
+	}
+	if lines[0]["error"] != io.ErrUnexpectedEOF.Error() {
+		t.Errorf("Expected the cause to be logged, got %v", lines[0]["error"])
+	}
+}
+
+func TestHandleError_LogsCause(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		level string
+		msg   string
+	}{
+		{name: "database rejection", err: &pq.Error{Code: "23505"}, level: "WARN", msg: "Request rejected by the database"},
+		{name: "canceled", err: context.Canceled, level: "INFO", msg: "Request canceled by the client"},
+		{name: "timed out", err: context.DeadlineExceeded, level: "WARN", msg: "Request timed out"},
+		{name: "validation", err: validation.NewError("name", validation.CodeRequired, nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+
+			mockService := &mocks.EntityServiceMock{}
+			mockService.On("GetEntityByID", mock.Anything, 1).Return(nil, tt.err)
+			fiberApp := app.NewFiberApp(mockService)
+
+			req, _ := http.NewRequest("GET", "/api/v1/entities/1", nil)
+			if _, err := fiberApp.Test(req); err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			// Every request has its access log line, after the cause if any
+			lines := logLines(t, logs)
+			if tt.msg == "" {
+				if len(lines) != 1 {
+					t.Errorf("Expected only the access log line, got %v", lines)
+				}
+				return
+			}
+			if len(lines) != 2 {
+				t.Fatalf("Expected the cause and the access log line, got %v", lines)
+			}
+			if lines[0]["level"] != tt.level || lines[0]["msg"] != tt.msg {
+				t.Errorf("Expected %s %q, got %v", tt.level, tt.msg, lines[0])
+			}
+		})
+	}
+}
+
+func TestNew_AddsRequestAndTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	ctx = logging.WithRequestID(ctx, "req-1")
+
+	logger.With("component", "test").InfoContext(ctx, "Hello")
+	logger.DebugContext(ctx, "Below the level")
+
+	lines := logLines(t, &buf)
+	if len(lines) != 1 {
+		t.Fatalf("Expected one line at the default level, got %d", len(lines))
+	}
+	want := map[string]interface{}{
+		"msg":        "Hello",
+		"component":  "test",
+		"request_id": "req-1",
+		"trace_id":   traceID.String(),
+		"span_id":    spanID.String(),
+	}
+	for key, value := range want {
+		if lines[0][key] != value {
+			t.Errorf("Expected %s %v, got %v", key, value, lines[0][key])
+		}
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     logging.Config
+		wantErr bool
+	}{
+		{name: "default", cfg: logging.DefaultConfig()},
+		{name: "text", cfg: logging.Config{Level: "warn", Format: logging.FormatText}},
+		{name: "level", cfg: logging.Config{Level: "verbose", Format: logging.FormatJSON}, wantErr: true},
+		{name: "format", cfg: logging.Config{Level: "info", Format: "xml"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
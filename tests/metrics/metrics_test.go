@@ -0,0 +1,129 @@
+package metrics_test
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"learn-api/internal/app"
+	"learn-api/internal/database"
+	"learn-api/internal/metrics"
+	"learn-api/internal/models"
+	"learn-api/internal/services/mocks"
+	"learn-api/pkg/errors"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/mock"
+)
+
+// scrape fetches /metrics from a Fiber app
+func scrape(t *testing.T, fiberApp *fiber.App) string {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	resp, err := fiberApp.Test(req)
+	if err != nil {
+		t.Fatalf("metrics request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected metrics 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestMiddleware_LabelsByRouteTemplate(t *testing.T) {
+	mockService := &mocks.EntityServiceMock{}
+	mockService.On("GetEntityByID", mock.Anything, 1).Return(&models.Entity{ID: 1}, nil)
+	mockService.On("GetEntityByID", mock.Anything, 2).Return(nil, errors.New(errors.CodeEntityNotFound))
+
+	m := metrics.New()
+	fiberApp := app.NewFiberApp(mockService, app.WithMetrics(m))
+
+	for _, path := range []string{"/api/v1/entities/1", "/api/v1/entities/2", "/no/such/route"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		if _, err := fiberApp.Test(req); err != nil {
+			t.Fatalf("request to %s failed: %v", path, err)
+		}
+	}
+
+	out := scrape(t, fiberApp)
+	for _, want := range []string{
+		`learnapi_http_requests_total{method="GET",route="/api/v1/entities/:id",status="200"} 1`,
+		`learnapi_http_requests_total{method="GET",route="/api/v1/entities/:id",status="404"} 1`,
+		`learnapi_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`learnapi_http_request_duration_seconds_count{method="GET",route="/api/v1/entities/:id",status="200"} 1`,
+		`learnapi_http_requests_in_flight 1`, // the scrape itself
+		`go_goroutines`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the metrics", want)
+		}
+	}
+
+	if strings.Contains(out, "/no/such/route") {
+		t.Error("Expected unmatched paths not to become labels")
+	}
+}
+
+func TestObserveQuery(t *testing.T) {
+	m := metrics.New()
+
+	m.ObserveQuery("GetByID", 10*time.Millisecond, nil)
+	m.ObserveQuery("GetByID", time.Millisecond, sql.ErrNoRows)
+	m.ObserveQuery("Update", time.Millisecond, errors.New(errors.CodePreconditionFailed))
+	m.ObserveQuery("Update", time.Millisecond, sql.ErrConnDone)
+
+	if n := testutil.CollectAndCount(m.Registry(), "learnapi_repository_query_duration_seconds"); n != 2 {
+		t.Errorf("Expected durations for 2 methods, got %d", n)
+	}
+
+	// Only the failure that is not an expected outcome counts as an error
+	expected := `
+# HELP learnapi_repository_query_errors_total Entity repository calls that failed, by method. Not found and client errors are not counted.
+# TYPE learnapi_repository_query_errors_total counter
+learnapi_repository_query_errors_total{method="Update"} 1
+`
+	if err := testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "learnapi_repository_query_errors_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRegisterPool(t *testing.T) {
+	open := func() *sql.DB {
+		db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+		if err != nil {
+			t.Fatalf("Error opening database: %v", err)
+		}
+		return db
+	}
+	pool := database.NewPool(open(), open())
+	defer pool.Close()
+
+	m := metrics.New()
+	if err := m.RegisterPool(pool); err != nil {
+		t.Fatalf("Error registering pool: %v", err)
+	}
+
+	out := scrape(t, app.NewFiberApp(&mocks.EntityServiceMock{}, app.WithMetrics(m)))
+	for _, want := range []string{
+		`go_sql_max_open_connections{db_name="primary"}`,
+		`go_sql_in_use_connections{db_name="replica_1"}`,
+		`go_sql_wait_count_total{db_name="primary"}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the metrics", want)
+		}
+	}
+
+	// The same pool cannot be registered twice
+	if err := m.RegisterPool(pool); err == nil {
+		t.Error("Expected an error registering the pool again")
+	}
+}
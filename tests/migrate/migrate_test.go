@@ -0,0 +1,298 @@
+package migrate_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"learn-api/internal/database/migrate"
+
+	_ "github.com/lib/pq"
+)
+
+// widgetMigrations creates and drops tables private to these tests
+var widgetMigrations = fstest.MapFS{
+	"0001_create_widgets.up.sql":   {Data: []byte("CREATE TABLE migrate_test_widgets (id SERIAL PRIMARY KEY);")},
+	"0001_create_widgets.down.sql": {Data: []byte("DROP TABLE migrate_test_widgets;")},
+	"0002_add_color.up.sql":        {Data: []byte("ALTER TABLE migrate_test_widgets ADD COLUMN color TEXT;\nCREATE INDEX ON migrate_test_widgets (color);")},
+	"0002_add_color.down.sql":      {Data: []byte("ALTER TABLE migrate_test_widgets DROP COLUMN color;")},
+}
+
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// openTestDB connects to the test database, skipping the test when it is not
+// available unless TEST_DB_HOST configures one, as in CI
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	psqlInfo := "host=" + getEnv("TEST_DB_HOST", "localhost") +
+		" port=" + getEnv("TEST_DB_PORT", "5432") +
+		" user=" + getEnv("TEST_DB_USER", "postgres") +
+		" password=" + getEnv("TEST_DB_PASSWORD", "postgres") +
+		" dbname=" + getEnv("TEST_DB_NAME", "learnapi_test") +
+		" sslmode=disable"
+
+	db, err := sql.Open("postgres", psqlInfo)
+	if err == nil {
+		err = db.Ping()
+	}
+	if err != nil && os.Getenv("TEST_DB_HOST") != "" {
+		t.Fatalf("Test database configured by TEST_DB_HOST is not available: %v", err)
+	}
+	if err != nil {
+		t.Skipf("Skipping test because database is not available: %v", err)
+	}
+
+	reset := func() {
+		db.Exec("DROP TABLE IF EXISTS migrate_test_widgets")
+		db.Exec("DROP TABLE IF EXISTS migrate_test_versions")
+	}
+	reset()
+	t.Cleanup(func() {
+		reset()
+		db.Close()
+	})
+	return db
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := migrate.Load(widgetMigrations)
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v", err)
+	}
+
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[1].Version != 2 {
+		t.Fatalf("Expected migrations 1 and 2 in order, got %+v", migrations)
+	}
+
+	if migrations[1].Name != "add_color" || migrations[1].Down != "ALTER TABLE migrate_test_widgets DROP COLUMN color;" {
+		t.Errorf("Unexpected migration: %+v", migrations[1])
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		source fstest.MapFS
+	}{
+		{
+			name:   "missing down",
+			source: fstest.MapFS{"0001_a.up.sql": {Data: []byte("SELECT 1;")}},
+		},
+		{
+			name:   "bad file name",
+			source: fstest.MapFS{"create_a.sql": {Data: []byte("SELECT 1;")}},
+		},
+		{
+			name: "conflicting names",
+			source: fstest.MapFS{
+				"0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+				"0001_b.down.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := migrate.Load(tt.source); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestSource(t *testing.T) {
+	migrations, err := migrate.Load(migrate.Source())
+	if err != nil {
+		t.Fatalf("Error loading embedded migrations: %v", err)
+	}
+
+	if len(migrations) == 0 || migrations[0].Version != 1 {
+		t.Errorf("Expected the baseline migration first, got %+v", migrations)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+
+	paths, err := migrate.Create(dir, "Add Widgets!")
+	if err != nil {
+		t.Fatalf("Error creating migration: %v", err)
+	}
+
+	want := []string{filepath.Join(dir, "0001_add_widgets.up.sql"), filepath.Join(dir, "0001_add_widgets.down.sql")}
+	if len(paths) != 2 || paths[0] != want[0] || paths[1] != want[1] {
+		t.Fatalf("Expected %v, got %v", want, paths)
+	}
+
+	// The next migration is numbered after the latest one
+	paths, err = migrate.Create(dir, "add color")
+	if err != nil || len(paths) != 2 || filepath.Base(paths[0]) != "0002_add_color.up.sql" {
+		t.Errorf("Expected migration 0002_add_color, got %v, %v", paths, err)
+	}
+
+	migrations, err := migrate.Load(os.DirFS(dir))
+	if err != nil || len(migrations) != 2 {
+		t.Errorf("Expected the created migrations to load, got %v, %v", migrations, err)
+	}
+
+	if _, err := migrate.Create(dir, "!!!"); err == nil {
+		t.Error("Expected an error for a name without letters or digits")
+	}
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	migrations, err := migrate.Load(widgetMigrations)
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v", err)
+	}
+	migrator := migrate.NewMigrator(db, migrations, migrate.WithTable("migrate_test_versions"))
+
+	applied, err := migrator.Up(ctx)
+	if err != nil || len(applied) != 2 {
+		t.Fatalf("Expected 2 migrations applied, got %v, %v", applied, err)
+	}
+
+	if _, err := db.Exec("INSERT INTO migrate_test_widgets (color) VALUES ('red')"); err != nil {
+		t.Errorf("Expected the migrated schema, got %v", err)
+	}
+
+	// Applying again is a no-op
+	applied, err = migrator.Up(ctx)
+	if err != nil || len(applied) != 0 {
+		t.Errorf("Expected nothing to apply, got %v, %v", applied, err)
+	}
+
+	reverted, err := migrator.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Fatalf("Expected migration 2 reverted, got %v, %v", reverted, err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("Expected 2 statuses, got %v, %v", statuses, err)
+	}
+
+	if statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Errorf("Expected only migration 1 applied, got %+v", statuses)
+	}
+
+	reverted, err = migrator.Down(ctx, 5)
+	if err != nil || len(reverted) != 1 {
+		t.Errorf("Expected the remaining migration reverted, got %v, %v", reverted, err)
+	}
+}
+
+func TestMigrator_Pending(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	migrations, err := migrate.Load(widgetMigrations)
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v", err)
+	}
+	migrator := migrate.NewMigrator(db, migrations, migrate.WithTable("migrate_test_versions"))
+
+	// Without a tracking table everything is pending, and none is created
+	pending, err := migrator.Pending(ctx)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("Expected 2 pending migrations, got %v, %v", pending, err)
+	}
+
+	var table sql.NullString
+	db.QueryRow("SELECT to_regclass('migrate_test_versions')::text").Scan(&table)
+	if table.Valid {
+		t.Error("Expected Pending not to create the tracking table")
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Error migrating: %v", err)
+	}
+	if _, err := migrator.Down(ctx, 1); err != nil {
+		t.Fatalf("Error reverting: %v", err)
+	}
+
+	pending, err = migrator.Pending(ctx)
+	if err != nil || len(pending) != 1 || pending[0].Version != 2 {
+		t.Errorf("Expected migration 2 pending, got %v, %v", pending, err)
+	}
+}
+
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	broken := fstest.MapFS{
+		"0001_create_widgets.up.sql":   widgetMigrations["0001_create_widgets.up.sql"],
+		"0001_create_widgets.down.sql": widgetMigrations["0001_create_widgets.down.sql"],
+		"0002_broken.up.sql":           {Data: []byte("ALTER TABLE migrate_test_widgets ADD COLUMN size INT;\nSELECT * FROM missing_table;")},
+		"0002_broken.down.sql":         {Data: []byte("SELECT 1;")},
+	}
+	migrations, err := migrate.Load(broken)
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v", err)
+	}
+	migrator := migrate.NewMigrator(db, migrations, migrate.WithTable("migrate_test_versions"))
+
+	applied, err := migrator.Up(ctx)
+	if err == nil || len(applied) != 1 {
+		t.Fatalf("Expected migration 2 to fail after applying 1, got %v, %v", applied, err)
+	}
+
+	// The failed migration left neither its column nor a version behind
+	if _, err := db.Exec("SELECT size FROM migrate_test_widgets"); err == nil {
+		t.Error("Expected the failed migration's column to be rolled back")
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil || statuses[1].AppliedAt != nil {
+		t.Errorf("Expected migration 2 pending, got %+v, %v", statuses, err)
+	}
+}
+
+func TestMigrator_ConcurrentRunners(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	migrations, err := migrate.Load(widgetMigrations)
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v", err)
+	}
+
+	// The advisory lock lets exactly one runner apply each migration
+	var wg sync.WaitGroup
+	counts := make([]int, 4)
+	for i := range counts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			applied, err := migrate.NewMigrator(db, migrations, migrate.WithTable("migrate_test_versions")).Up(ctx)
+			if err != nil {
+				t.Errorf("Error migrating: %v", err)
+			}
+			counts[i] = len(applied)
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	if total != len(migrations) {
+		t.Errorf("Expected %d migrations applied in total, got %v", len(migrations), counts)
+	}
+}
@@ -1,14 +1,19 @@
 package repository_test
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 
 	"learn-api/internal/database"
+	"learn-api/internal/database/migrate"
 	"learn-api/internal/models"
 	"learn-api/internal/repository"
+	"learn-api/internal/repository/repotest"
 	"learn-api/pkg/errors"
 
 	_ "github.com/lib/pq"
@@ -20,13 +25,18 @@ var entityRepo repository.EntityRepository
 func TestMain(m *testing.M) {
 	// Set up test database connection
 	if err := setupTestDB(); err != nil {
+		// A database configured for the tests must be reachable, so CI fails
+		// rather than silently skipping the PostgreSQL runs
+		if os.Getenv("TEST_DB_HOST") != "" {
+			log.Fatalf("Test database configured by TEST_DB_HOST is not available: %v", err)
+		}
 		log.Printf("Test database not available: %v", err)
 		os.Setenv("SKIP_REPOSITORY_TESTS", "true")
 	}
 
 	if os.Getenv("SKIP_REPOSITORY_TESTS") != "true" {
 		// Create repository instance
-		entityRepo = repository.NewEntityRepository()
+		entityRepo = repository.NewEntityRepository(testDB)
 	}
 
 	// Run tests
@@ -66,19 +76,13 @@ func setupTestDB() error {
 		return err
 	}
 
-	// Set the global DB for testing
-	database.DB = testDB
-
-	// Create entities table if it doesn't exist
-	createTableQuery := `
-	CREATE TABLE IF NOT EXISTS entities (
-		id SERIAL PRIMARY KEY,
-		name VARCHAR(255) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	)`
+	// Bring the schema up to date
+	migrations, err := migrate.Load(migrate.Source())
+	if err != nil {
+		return err
+	}
 
-	_, err = testDB.Exec(createTableQuery)
+	_, err = migrate.NewMigrator(testDB, migrations).Up(context.Background())
 	if err != nil {
 		return err
 	}
@@ -126,7 +130,7 @@ func TestCreateEntity(t *testing.T) {
 		Name: "Test Entity",
 	}
 
-	err := entityRepo.Create(entity)
+	err := entityRepo.Create(context.Background(), entity)
 	if err != nil {
 		t.Fatalf("Error creating entity: %v", err)
 	}
@@ -155,13 +159,13 @@ func TestGetEntityByID(t *testing.T) {
 	entity := &models.Entity{
 		Name: "Test Entity",
 	}
-	err := entityRepo.Create(entity)
+	err := entityRepo.Create(context.Background(), entity)
 	if err != nil {
 		t.Fatalf("Error creating entity: %v", err)
 	}
 
 	// Now retrieve it
-	retrievedEntity, err := entityRepo.GetByID(entity.ID)
+	retrievedEntity, err := entityRepo.GetByID(context.Background(), entity.ID)
 	if err != nil {
 		t.Fatalf("Error retrieving entity: %v", err)
 	}
@@ -182,7 +186,7 @@ func TestGetEntityByID(t *testing.T) {
 func TestGetEntityByID_NotFound(t *testing.T) {
 	skipIfDatabaseNotAvailable(t)
 
-	entity, err := entityRepo.GetByID(999999) // Non-existent ID
+	entity, err := entityRepo.GetByID(context.Background(), 999999) // Non-existent ID
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -203,17 +207,18 @@ func TestGetAllEntities(t *testing.T) {
 	}
 
 	for _, entity := range entities {
-		err := entityRepo.Create(entity)
+		err := entityRepo.Create(context.Background(), entity)
 		if err != nil {
 			t.Fatalf("Error creating entity: %v", err)
 		}
 	}
 
 	// Retrieve all entities
-	allEntities, err := entityRepo.GetAll()
+	page, err := entityRepo.GetAll(context.Background(), models.ListParams{Limit: models.MaxPageLimit})
 	if err != nil {
 		t.Fatalf("Error retrieving all entities: %v", err)
 	}
+	allEntities := page.Entities
 
 	if len(allEntities) < 3 {
 		t.Errorf("Expected at least 3 entities, got %d", len(allEntities))
@@ -242,20 +247,20 @@ func TestUpdateEntity(t *testing.T) {
 	entity := &models.Entity{
 		Name: "Original Name",
 	}
-	err := entityRepo.Create(entity)
+	err := entityRepo.Create(context.Background(), entity)
 	if err != nil {
 		t.Fatalf("Error creating entity: %v", err)
 	}
 
 	// Update the entity
 	entity.Name = "Updated Name"
-	err = entityRepo.Update(entity.ID, entity)
+	err = entityRepo.Update(context.Background(), entity.ID, entity)
 	if err != nil {
 		t.Fatalf("Error updating entity: %v", err)
 	}
 
 	// Retrieve the updated entity
-	updatedEntity, err := entityRepo.GetByID(entity.ID)
+	updatedEntity, err := entityRepo.GetByID(context.Background(), entity.ID)
 	if err != nil {
 		t.Fatalf("Error retrieving updated entity: %v", err)
 	}
@@ -277,13 +282,13 @@ func TestUpdateEntity_NotFound(t *testing.T) {
 		Name: "Test Name",
 	}
 
-	err := entityRepo.Update(entity.ID, entity)
+	err := entityRepo.Update(context.Background(), entity.ID, entity)
 	if err == nil {
 		t.Error("Expected error for non-existent entity")
 	}
 
-	if err != errors.ErrDatabase && err != sql.ErrNoRows {
-		t.Errorf("Expected ErrDatabase or ErrNoRows, got %v", err)
+	if !errors.Is(err, errors.CodeDatabaseError) && err != sql.ErrNoRows {
+		t.Errorf("Expected CodeDatabaseError or ErrNoRows, got %v", err)
 	}
 }
 
@@ -294,19 +299,19 @@ func TestDeleteEntity(t *testing.T) {
 	entity := &models.Entity{
 		Name: "Test Entity",
 	}
-	err := entityRepo.Create(entity)
+	err := entityRepo.Create(context.Background(), entity)
 	if err != nil {
 		t.Fatalf("Error creating entity: %v", err)
 	}
 
 	// Delete the entity
-	err = entityRepo.Delete(entity.ID)
+	err = entityRepo.Delete(context.Background(), entity.ID, 0)
 	if err != nil {
 		t.Fatalf("Error deleting entity: %v", err)
 	}
 
 	// Try to retrieve the deleted entity
-	deletedEntity, err := entityRepo.GetByID(entity.ID)
+	deletedEntity, err := entityRepo.GetByID(context.Background(), entity.ID)
 	if err != nil {
 		t.Fatalf("Error retrieving entity after deletion: %v", err)
 	}
@@ -319,12 +324,496 @@ func TestDeleteEntity(t *testing.T) {
 func TestDeleteEntity_NotFound(t *testing.T) {
 	skipIfDatabaseNotAvailable(t)
 
-	err := entityRepo.Delete(999999) // Non-existent ID
+	err := entityRepo.Delete(context.Background(), 999999, 0) // Non-existent ID
 	if err == nil {
 		t.Error("Expected error for non-existent entity")
 	}
 
-	if err != errors.ErrDatabase && err != sql.ErrNoRows {
-		t.Errorf("Expected ErrDatabase or ErrNoRows, got %v", err)
+	if !errors.Is(err, errors.CodeDatabaseError) && err != sql.ErrNoRows {
+		t.Errorf("Expected CodeDatabaseError or ErrNoRows, got %v", err)
+	}
+}
+
+func TestGetAllEntities_Pagination(t *testing.T) {
+	skipIfDatabaseNotAvailable(t)
+
+	for i := 0; i < 5; i++ {
+		if err := entityRepo.Create(context.Background(), &models.Entity{Name: "Paged Entity"}); err != nil {
+			t.Fatalf("Error creating entity: %v", err)
+		}
+	}
+
+	// Offset paging
+	first, err := entityRepo.GetAll(context.Background(), models.ListParams{Limit: 2})
+	if err != nil {
+		t.Fatalf("Error retrieving first page: %v", err)
+	}
+
+	if len(first.Entities) != 2 {
+		t.Fatalf("Expected 2 entities, got %d", len(first.Entities))
+	}
+
+	if first.Total < 5 {
+		t.Errorf("Expected total to be at least 5, got %d", first.Total)
+	}
+
+	if !first.HasNext || first.HasPrev {
+		t.Errorf("Expected first page to have next and no prev, got next=%v prev=%v", first.HasNext, first.HasPrev)
+	}
+
+	// Keyset paging forward from the first page
+	second, err := entityRepo.GetAll(context.Background(), models.ListParams{Limit: 2, After: first.NextCursor})
+	if err != nil {
+		t.Fatalf("Error retrieving second page: %v", err)
+	}
+
+	if len(second.Entities) != 2 || second.Entities[0].ID <= first.Entities[1].ID {
+		t.Fatalf("Expected second page to continue after id %d", first.Entities[1].ID)
+	}
+
+	// Keyset paging backward returns the first page again
+	back, err := entityRepo.GetAll(context.Background(), models.ListParams{Limit: 2, Before: second.PrevCursor})
+	if err != nil {
+		t.Fatalf("Error retrieving previous page: %v", err)
+	}
+
+	if len(back.Entities) != 2 || back.Entities[0].ID != first.Entities[0].ID || back.Entities[1].ID != first.Entities[1].ID {
+		t.Errorf("Expected previous page to match first page")
+	}
+
+	if back.HasPrev {
+		t.Error("Expected previous page to be the first page")
+	}
+}
+
+func TestGetAllEntities_InvalidCursor(t *testing.T) {
+	skipIfDatabaseNotAvailable(t)
+
+	_, err := entityRepo.GetAll(context.Background(), models.ListParams{Limit: 2, After: "not-a-cursor"})
+	if !errors.Is(err, errors.CodeInvalidCursor) {
+		t.Errorf("Expected CodeInvalidCursor, got %v", err)
+	}
+}
+
+func TestGetAllEntities_FilterAndSort(t *testing.T) {
+	skipIfDatabaseNotAvailable(t)
+
+	for _, name := range []string{"Filter Beta", "Filter Alpha", "Filter Gamma", "Unrelated 100%"} {
+		if err := entityRepo.Create(context.Background(), &models.Entity{Name: name}); err != nil {
+			t.Fatalf("Error creating entity: %v", err)
+		}
+	}
+
+	params := models.ListParams{
+		Limit:   2,
+		Filters: []models.Filter{{Field: "name", Operator: "prefix", Value: "filter "}},
+		Sort:    []models.SortField{{Field: "name", Desc: true}},
+	}
+
+	first, err := entityRepo.GetAll(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Error retrieving entities: %v", err)
+	}
+
+	if first.Total != 3 {
+		t.Errorf("Expected 3 matching entities, got %d", first.Total)
+	}
+
+	if len(first.Entities) != 2 || first.Entities[0].Name != "Filter Gamma" || first.Entities[1].Name != "Filter Beta" {
+		t.Fatalf("Unexpected first page: %+v", first.Entities)
+	}
+
+	// The keyset cursor continues in the requested order
+	params.After = first.NextCursor
+	second, err := entityRepo.GetAll(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Error retrieving second page: %v", err)
+	}
+
+	if len(second.Entities) != 1 || second.Entities[0].Name != "Filter Alpha" || second.HasNext {
+		t.Errorf("Unexpected second page: %+v", second.Entities)
+	}
+
+	// A cursor issued for one order is rejected for another
+	params.Sort = nil
+	if _, err := entityRepo.GetAll(context.Background(), params); !errors.Is(err, errors.CodeInvalidCursor) {
+		t.Errorf("Expected CodeInvalidCursor, got %v", err)
+	}
+
+	// LIKE wildcards in filter values are matched literally
+	literal, err := entityRepo.GetAll(context.Background(), models.ListParams{
+		Limit:   10,
+		Filters: []models.Filter{{Field: "name", Operator: "contains", Value: "100%"}},
+	})
+	if err != nil {
+		t.Fatalf("Error retrieving entities: %v", err)
+	}
+
+	if literal.Total != 1 {
+		t.Errorf("Expected 1 entity matching '100%%', got %d", literal.Total)
+	}
+}
+
+func TestSearchEntities(t *testing.T) {
+	skipIfDatabaseNotAvailable(t)
+
+	for _, name := range []string{"Searchable Gizmo", "Searchable Gadget", "Something Else"} {
+		if err := entityRepo.Create(context.Background(), &models.Entity{Name: name}); err != nil {
+			t.Fatalf("Error creating entity: %v", err)
+		}
+	}
+
+	// A misspelled query still finds the closest entity first
+	results, err := entityRepo.Search(context.Background(), "gizmmo", 10)
+	if err != nil {
+		t.Fatalf("Error searching entities: %v", err)
+	}
+
+	if len(results) == 0 {
+		t.Fatal("Expected at least one search result")
+	}
+
+	if results[0].Name != "Searchable Gizmo" {
+		t.Errorf("Expected best match to be 'Searchable Gizmo', got '%s'", results[0].Name)
+	}
+
+	for i := 1; i < len(results); i++ {
+		if results[i].Score > results[i-1].Score {
+			t.Error("Expected results to be ordered by descending score")
+		}
+	}
+}
+
+func TestSoftDeleteRestoreAndPurge(t *testing.T) {
+	skipIfDatabaseNotAvailable(t)
+
+	entity := &models.Entity{Name: "Trashable Entity"}
+	if err := entityRepo.Create(context.Background(), entity); err != nil {
+		t.Fatalf("Error creating entity: %v", err)
+	}
+
+	// Purging a live entity is refused
+	if err := entityRepo.Purge(context.Background(), entity.ID); err != sql.ErrNoRows {
+		t.Fatalf("Expected CodeNoRows when purging a live entity, got %v", err)
+	}
+
+	if err := entityRepo.Delete(context.Background(), entity.ID, 0); err != nil {
+		t.Fatalf("Error deleting entity: %v", err)
+	}
+
+	// Deleting twice reports the entity as missing
+	if err := entityRepo.Delete(context.Background(), entity.ID, 0); err != sql.ErrNoRows {
+		t.Errorf("Expected CodeNoRows when deleting twice, got %v", err)
+	}
+
+	// The deleted entity shows up in the trash with its deletion time
+	trash, err := entityRepo.GetDeleted(context.Background(), models.ListParams{
+		Limit:   10,
+		Filters: []models.Filter{{Field: "id", Operator: "eq", Value: strconv.Itoa(entity.ID)}},
+	})
+	if err != nil {
+		t.Fatalf("Error listing trash: %v", err)
+	}
+
+	if len(trash.Entities) != 1 || trash.Entities[0].DeletedAt == nil {
+		t.Fatalf("Expected the deleted entity in the trash, got %+v", trash.Entities)
+	}
+
+	// Restoring makes it visible again
+	if err := entityRepo.Restore(context.Background(), entity.ID); err != nil {
+		t.Fatalf("Error restoring entity: %v", err)
+	}
+
+	restored, err := entityRepo.GetByID(context.Background(), entity.ID)
+	if err != nil || restored == nil {
+		t.Fatalf("Expected restored entity to be found, got %v, %v", restored, err)
+	}
+
+	// Delete and purge permanently removes the row
+	if err := entityRepo.Delete(context.Background(), entity.ID, 0); err != nil {
+		t.Fatalf("Error deleting entity: %v", err)
+	}
+
+	if err := entityRepo.Purge(context.Background(), entity.ID); err != nil {
+		t.Fatalf("Error purging entity: %v", err)
+	}
+
+	if err := entityRepo.Restore(context.Background(), entity.ID); err != sql.ErrNoRows {
+		t.Errorf("Expected CodeNoRows when restoring a purged entity, got %v", err)
+	}
+}
+
+func TestUpdateEntity_Version(t *testing.T) {
+	skipIfDatabaseNotAvailable(t)
+
+	entity := &models.Entity{Name: "Versioned Entity"}
+	if err := entityRepo.Create(context.Background(), entity); err != nil {
+		t.Fatalf("Error creating entity: %v", err)
+	}
+
+	if entity.Version != 1 {
+		t.Fatalf("Expected new entity to be at version 1, got %d", entity.Version)
+	}
+
+	// A conditional update at the current version succeeds and bumps the version
+	entity.Name = "Versioned Entity v2"
+	if err := entityRepo.Update(context.Background(), entity.ID, entity); err != nil {
+		t.Fatalf("Error updating entity: %v", err)
+	}
+
+	if entity.Version != 2 {
+		t.Errorf("Expected version 2 after update, got %d", entity.Version)
+	}
+
+	// A conditional update at a stale version is rejected
+	stale := &models.Entity{Name: "Lost Update", Version: 1}
+	if err := entityRepo.Update(context.Background(), entity.ID, stale); !errors.Is(err, errors.CodePreconditionFailed) {
+		t.Errorf("Expected CodePreconditionFailed, got %v", err)
+	}
+
+	// So is a conditional delete
+	if err := entityRepo.Delete(context.Background(), entity.ID, 1); !errors.Is(err, errors.CodePreconditionFailed) {
+		t.Errorf("Expected CodePreconditionFailed, got %v", err)
+	}
+
+	current, err := entityRepo.GetByID(context.Background(), entity.ID)
+	if err != nil || current == nil {
+		t.Fatalf("Expected entity to be found, got %v, %v", current, err)
+	}
+
+	if current.Name != "Versioned Entity v2" || current.Version != 2 {
+		t.Errorf("Expected entity to be unchanged, got %+v", current)
+	}
+}
+
+func TestUpdateFields(t *testing.T) {
+	skipIfDatabaseNotAvailable(t)
+
+	entity := &models.Entity{Name: "Patchable Entity"}
+	if err := entityRepo.Create(context.Background(), entity); err != nil {
+		t.Fatalf("Error creating entity: %v", err)
+	}
+
+	// Only the given columns change and the version is bumped
+	updated, err := entityRepo.UpdateFields(context.Background(), entity.ID, map[string]interface{}{"name": "Patched Entity"}, entity.Version)
+	if err != nil {
+		t.Fatalf("Error updating fields: %v", err)
+	}
+
+	if updated.Name != "Patched Entity" || updated.Version != entity.Version+1 {
+		t.Errorf("Expected patched entity at version %d, got %+v", entity.Version+1, updated)
+	}
+
+	// A stale version is rejected
+	if _, err := entityRepo.UpdateFields(context.Background(), entity.ID, map[string]interface{}{"name": "Lost Update"}, entity.Version); !errors.Is(err, errors.CodePreconditionFailed) {
+		t.Errorf("Expected CodePreconditionFailed, got %v", err)
+	}
+
+	// Columns outside the writable set are refused
+	if _, err := entityRepo.UpdateFields(context.Background(), entity.ID, map[string]interface{}{"id": 99}, 0); !errors.Is(err, errors.CodeInvalidRequest) {
+		t.Errorf("Expected CodeInvalidRequest, got %v", err)
+	}
+}
+
+func TestEntityRepositoryConformance(t *testing.T) {
+	skipIfDatabaseNotAvailable(t)
+
+	repotest.RunConformance(t, func(t *testing.T) (repository.EntityRepository, repository.UnitOfWork) {
+		if _, err := testDB.Exec("TRUNCATE TABLE entities RESTART IDENTITY"); err != nil {
+			t.Fatalf("Error truncating entities table: %v", err)
+		}
+		return repository.NewEntityRepository(testDB), repository.NewUnitOfWork(testDB)
+	})
+}
+
+func TestUnitOfWork(t *testing.T) {
+	skipIfDatabaseNotAvailable(t)
+
+	uow := repository.NewUnitOfWork(testDB)
+
+	// A failing unit of work leaves no trace
+	var rolledBack models.Entity
+	err := uow.Do(context.Background(), func(repo repository.EntityRepository) error {
+		rolledBack.Name = "Rolled Back Entity"
+		if err := repo.Create(context.Background(), &rolledBack); err != nil {
+			return err
+		}
+		return errors.New(errors.CodeInvalidRequest)
+	})
+	if !errors.Is(err, errors.CodeInvalidRequest) {
+		t.Fatalf("Expected the callback error, got %v", err)
+	}
+
+	if entity, err := entityRepo.GetByID(context.Background(), rolledBack.ID); err != nil || entity != nil {
+		t.Errorf("Expected rolled back entity to be absent, got %v, %v", entity, err)
+	}
+
+	// Neither does one that panics
+	var panicked models.Entity
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the panic to be re-raised")
+			}
+		}()
+
+		uow.Do(context.Background(), func(repo repository.EntityRepository) error {
+			panicked.Name = "Panicked Entity"
+			if err := repo.Create(context.Background(), &panicked); err != nil {
+				return err
+			}
+			panic("unit of work failed")
+		})
+	}()
+
+	if entity, err := entityRepo.GetByID(context.Background(), panicked.ID); err != nil || entity != nil {
+		t.Errorf("Expected panicked entity to be absent, got %v, %v", entity, err)
+	}
+
+	// A successful unit of work is committed, and its locked reads see its own writes
+	var committed models.Entity
+	err = uow.Do(context.Background(), func(repo repository.EntityRepository) error {
+		committed.Name = "Committed Entity"
+		if err := repo.Create(context.Background(), &committed); err != nil {
+			return err
+		}
+
+		locked, err := repo.GetByIDForUpdate(context.Background(), committed.ID)
+		if err != nil || locked == nil || locked.Name != committed.Name {
+			t.Errorf("Expected locked read of the new entity, got %v, %v", locked, err)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Error committing unit of work: %v", err)
+	}
+
+	if entity, err := entityRepo.GetByID(context.Background(), committed.ID); err != nil || entity == nil {
+		t.Errorf("Expected committed entity to be found, got %v, %v", entity, err)
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	skipIfDatabaseNotAvailable(t)
+
+	// A query that outlives the repository's timeout is cancelled
+	repo := repository.NewEntityRepository(testDB, repository.WithQueryTimeout(time.Nanosecond))
+	if _, err := repo.GetByID(context.Background(), 1); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// So is one whose caller has gone away
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := entityRepo.GetAll(ctx, models.ListParams{Limit: 10}); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestNewEntityRepository_NilHandle(t *testing.T) {
+	handles := map[string]func(){
+		"nil interface":    func() { repository.NewEntityRepository(nil) },
+		"nil *sql.DB":      func() { repository.NewEntityRepository((*sql.DB)(nil)) },
+		"nil *sql.Tx":      func() { repository.NewEntityRepository((*sql.Tx)(nil)) },
+		"nil *Pool":        func() { repository.NewEntityRepository((*database.Pool)(nil)) },
+		"nil unit of work": func() { repository.NewUnitOfWork((*database.Pool)(nil)) },
+	}
+
+	// A missing connection is reported when wiring, not on the first query
+	for name, construct := range handles {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+			construct()
+		})
+	}
+}
+
+func TestEntityRepository_Transaction(t *testing.T) {
+	skipIfDatabaseNotAvailable(t)
+
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Error starting transaction: %v", err)
+	}
+
+	// A repository bound to a transaction only writes inside it
+	txRepo := repository.NewEntityRepository(tx)
+	entity := &models.Entity{Name: "Transactional Entity"}
+	if err := txRepo.Create(context.Background(), entity); err != nil {
+		t.Fatalf("Error creating entity: %v", err)
+	}
+
+	if found, err := txRepo.GetByID(context.Background(), entity.ID); err != nil || found == nil {
+		t.Errorf("Expected the entity inside the transaction, got %v, %v", found, err)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Error rolling back: %v", err)
+	}
+
+	if found, err := entityRepo.GetByID(context.Background(), entity.ID); err != nil || found != nil {
+		t.Errorf("Expected the entity to be rolled back, got %v, %v", found, err)
+	}
+}
+
+// fixedReplica always hands out the same replica
+type fixedReplica struct {
+	db *sql.DB
+}
+
+func (f fixedReplica) Replica() *sql.DB {
+	return f.db
+}
+
+func TestEntityRepository_ReadReplicas(t *testing.T) {
+	skipIfDatabaseNotAvailable(t)
+
+	// A replica that never answers shows which calls are routed to it
+	down, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("Error opening replica: %v", err)
+	}
+	defer down.Close()
+
+	repo := repository.NewEntityRepository(testDB, repository.WithReplicas(fixedReplica{down}))
+
+	// Writes go to the primary
+	entity := &models.Entity{Name: "Replicated Entity"}
+	if err := repo.Create(context.Background(), entity); err != nil {
+		t.Fatalf("Expected the write to reach the primary, got %v", err)
+	}
+
+	// Reads go to the replica
+	if _, err := repo.GetByID(context.Background(), entity.ID); err == nil {
+		t.Error("Expected GetByID to read from the replica")
+	}
+
+	if _, err := repo.GetAll(context.Background(), models.ListParams{Limit: 10}); err == nil {
+		t.Error("Expected GetAll to read from the replica")
+	}
+
+	// Unless the caller needs to read its own writes
+	ctx := repository.WithPrimary(context.Background())
+	if found, err := repo.GetByID(ctx, entity.ID); err != nil || found == nil {
+		t.Errorf("Expected the primary to serve the read, got %v, %v", found, err)
+	}
+
+	// Locked reads always use the primary
+	if found, err := repo.GetByIDForUpdate(context.Background(), entity.ID); err != nil || found == nil {
+		t.Errorf("Expected the locked read on the primary, got %v, %v", found, err)
+	}
+
+	// As does everything in a unit of work
+	uow := repository.NewUnitOfWork(testDB, repository.WithReplicas(fixedReplica{down}))
+	err = uow.Do(context.Background(), func(tx repository.EntityRepository) error {
+		_, err := tx.GetByID(context.Background(), entity.ID)
+		return err
+	})
+	if err != nil {
+		t.Errorf("Expected reads in a unit of work to use its transaction, got %v", err)
 	}
 }
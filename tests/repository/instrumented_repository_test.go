@@ -0,0 +1,88 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"learn-api/internal/models"
+	"learn-api/internal/repository"
+	"learn-api/internal/repository/mocks"
+)
+
+// observation is one call reported to a recordingObserver
+type observation struct {
+	method string
+	err    error
+}
+
+// recordingObserver remembers the calls reported to it
+type recordingObserver struct {
+	calls []observation
+}
+
+func (o *recordingObserver) ObserveQuery(method string, duration time.Duration, err error) {
+	if duration < 0 {
+		panic("negative duration")
+	}
+	o.calls = append(o.calls, observation{method, err})
+}
+
+func TestInstrumentedRepository(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := &mocks.EntityRepositoryMock{}
+	mockRepo.On("GetByID", ctx, 1).Return(&models.Entity{ID: 1}, nil)
+	mockRepo.On("GetByID", ctx, 2).Return(nil, sql.ErrNoRows)
+	mockRepo.On("Purge", ctx, 3).Return(sql.ErrConnDone)
+
+	observer := &recordingObserver{}
+	repo := repository.NewInstrumentedRepository(mockRepo, observer)
+
+	entity, err := repo.GetByID(ctx, 1)
+	if err != nil || entity.ID != 1 {
+		t.Errorf("Expected the wrapped repository's result, got %v, %v", entity, err)
+	}
+	if _, err := repo.GetByID(ctx, 2); err != sql.ErrNoRows {
+		t.Errorf("Expected the wrapped repository's error, got %v", err)
+	}
+	repo.Purge(ctx, 3)
+
+	want := []observation{{"GetByID", nil}, {"GetByID", sql.ErrNoRows}, {"Purge", sql.ErrConnDone}}
+	if !reflect.DeepEqual(observer.calls, want) {
+		t.Errorf("Expected %v, got %v", want, observer.calls)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestInstrumentedUnitOfWork(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryEntityRepository()
+	observer := &recordingObserver{}
+	memoryUnit, err := repository.NewMemoryUnitOfWork(repo)
+	if err != nil {
+		t.Fatalf("Error creating unit of work: %v", err)
+	}
+	unit := repository.NewInstrumentedUnitOfWork(memoryUnit, observer)
+
+	errAbort := errors.New("abort")
+	err = unit.Do(ctx, func(repo repository.EntityRepository) error {
+		if err := repo.Create(ctx, &models.Entity{Name: "Draft"}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("Expected the unit's error, got %v", err)
+	}
+
+	// Calls inside the unit are observed, and the rollback still applies
+	if len(observer.calls) != 1 || observer.calls[0].method != "Create" {
+		t.Errorf("Expected the Create inside the unit to be observed, got %v", observer.calls)
+	}
+	if entity, err := repo.GetByID(ctx, 1); entity != nil || err != nil {
+		t.Errorf("Expected the unit to be rolled back, got %v, %v", entity, err)
+	}
+}
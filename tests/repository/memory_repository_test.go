@@ -0,0 +1,75 @@
+package repository_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"learn-api/internal/models"
+	"learn-api/internal/repository"
+	"learn-api/internal/repository/repotest"
+)
+
+func TestMemoryRepositoryConformance(t *testing.T) {
+	repotest.RunConformance(t, func(t *testing.T) (repository.EntityRepository, repository.UnitOfWork) {
+		repo := repository.NewMemoryEntityRepository()
+		uow, err := repository.NewMemoryUnitOfWork(repo)
+		if err != nil {
+			t.Fatalf("Error creating unit of work: %v", err)
+		}
+		return repo, uow
+	})
+}
+
+func TestNewMemoryUnitOfWork_OtherRepository(t *testing.T) {
+	wrapped := repository.NewInstrumentedRepository(repository.NewMemoryEntityRepository(), nil)
+	if uow, err := repository.NewMemoryUnitOfWork(wrapped); err == nil || uow != nil {
+		t.Errorf("Expected an error for a wrapped repository, got %v, %v", uow, err)
+	}
+}
+
+func TestMemoryRepository_Concurrent(t *testing.T) {
+	repo := repository.NewMemoryEntityRepository()
+	uow, err := repository.NewMemoryUnitOfWork(repo)
+	if err != nil {
+		t.Fatalf("Error creating unit of work: %v", err)
+	}
+
+	entity := &models.Entity{Name: "Counter"}
+	if err := repo.Create(context.Background(), entity); err != nil {
+		t.Fatalf("Error creating entity: %v", err)
+	}
+
+	// Concurrent read-then-write units of work never lose an update
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := uow.Do(context.Background(), func(tx repository.EntityRepository) error {
+				current, err := tx.GetByIDForUpdate(context.Background(), entity.ID)
+				if err != nil {
+					return err
+				}
+				return tx.Update(context.Background(), entity.ID, &models.Entity{Name: current.Name, Version: current.Version})
+			})
+			if err != nil {
+				t.Errorf("Error updating entity: %v", err)
+			}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.GetAll(context.Background(), models.ListParams{Limit: 10}); err != nil {
+				t.Errorf("Error listing entities: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	updated, err := repo.GetByID(context.Background(), entity.ID)
+	if err != nil || updated == nil || updated.Version != 51 {
+		t.Errorf("Expected version 51 after 50 updates, got %v, %v", updated, err)
+	}
+}
@@ -1,23 +1,28 @@
 package services_test
 
 import (
+	"context"
+	"database/sql"
 	"testing"
 
 	"learn-api/internal/models"
 	"learn-api/internal/repository/mocks"
 	"learn-api/internal/services"
 	"learn-api/pkg/errors"
+
+	"github.com/stretchr/testify/mock"
 )
 
 func TestCreateEntity(t *testing.T) {
 	// Create a mock repository
 	mockRepo := &mocks.EntityRepositoryMock{}
 
-	// Create service with mock repository
-	entityService := services.NewEntityService(mockRepo)
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
 
 	// Set up the mock expectation
-	mockRepo.On("Create", &models.Entity{Name: "Test Entity"}).Return(nil)
+	mockRepo.On("Create", mock.Anything, &models.Entity{Name: "Test Entity"}).Return(nil)
 
 	// Create entity request
 	req := &models.EntityRequest{
@@ -25,7 +30,7 @@ func TestCreateEntity(t *testing.T) {
 	}
 
 	// Call the service method
-	entity, err := entityService.CreateEntity(req)
+	entity, err := entityService.CreateEntity(context.Background(), req)
 
 	// Assertions
 	if err != nil {
@@ -48,8 +53,9 @@ func TestGetEntityByID(t *testing.T) {
 	// Create a mock repository
 	mockRepo := &mocks.EntityRepositoryMock{}
 
-	// Create service with mock repository
-	entityService := services.NewEntityService(mockRepo)
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
 
 	// Create a test entity
 	expectedEntity := &models.Entity{
@@ -58,10 +64,10 @@ func TestGetEntityByID(t *testing.T) {
 	}
 
 	// Set up the mock expectation
-	mockRepo.On("GetByID", 1).Return(expectedEntity, nil)
+	mockRepo.On("GetByID", mock.Anything, 1).Return(expectedEntity, nil)
 
 	// Call the service method
-	entity, err := entityService.GetEntityByID(1)
+	entity, err := entityService.GetEntityByID(context.Background(), 1)
 
 	// Assertions
 	if err != nil {
@@ -88,8 +94,9 @@ func TestGetAllEntities(t *testing.T) {
 	// Create a mock repository
 	mockRepo := &mocks.EntityRepositoryMock{}
 
-	// Create service with mock repository
-	entityService := services.NewEntityService(mockRepo)
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
 
 	// Create test entities
 	expectedEntities := []*models.Entity{
@@ -98,19 +105,55 @@ func TestGetAllEntities(t *testing.T) {
 		{ID: 3, Name: "Entity 3"},
 	}
 
+	params := models.ListParams{Limit: 10}
+
 	// Set up the mock expectation
-	mockRepo.On("GetAll").Return(expectedEntities, nil)
+	mockRepo.On("GetAll", mock.Anything, params).Return(&models.EntityPage{Entities: expectedEntities, Total: 3}, nil)
 
 	// Call the service method
-	entities, err := entityService.GetAllEntities()
+	page, err := entityService.GetAllEntities(context.Background(), params)
 
 	// Assertions
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
-	if len(entities) != 3 {
-		t.Errorf("Expected 3 entities, got %d", len(entities))
+	if len(page.Entities) != 3 {
+		t.Errorf("Expected 3 entities, got %d", len(page.Entities))
+	}
+
+	if page.Total != 3 {
+		t.Errorf("Expected total to be 3, got %d", page.Total)
+	}
+
+	// Verify mock was called
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSearchEntities(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+
+	// Set up the mock expectation; surrounding whitespace is trimmed
+	expectedResults := []*models.EntitySearchResult{
+		{Entity: models.Entity{ID: 1, Name: "Widget"}, Score: 0.9},
+	}
+	mockRepo.On("Search", mock.Anything, "widget", 10).Return(expectedResults, nil)
+
+	// Call the service method
+	results, err := entityService.SearchEntities(context.Background(), "  widget ", 10)
+
+	// Assertions
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(results) != 1 || results[0].Name != "Widget" {
+		t.Errorf("Unexpected search results: %v", results)
 	}
 
 	// Verify mock was called
@@ -121,8 +164,10 @@ func TestUpdateEntity(t *testing.T) {
 	// Create a mock repository
 	mockRepo := &mocks.EntityRepositoryMock{}
 
-	// Create service with mock repository
-	entityService := services.NewEntityService(mockRepo)
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+	mockUoW.On("Do", mock.Anything).Return(nil)
 
 	// Create a test entity for the existing entity
 	existingEntity := &models.Entity{
@@ -137,8 +182,8 @@ func TestUpdateEntity(t *testing.T) {
 	}
 
 	// Set up the mock expectations
-	mockRepo.On("GetByID", 1).Return(existingEntity, nil)
-	mockRepo.On("Update", 1, updatedEntity).Return(nil)
+	mockRepo.On("GetByIDForUpdate", mock.Anything, 1).Return(existingEntity, nil)
+	mockRepo.On("Update", mock.Anything, 1, updatedEntity).Return(nil)
 
 	// Create entity request
 	req := &models.EntityRequest{
@@ -146,7 +191,7 @@ func TestUpdateEntity(t *testing.T) {
 	}
 
 	// Call the service method
-	entity, err := entityService.UpdateEntity(1, req)
+	entity, err := entityService.UpdateEntity(context.Background(), 1, req, 0)
 
 	// Assertions
 	if err != nil {
@@ -169,11 +214,13 @@ func TestUpdateEntity_NotFound(t *testing.T) {
 	// Create a mock repository
 	mockRepo := &mocks.EntityRepositoryMock{}
 
-	// Create service with mock repository
-	entityService := services.NewEntityService(mockRepo)
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+	mockUoW.On("Do", mock.Anything).Return(nil)
 
 	// Set up the mock expectation for non-existent entity
-	mockRepo.On("GetByID", 999).Return(nil, nil)
+	mockRepo.On("GetByIDForUpdate", mock.Anything, 999).Return(nil, nil)
 
 	// Create entity request
 	req := &models.EntityRequest{
@@ -181,11 +228,11 @@ func TestUpdateEntity_NotFound(t *testing.T) {
 	}
 
 	// Call the service method
-	entity, err := entityService.UpdateEntity(999, req)
+	entity, err := entityService.UpdateEntity(context.Background(), 999, req, 0)
 
 	// Assertions
-	if err != errors.ErrEntityNotFound {
-		t.Fatalf("Expected ErrEntityNotFound, got %v", err)
+	if !errors.Is(err, errors.CodeEntityNotFound) {
+		t.Fatalf("Expected CodeEntityNotFound, got %v", err)
 	}
 
 	if entity != nil {
@@ -196,12 +243,68 @@ func TestUpdateEntity_NotFound(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestUpdateEntity_StaleVersion(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+	mockUoW.On("Do", mock.Anything).Return(nil)
+
+	// Set up the mock expectation; the stored entity is already at version 3
+	mockRepo.On("GetByIDForUpdate", mock.Anything, 1).Return(&models.Entity{ID: 1, Name: "Original Name", Version: 3}, nil)
+
+	// Call the service method with the version the client last saw
+	entity, err := entityService.UpdateEntity(context.Background(), 1, &models.EntityRequest{Name: "Updated Name"}, 2)
+
+	// Assertions
+	if !errors.Is(err, errors.CodePreconditionFailed) {
+		t.Fatalf("Expected CodePreconditionFailed, got %v", err)
+	}
+
+	if entity != nil {
+		t.Error("Expected entity to be nil")
+	}
+
+	// The stale write never reaches the repository
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeleteEntity_ConditionalVersion(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+	mockUoW.On("Do", mock.Anything).Return(nil)
+
+	// Set up the mock expectations; the repository enforces the version atomically
+	mockRepo.On("GetByIDForUpdate", mock.Anything, 1).Return(&models.Entity{ID: 1, Version: 2}, nil)
+	mockRepo.On("Delete", mock.Anything, 1, 2).Return(errors.New(errors.CodePreconditionFailed))
+
+	// Call the service method
+	err := entityService.DeleteEntity(context.Background(), 1, 2)
+
+	// Assertions
+	if !errors.Is(err, errors.CodePreconditionFailed) {
+		t.Fatalf("Expected CodePreconditionFailed, got %v", err)
+	}
+
+	// Verify mock was called
+	mockRepo.AssertExpectations(t)
+}
+
 func TestDeleteEntity(t *testing.T) {
 	// Create a mock repository
 	mockRepo := &mocks.EntityRepositoryMock{}
 
-	// Create service with mock repository
-	entityService := services.NewEntityService(mockRepo)
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+	mockUoW.On("Do", mock.Anything).Return(nil)
 
 	// Create a test entity for the existing entity
 	existingEntity := &models.Entity{
@@ -210,11 +313,11 @@ func TestDeleteEntity(t *testing.T) {
 	}
 
 	// Set up the mock expectations
-	mockRepo.On("GetByID", 1).Return(existingEntity, nil)
-	mockRepo.On("Delete", 1).Return(nil)
+	mockRepo.On("GetByIDForUpdate", mock.Anything, 1).Return(existingEntity, nil)
+	mockRepo.On("Delete", mock.Anything, 1, 0).Return(nil)
 
 	// Call the service method
-	err := entityService.DeleteEntity(1)
+	err := entityService.DeleteEntity(context.Background(), 1, 0)
 
 	// Assertions
 	if err != nil {
@@ -229,20 +332,432 @@ func TestDeleteEntity_NotFound(t *testing.T) {
 	// Create a mock repository
 	mockRepo := &mocks.EntityRepositoryMock{}
 
-	// Create service with mock repository
-	entityService := services.NewEntityService(mockRepo)
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+	mockUoW.On("Do", mock.Anything).Return(nil)
 
 	// Set up the mock expectation for non-existent entity
-	mockRepo.On("GetByID", 999).Return(nil, nil)
+	mockRepo.On("GetByIDForUpdate", mock.Anything, 999).Return(nil, nil)
+
+	// Call the service method
+	err := entityService.DeleteEntity(context.Background(), 999, 0)
+
+	// Assertions
+	if !errors.Is(err, errors.CodeEntityNotFound) {
+		t.Fatalf("Expected CodeEntityNotFound, got %v", err)
+	}
+
+	// Verify mock was called
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRestoreEntity(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+	mockUoW.On("Do", mock.Anything).Return(nil)
+
+	// Set up the mock expectations
+	restoredEntity := &models.Entity{ID: 1, Name: "Restored"}
+	mockRepo.On("Restore", mock.Anything, 1).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, 1).Return(restoredEntity, nil)
+
+	// Call the service method
+	entity, err := entityService.RestoreEntity(context.Background(), 1)
+
+	// Assertions
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if entity != restoredEntity {
+		t.Errorf("Expected restored entity to be returned, got %v", entity)
+	}
+
+	// Verify mock was called
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRestoreEntity_NotInTrash(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+	mockUoW.On("Do", mock.Anything).Return(nil)
+
+	// Set up the mock expectation for an entity that is not in the trash
+	mockRepo.On("Restore", mock.Anything, 999).Return(sql.ErrNoRows)
+
+	// Call the service method
+	entity, err := entityService.RestoreEntity(context.Background(), 999)
+
+	// Assertions
+	if err != sql.ErrNoRows {
+		t.Fatalf("Expected CodeNoRows, got %v", err)
+	}
+
+	if entity != nil {
+		t.Error("Expected entity to be nil")
+	}
+
+	// Verify mock was called
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPurgeEntity(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+
+	// Set up the mock expectation
+	mockRepo.On("Purge", mock.Anything, 1).Return(nil)
 
 	// Call the service method
-	err := entityService.DeleteEntity(999)
+	err := entityService.PurgeEntity(context.Background(), 1)
 
 	// Assertions
-	if err != errors.ErrEntityNotFound {
-		t.Fatalf("Expected ErrEntityNotFound, got %v", err)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
 	}
 
 	// Verify mock was called
 	mockRepo.AssertExpectations(t)
 }
+
+func TestPatchEntity_MergePatch(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+	mockUoW.On("Do", mock.Anything).Return(nil)
+
+	// Set up the mock expectations; only the changed column is written
+	mockRepo.On("GetByIDForUpdate", mock.Anything, 1).Return(&models.Entity{ID: 1, Name: "Original Name", Version: 2}, nil)
+	mockRepo.On("UpdateFields", mock.Anything, 1, map[string]interface{}{"name": "Patched Name"}, 2).
+		Return(&models.Entity{ID: 1, Name: "Patched Name", Version: 3}, nil)
+
+	// Call the service method
+	patch := &models.EntityPatch{
+		ContentType: models.MergePatchContentType,
+		Document:    []byte(`{"name":"Patched Name"}`),
+	}
+	entity, err := entityService.PatchEntity(context.Background(), 1, patch, 0)
+
+	// Assertions
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if entity.Name != "Patched Name" || entity.Version != 3 {
+		t.Errorf("Expected patched entity at version 3, got %+v", entity)
+	}
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatchEntity_JSONPatchTestFails(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+	mockUoW.On("Do", mock.Anything).Return(nil)
+
+	// Set up the mock expectation
+	mockRepo.On("GetByIDForUpdate", mock.Anything, 1).Return(&models.Entity{ID: 1, Name: "Original Name", Version: 2}, nil)
+
+	// Call the service method with a test operation that does not hold
+	patch := &models.EntityPatch{
+		ContentType: models.JSONPatchContentType,
+		Document:    []byte(`[{"op":"test","path":"/name","value":"Other Name"},{"op":"replace","path":"/name","value":"Patched Name"}]`),
+	}
+	_, err := entityService.PatchEntity(context.Background(), 1, patch, 0)
+
+	// Assertions
+	if !errors.Is(err, errors.CodePatchConflict) {
+		t.Fatalf("Expected CodePatchConflict, got %v", err)
+	}
+
+	mockRepo.AssertNotCalled(t, "UpdateFields", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatchEntity_InvalidChanges(t *testing.T) {
+	cases := map[string]*models.EntityPatch{
+		"read-only field": {ContentType: models.MergePatchContentType, Document: []byte(`{"id":7}`)},
+		"unknown field":   {ContentType: models.JSONPatchContentType, Document: []byte(`[{"op":"add","path":"/color","value":"red"}]`)},
+		"invalid name":    {ContentType: models.MergePatchContentType, Document: []byte(`{"name":""}`)},
+		"malformed":       {ContentType: models.MergePatchContentType, Document: []byte(`{"name":`)},
+	}
+
+	for name, patch := range cases {
+		// Create a mock repository
+		mockRepo := &mocks.EntityRepositoryMock{}
+
+		// Create service with mock repository and unit of work
+		mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+		entityService := services.NewEntityService(mockRepo, mockUoW)
+		mockUoW.On("Do", mock.Anything).Return(nil)
+
+		// Set up the mock expectation
+		mockRepo.On("GetByIDForUpdate", mock.Anything, 1).Return(&models.Entity{ID: 1, Name: "Original Name", Version: 2}, nil)
+
+		// Call the service method
+		_, err := entityService.PatchEntity(context.Background(), 1, patch, 0)
+
+		// Assertions
+		apiErr, ok := err.(*errors.APIError)
+		if !ok || apiErr.Status() != 400 {
+			t.Errorf("%s: expected a 400 APIError, got %v", name, err)
+		}
+
+		mockRepo.AssertNotCalled(t, "UpdateFields", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
+func TestPatchEntity_NoChanges(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+	mockUoW.On("Do", mock.Anything).Return(nil)
+
+	// Set up the mock expectation
+	mockRepo.On("GetByIDForUpdate", mock.Anything, 1).Return(&models.Entity{ID: 1, Name: "Original Name", Version: 2}, nil)
+
+	// Call the service method with a patch that leaves the entity as it is
+	patch := &models.EntityPatch{
+		ContentType: models.MergePatchContentType,
+		Document:    []byte(`{"name":"Original Name"}`),
+	}
+	entity, err := entityService.PatchEntity(context.Background(), 1, patch, 0)
+
+	// Assertions
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if entity.Version != 2 {
+		t.Errorf("Expected version to stay at 2, got %d", entity.Version)
+	}
+
+	mockRepo.AssertNotCalled(t, "UpdateFields", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatchEntity_UnitOfWorkError(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+
+	// Set up the mock expectation; the unit of work cannot be started
+	mockUoW.On("Do", mock.Anything).Return(sql.ErrConnDone)
+
+	// Call the service method
+	patch := &models.EntityPatch{
+		ContentType: models.MergePatchContentType,
+		Document:    []byte(`{"name":"Patched Name"}`),
+	}
+	entity, err := entityService.PatchEntity(context.Background(), 1, patch, 0)
+
+	// Assertions
+	if err != sql.ErrConnDone {
+		t.Fatalf("Expected sql.ErrConnDone, got %v", err)
+	}
+
+	if entity != nil {
+		t.Error("Expected entity to be nil")
+	}
+
+	// The entity is only read and written inside the unit of work
+	mockRepo.AssertNotCalled(t, "GetByIDForUpdate", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateFields", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockUoW.AssertExpectations(t)
+}
+
+func TestCreateEntities_BestEffort(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+	mockUoW.On("Do", mock.Anything).Return(nil)
+
+	// Set up the mock expectations; the second item is invalid and the third fails in the database
+	mockRepo.On("Create", mock.Anything, &models.Entity{Name: "First"}).Return(nil)
+	mockRepo.On("Create", mock.Anything, &models.Entity{Name: "Third"}).Return(sql.ErrConnDone)
+
+	// Call the service method
+	result, err := entityService.CreateEntities(context.Background(), &models.BatchCreateRequest{
+		Items: []models.EntityRequest{{Name: "First"}, {Name: ""}, {Name: "Third"}},
+	})
+
+	// Assertions
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.Succeeded != 1 || result.Failed != 2 {
+		t.Errorf("Expected 1 succeeded and 2 failed, got %d and %d", result.Succeeded, result.Failed)
+	}
+
+	statuses := []int{201, 400, 500}
+	for i, item := range result.Results {
+		if item.Index != i || item.Status != statuses[i] {
+			t.Errorf("Item %d: expected status %d, got %+v", i, statuses[i], item)
+		}
+	}
+
+	if result.Results[0].Data == nil || result.Results[0].Data.Name != "First" {
+		t.Errorf("Expected created entity in first result, got %+v", result.Results[0].Data)
+	}
+
+	// Each valid item of a best-effort batch runs in a unit of work of its own
+	mockUoW.AssertNumberOfCalls(t, "Do", 2)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUpdateEntities_AtomicRollsBack(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+	mockUoW.On("Do", mock.Anything).Return(nil)
+
+	// Set up the mock expectations; the second entity does not exist
+	mockRepo.On("GetByIDForUpdate", mock.Anything, 1).Return(&models.Entity{ID: 1, Name: "One", Version: 1}, nil)
+	mockRepo.On("Update", mock.Anything, 1, mock.Anything).Return(nil)
+	mockRepo.On("GetByIDForUpdate", mock.Anything, 2).Return(nil, nil)
+
+	// Call the service method
+	result, err := entityService.UpdateEntities(context.Background(), &models.BatchUpdateRequest{
+		Atomic: true,
+		Items: []models.BatchUpdateItem{
+			{ID: 1, Name: "One v2"},
+			{ID: 2, Name: "Two v2"},
+			{ID: 3, Name: "Three v2"},
+		},
+	})
+
+	// Assertions
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.Succeeded != 0 || result.Failed != 3 {
+		t.Errorf("Expected every item to fail, got %d succeeded and %d failed", result.Succeeded, result.Failed)
+	}
+
+	expected := []errors.Code{errors.CodeBatchAborted, errors.CodeEntityNotFound, errors.CodeBatchAborted}
+	for i, item := range result.Results {
+		if item.Error == nil || item.Error.Code() != expected[i] || item.Data != nil {
+			t.Errorf("Item %d: expected %v, got %+v", i, expected[i], item)
+		}
+	}
+
+	// The batch stops at the first failure
+	mockRepo.AssertNotCalled(t, "GetByIDForUpdate", mock.Anything, 3)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeleteEntities_AtomicInvalidItem(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+
+	// Call the service method with an item that fails validation
+	result, err := entityService.DeleteEntities(context.Background(), &models.BatchDeleteRequest{
+		Atomic: true,
+		Items:  []models.BatchDeleteItem{{ID: 1}, {ID: 0}},
+	})
+
+	// Assertions
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !errors.Is(result.Results[0].Error, errors.CodeBatchAborted) {
+		t.Errorf("Expected first item to be aborted, got %+v", result.Results[0])
+	}
+
+	if result.Results[1].Status != 400 {
+		t.Errorf("Expected second item to fail validation, got %+v", result.Results[1])
+	}
+
+	// Nothing reaches the database
+	mockUoW.AssertNotCalled(t, "Do", mock.Anything)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeleteEntities_UnitOfWorkError(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+
+	// Set up the mock expectation; the unit of work cannot be started
+	mockUoW.On("Do", mock.Anything).Return(sql.ErrConnDone)
+
+	// Call the service method
+	result, err := entityService.DeleteEntities(context.Background(), &models.BatchDeleteRequest{
+		Atomic: true,
+		Items:  []models.BatchDeleteItem{{ID: 1}},
+	})
+
+	// Assertions
+	if err != sql.ErrConnDone {
+		t.Fatalf("Expected sql.ErrConnDone, got %v", err)
+	}
+
+	if result != nil {
+		t.Error("Expected result to be nil")
+	}
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetEntityByID_PropagatesContext(t *testing.T) {
+	// Create a mock repository
+	mockRepo := &mocks.EntityRepositoryMock{}
+
+	// Create service with mock repository and unit of work
+	mockUoW := &mocks.UnitOfWorkMock{Repo: mockRepo}
+	entityService := services.NewEntityService(mockRepo, mockUoW)
+
+	// Set up the mock expectation; the caller's context must reach the repository
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	mockRepo.On("GetByID", ctx, 1).Return(&models.Entity{ID: 1, Name: "Test Entity"}, nil)
+
+	// Call the service method
+	if _, err := entityService.GetEntityByID(ctx, 1); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	mockRepo.AssertExpectations(t)
+}
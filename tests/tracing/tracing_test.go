@@ -0,0 +1,261 @@
+package tracing_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+	"testing"
+
+	"learn-api/internal/app"
+	"learn-api/internal/repository"
+	"learn-api/internal/services"
+	"learn-api/internal/services/mocks"
+	"learn-api/internal/tracing"
+	"learn-api/pkg/errors"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exporter collects the spans of every test; the global tracer provider can
+// only be installed once, so the tests share it and reset it
+var exporter = tracetest.NewInMemoryExporter()
+
+func TestMain(m *testing.M) {
+	if _, err := tracing.Setup(context.Background(), tracing.DefaultConfig()); err != nil {
+		panic(err)
+	}
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	os.Exit(m.Run())
+}
+
+// spans returns the recorded spans by name and starts a new recording
+func spans(t *testing.T) map[string]tracetest.SpanStub {
+	t.Helper()
+
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		byName[span.Name] = span
+	}
+	exporter.Reset()
+	return byName
+}
+
+// attributeValue returns the value of a span attribute
+func attributeValue(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestMiddleware_ContinuesCallerTrace(t *testing.T) {
+	exporter.Reset()
+
+	entityRepo := repository.NewMemoryEntityRepository()
+	unitOfWork, err := repository.NewMemoryUnitOfWork(entityRepo)
+	if err != nil {
+		t.Fatalf("Error creating unit of work: %v", err)
+	}
+	entityService := services.NewTracedEntityService(services.NewEntityService(entityRepo, unitOfWork))
+	fiberApp := app.NewFiberApp(entityService)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, _ := http.NewRequest("GET", "/api/v1/entities/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	resp, err := fiberApp.Test(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d", resp.StatusCode)
+	}
+
+	recorded := spans(t)
+	server, ok := recorded["GET /api/v1/entities/:id"]
+	if !ok {
+		t.Fatalf("Expected a server span named after the route, got %v", recorded)
+	}
+	handler, ok := recorded["EntityHandler.GetEntityByID"]
+	if !ok {
+		t.Fatal("Expected a span for the handler action")
+	}
+	service, ok := recorded["EntityService.GetEntityByID"]
+	if !ok {
+		t.Fatal("Expected a span for the service method")
+	}
+
+	if got := server.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("Expected the caller's trace %s, got %s", traceID, got)
+	}
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("Expected a server span, got %v", server.SpanKind)
+	}
+	if handler.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("Expected the handler span to be a child of the server span")
+	}
+	if service.Parent.SpanID() != handler.SpanContext.SpanID() {
+		t.Error("Expected the service span to be a child of the handler span")
+	}
+
+	if v, _ := attributeValue(server, "http.route"); v.AsString() != "/api/v1/entities/:id" {
+		t.Errorf("Expected http.route to be the template, got %q", v.AsString())
+	}
+	if v, _ := attributeValue(server, "http.response.status_code"); v.AsInt64() != http.StatusNotFound {
+		t.Errorf("Expected status code 404, got %d", v.AsInt64())
+	}
+	if v, _ := attributeValue(service, "entity.id"); v.AsInt64() != 42 {
+		t.Errorf("Expected entity.id 42, got %d", v.AsInt64())
+	}
+
+	// A missing entity is an answer, not a failure
+	for _, span := range []tracetest.SpanStub{server, service} {
+		if span.Status.Code == codes.Error {
+			t.Errorf("Expected span %s not to be an error", span.Name)
+		}
+	}
+}
+
+func TestMiddleware_ServerErrors(t *testing.T) {
+	exporter.Reset()
+
+	mockService := &mocks.EntityServiceMock{}
+	mockService.On("GetEntityByID", mock.Anything, 1).Return(nil, errors.New(errors.CodeDatabaseError))
+	fiberApp := app.NewFiberApp(services.NewTracedEntityService(mockService))
+
+	req, _ := http.NewRequest("GET", "/api/v1/entities/1", nil)
+	if _, err := fiberApp.Test(req); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	recorded := spans(t)
+	for _, name := range []string{"GET /api/v1/entities/:id", "EntityService.GetEntityByID"} {
+		span, ok := recorded[name]
+		if !ok {
+			t.Fatalf("Expected a span named %s", name)
+		}
+		if span.Status.Code != codes.Error {
+			t.Errorf("Expected span %s to be an error", name)
+		}
+	}
+}
+
+func TestMiddleware_UnmatchedRoute(t *testing.T) {
+	exporter.Reset()
+
+	fiberApp := app.NewFiberApp(&mocks.EntityServiceMock{})
+	req, _ := http.NewRequest("GET", "/no/such/route", nil)
+	if _, err := fiberApp.Test(req); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	recorded := spans(t)
+	server, ok := recorded["GET"]
+	if !ok {
+		t.Fatalf("Expected an unmatched request to be named after its method, got %v", recorded)
+	}
+	if _, ok := attributeValue(server, "http.route"); ok {
+		t.Error("Expected no http.route for an unmatched request")
+	}
+}
+
+func TestRepository_TracesStatements(t *testing.T) {
+	exporter.Reset()
+
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewEntityRepository(db)
+	if _, err := repo.GetByID(context.Background(), 7); err == nil {
+		t.Fatal("Expected an error from an unreachable database")
+	}
+
+	span, ok := spans(t)["SELECT"]
+	if !ok {
+		t.Fatal("Expected a span named after the statement's operation")
+	}
+	if span.SpanKind != trace.SpanKindClient {
+		t.Errorf("Expected a client span, got %v", span.SpanKind)
+	}
+	if v, _ := attributeValue(span, "db.system"); v.AsString() != "postgresql" {
+		t.Errorf("Expected db.system postgresql, got %q", v.AsString())
+	}
+	if v, _ := attributeValue(span, "db.query.text"); v.AsString() == "" {
+		t.Error("Expected the statement as db.query.text")
+	}
+	if span.Status.Code != codes.Error {
+		t.Error("Expected a failed statement to be an error")
+	}
+}
+
+func TestSanitizeStatement(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			query: "SELECT id FROM entities WHERE id = $1",
+			want:  "SELECT id FROM entities WHERE id = $1",
+		},
+		{
+			query: "SELECT id\n\t FROM entities\n WHERE name = 'O''Brien' AND version > 3",
+			want:  "SELECT id FROM entities WHERE name = ? AND version > ?",
+		},
+		{
+			query: "SELECT col1, t2.x FROM t2 LIMIT 10 OFFSET -2.5",
+			want:  "SELECT col1, t2.x FROM t2 LIMIT ? OFFSET ?",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := repository.SanitizeStatement(tt.query); got != tt.want {
+			t.Errorf("SanitizeStatement(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	otlp := tracing.DefaultConfig()
+	otlp.Exporter = tracing.ExporterOTLP
+
+	badEndpoint := otlp
+	badEndpoint.Endpoint = "localhost:4318"
+
+	badRatio := otlp
+	badRatio.SampleRatio = 1.5
+
+	badExporter := otlp
+	badExporter.Exporter = "zipkin"
+
+	tests := []struct {
+		name    string
+		cfg     tracing.Config
+		wantErr bool
+	}{
+		{name: "default", cfg: tracing.DefaultConfig()},
+		{name: "otlp", cfg: otlp},
+		{name: "endpoint", cfg: badEndpoint, wantErr: true},
+		{name: "sample ratio", cfg: badRatio, wantErr: true},
+		{name: "exporter", cfg: badExporter, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package validation_test
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"learn-api/internal/models"
+	"learn-api/pkg/i18n"
+	"learn-api/pkg/validation"
+)
+
+type address struct {
+	City    string `json:"city" validate:"required"`
+	ZipCode string `json:"zip_code" validate:"pattern=^[0-9]{5}$"`
+}
+
+type audit struct {
+	CreatedBy string `json:"created_by" validate:"required"`
+}
+
+type tag struct {
+	Label string `json:"label" validate:"trim,required,max=10"`
+}
+
+type order struct {
+	audit
+	CustomerID int               `json:"customer_id" validate:"min=1"`
+	Note       string            `json:"note" validate:"trim,max=5"`
+	Quantity   float64           `json:"quantity" validate:"min=0.5,max=10"`
+	Address    address           `json:"address"`
+	Billing    *address          `json:"billing,omitempty"`
+	Tags       []tag             `json:"tags" validate:"max=3,dive"`
+	Codes      []string          `json:"codes" validate:"required,dive,trim,min=2"`
+	Labels     map[string]string `json:"labels" validate:"max=1"`
+	Ignored    string            `json:"-" validate:"required"`
+	internal   string
+}
+
+func validOrder() order {
+	return order{
+		audit:      audit{CreatedBy: "admin"},
+		CustomerID: 1,
+		Quantity:   1,
+		Address:    address{City: "Bangkok", ZipCode: "10110"},
+		Codes:      []string{"AB"},
+	}
+}
+
+// fieldsOf lists the JSON paths and codes of the invalid fields
+func fieldsOf(errs []validation.ValidationError) map[string]string {
+	fields := map[string]string{}
+	for _, err := range errs {
+		fields[err.Field] = err.Code
+	}
+	return fields
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *order)
+		want   map[string]string
+	}{
+		{
+			name:   "valid",
+			modify: func(o *order) {},
+			want:   map[string]string{},
+		},
+		{
+			name:   "numbers",
+			modify: func(o *order) { o.CustomerID = 0; o.Quantity = 0.25 },
+			want:   map[string]string{"customer_id": validation.CodeMin, "quantity": validation.CodeMin},
+		},
+		{
+			name:   "string length in characters",
+			modify: func(o *order) { o.Note = "สวัสดีครับ" },
+			want:   map[string]string{"note": validation.CodeMaxLength},
+		},
+		{
+			name:   "nested struct",
+			modify: func(o *order) { o.Address = address{ZipCode: "1011"} },
+			want:   map[string]string{"address.city": validation.CodeRequired, "address.zip_code": validation.CodePattern},
+		},
+		{
+			name:   "pointer to struct",
+			modify: func(o *order) { o.Billing = &address{City: "Chiang Mai"}; o.Billing.ZipCode = "abc" },
+			want:   map[string]string{"billing.zip_code": validation.CodePattern},
+		},
+		{
+			name:   "embedded struct",
+			modify: func(o *order) { o.CreatedBy = "" },
+			want:   map[string]string{"created_by": validation.CodeRequired},
+		},
+		{
+			name: "slice elements",
+			modify: func(o *order) {
+				o.Tags = []tag{{Label: "ok"}, {Label: "   "}, {Label: "far too long"}}
+				o.Codes = []string{"AB", "C"}
+			},
+			want: map[string]string{
+				"tags[1].label": validation.CodeRequired,
+				"tags[2].label": validation.CodeMaxLength,
+				"codes[1]":      validation.CodeMinLength,
+			},
+		},
+		{
+			name: "collection sizes",
+			modify: func(o *order) {
+				o.Tags = make([]tag, 4)
+				o.Codes = nil
+				o.Labels = map[string]string{"a": "1", "b": "2"}
+			},
+			// The elements are not checked once the slice itself is invalid
+			want: map[string]string{
+				"tags":   validation.CodeMaxItems,
+				"codes":  validation.CodeNotEmpty,
+				"labels": validation.CodeMaxItems,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := validOrder()
+			tt.modify(&o)
+
+			if got := fieldsOf(validation.Struct(&o)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Struct() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStruct_Trim(t *testing.T) {
+	o := validOrder()
+	o.Note = "  hi  "
+	o.Tags = []tag{{Label: " go "}}
+	o.Codes = []string{" AB "}
+
+	if errs := validation.Struct(&o); len(errs) > 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+	if o.Note != "hi" || o.Tags[0].Label != "go" || o.Codes[0] != "AB" {
+		t.Errorf("Expected the strings to be trimmed in place, got %q, %q and %q", o.Note, o.Tags[0].Label, o.Codes[0])
+	}
+
+	// Values that cannot be changed are checked trimmed
+	errs := validation.Struct(models.EntityRequest{Name: "   "})
+	if len(errs) != 1 || errs[0].Code != validation.CodeRequired {
+		t.Errorf("Expected the blank name to be required, got %v", errs)
+	}
+}
+
+func TestStruct_Messages(t *testing.T) {
+	errs := validation.Struct(&models.BatchUpdateItem{ID: 0, Name: strings.Repeat("a", 256)})
+
+	want := []validation.ValidationError{
+		{Field: "id", Message: "ID must be at least 1", Code: validation.CodeMin},
+		{Field: "name", Message: "Name must be at most 255 characters", Code: validation.CodeMaxLength},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Expected %d errors, got %v", len(want), errs)
+	}
+	for i := range want {
+		if errs[i].Field != want[i].Field || errs[i].Message != want[i].Message || errs[i].Code != want[i].Code {
+			t.Errorf("Expected %v, got %v", want[i], errs[i])
+		}
+	}
+
+	// The messages follow the language of the response
+	apiErr := validation.Localize(validation.ToAPIError(errs), i18n.Thai)
+	if got := apiErr.FieldErrors()[1].Message; got != "name ต้องมีความยาวไม่เกิน 255 ตัวอักษร" {
+		t.Errorf("Expected the Thai message, got %q", got)
+	}
+}
+
+type booking struct {
+	From    int    `json:"from"`
+	To      int    `json:"to"`
+	Country string `json:"country" validate:"country"`
+}
+
+// Validate checks that a booking ends after it starts
+func (b *booking) Validate() []validation.ValidationError {
+	if b.To <= b.From {
+		return []validation.ValidationError{validation.NewError("to", validation.CodeMin, map[string]string{"label": "To", "min": "from"})}
+	}
+	return nil
+}
+
+type trip struct {
+	Bookings []booking `json:"bookings" validate:"dive"`
+}
+
+func init() {
+	validation.RegisterRule("country", func(value reflect.Value, param string) bool {
+		return value.String() == "TH" || value.String() == "US"
+	}, map[i18n.Language]string{
+		i18n.English: "{label} must be a supported country",
+		i18n.Thai:    "ไม่รองรับประเทศใน {field}",
+	})
+}
+
+func TestStruct_CustomRulesAndValidator(t *testing.T) {
+	tr := trip{Bookings: []booking{
+		{From: 1, To: 2, Country: "TH"},
+		{From: 3, To: 3, Country: "XX"},
+	}}
+
+	errs := validation.Struct(&tr)
+	want := map[string]string{
+		"bookings[1].country": "country",
+		"bookings[1].to":      validation.CodeMin,
+	}
+	if got := fieldsOf(errs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Struct() = %v, want %v", got, want)
+	}
+
+	apiErr := validation.ToAPIError(errs)
+	if got := apiErr.FieldErrors()[0].Message; got != "Country must be a supported country" {
+		t.Errorf("Expected the custom rule's message, got %q", got)
+	}
+	if got := validation.Localize(apiErr, i18n.Thai).FieldErrors()[0].Message; got != "ไม่รองรับประเทศใน bookings[1].country" {
+		t.Errorf("Expected the custom rule's Thai message, got %q", got)
+	}
+}
+
+func TestRegisterRule_Taken(t *testing.T) {
+	for _, name := range []string{"required", "country"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected registering %q again to panic", name)
+				}
+			}()
+			validation.RegisterRule(name, func(reflect.Value, string) bool { return true }, map[i18n.Language]string{i18n.English: "x"})
+		}()
+	}
+}
+
+func TestStruct_MalformedTag(t *testing.T) {
+	type bad struct {
+		Name string `json:"name" validate:"unknown"`
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected an unknown rule to panic")
+		}
+	}()
+	validation.Struct(&bad{})
+}
+
+func TestBatchRequests_MaxBatchSize(t *testing.T) {
+	requests := map[string]interface{}{
+		"create": &models.BatchCreateRequest{Items: make([]models.EntityRequest, models.MaxBatchSize+1)},
+		"update": &models.BatchUpdateRequest{Items: make([]models.BatchUpdateItem, models.MaxBatchSize+1)},
+		"delete": &models.BatchDeleteRequest{Items: make([]models.BatchDeleteItem, models.MaxBatchSize+1)},
+	}
+
+	for name, req := range requests {
+		errs := validation.Struct(req)
+		if len(errs) != 1 || errs[0].Field != "items" || errs[0].Code != validation.CodeMaxItems {
+			t.Errorf("%s: expected a batch over the limit to be rejected, got %v", name, errs)
+		}
+		if len(errs) == 1 && errs[0].Message != "Items must contain at most "+strconv.Itoa(models.MaxBatchSize)+" entries" {
+			t.Errorf("%s: expected the limit in the message, got %q", name, errs[0].Message)
+		}
+	}
+
+	// A batch at the limit is accepted
+	full := &models.BatchDeleteRequest{Items: make([]models.BatchDeleteItem, models.MaxBatchSize)}
+	for i := range full.Items {
+		full.Items[i].ID = i + 1
+	}
+	if errs := validation.Struct(full); len(errs) != 0 {
+		t.Errorf("Expected a batch at the limit to be accepted, got %v", errs)
+	}
+
+	// The API docs repeat the constant, generated from the handlers' annotations
+	sizes := regexp.MustCompile(`[Uu]p to (\d+) entities`)
+	for _, path := range []string{"../../internal/handlers/batch_handler.go", "../../docs/swagger.json", "../../README.md"} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Error reading %s: %v", path, err)
+		}
+		matches := sizes.FindAllStringSubmatch(string(content), -1)
+		if len(matches) == 0 {
+			t.Errorf("%s: expected the batch size to be documented", path)
+		}
+		for _, match := range matches {
+			if match[1] != strconv.Itoa(models.MaxBatchSize) {
+				t.Errorf("%s: documents batches of up to %s entities, expected %d", path, match[1], models.MaxBatchSize)
+			}
+		}
+	}
+}
+
+func TestValidateFilter_IntRange(t *testing.T) {
+	rules := map[string]validation.FieldRule{
+		"id": {Kind: validation.KindInt, Operators: []string{"eq"}},
+	}
+
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{value: "2147483647", valid: true},
+		{value: "-2147483648", valid: true},
+		{value: "2147483648"},
+		{value: "-2147483649"},
+		{value: "3000000000"},
+	}
+
+	for _, tt := range tests {
+		errs := validation.ValidateFilter("id", "eq", tt.value, rules)
+		if tt.valid && len(errs) != 0 {
+			t.Errorf("%s: expected no errors, got %v", tt.value, errs)
+		}
+		if !tt.valid && (len(errs) != 1 || errs[0].Code != validation.CodeValueNotInteger) {
+			t.Errorf("%s: expected a %s error, got %v", tt.value, validation.CodeValueNotInteger, errs)
+		}
+	}
+}
+
+func TestValidateSearchQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "empty", query: "  ", want: []string{"Search query is required"}},
+		{name: "thai at the limit", query: strings.Repeat("ก", 255)},
+		{name: "too long", query: strings.Repeat("ก", 256), want: []string{"Search query must be at most 255 characters"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range validation.ValidateSearchQuery(tt.query) {
+				got = append(got, err.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateSearchQuery() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}